@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// inGitHubActions reports whether the tool runs in a GitHub Actions job,
+// where deletions are also reported as workflow annotations and in the job
+// summary
+func inGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// escapeWorkflowData escapes the message of a workflow command
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a property value of a workflow command
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// annotate emits a workflow annotation ("notice", "warning" or "error") when
+// running in GitHub Actions
+func annotate(level, title, message string) {
+	if !inGitHubActions() {
+		return
+	}
+	fmt.Printf("::%s title=%s::%s\n", level, escapeWorkflowProperty(title), escapeWorkflowData(message))
+}
+
+// writeJobSummary appends a table of the outcome of every branch of a
+// deletion to the job summary of a GitHub Actions job
+func writeJobSummary(localizer *i18n.Localizer, progress *deletionProgress) {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if !inGitHubActions() || path == "" {
+		return
+	}
+	localize := func(id string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		return msg
+	}
+
+	progress.mu.Lock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", localize("JobSummaryTitle", map[string]interface{}{"Deleted": len(progress.deleted), "Total": len(progress.branches)}))
+	fmt.Fprintf(&b, "| %s | %s |\n| --- | --- |\n", localize("Branch", nil), localize("JobSummaryResult", nil))
+	for _, branch := range progress.branches {
+		var result string
+		switch reason, failed := progress.failed[branch]; {
+		case progress.deleted[branch] && softDelete:
+			result = localize("JobSummaryTrashed", map[string]interface{}{"Trash": trashedBranch(branch)})
+		case progress.deleted[branch]:
+			result = localize("JobSummaryDeleted", nil)
+		case failed:
+			result = localize("JobSummaryFailed", map[string]interface{}{"Reason": reason})
+		default:
+			result = localize("JobSummaryNotDeleted", nil)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s |\n", branch, strings.ReplaceAll(strings.ReplaceAll(result, "|", "\\|"), "\n", " "))
+	}
+	progress.mu.Unlock()
+	b.WriteString("\n")
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		logger.Debug("job summary", "error", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(b.String()); err != nil {
+		logger.Debug("job summary", "error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// activityWeeks is the number of weeks the activity sparkline covers
+const activityWeeks = 12
+
+// sparkBlocks draw the weeks of a sparkline from the fewest commits to the
+// most; weeks without commits are drawn as sparkEmpty
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+const sparkEmpty = '·'
+
+// weeklyActivity counts the commits on tip that base lacks in each of the
+// last activityWeeks weeks, oldest first, by their -date-field. Without a
+// base every commit of tip counts.
+func weeklyActivity(tip, base string) ([]int, error) {
+	// Only committer dates can end the walk early; author dates, which rebases
+	// keep, are filtered below
+	args := []string{"log", "--format=%ct", "--since=" + strconv.Itoa(activityWeeks) + ".weeks", tip}
+	if dateField == "author" {
+		args = []string{"log", "--format=%at", tip}
+	}
+	if base != "" {
+		args = append(args, "--not", base)
+	}
+	output, err := runGit(append(args, "--")...)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int, activityWeeks)
+	now := time.Now()
+	for _, line := range strings.Fields(output) {
+		seconds, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			continue
+		}
+		week := int(now.Sub(time.Unix(seconds, 0)) / (7 * 24 * time.Hour))
+		if week >= 0 && week < activityWeeks {
+			counts[activityWeeks-1-week]++
+		}
+	}
+	return counts, nil
+}
+
+// formatActivity draws the activity of tip relative to base as a sparkline
+// followed by the number of commits it shows
+func formatActivity(localizer *i18n.Localizer, tip, base string) (string, error) {
+	counts, err := weeklyActivity(tip, base)
+	if err != nil {
+		return "", err
+	}
+	line, total := sparkline(counts)
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ActivityCommits",
+		TemplateData: map[string]interface{}{"Count": total, "Weeks": activityWeeks},
+		PluralCount:  total,
+	})
+	return line + " " + msg, nil
+}
+
+// sparkline draws the weekly commit counts as a row of blocks scaled to the
+// busiest week, and returns the total number of commits
+func sparkline(counts []int) (string, int) {
+	busiest, total := 0, 0
+	for _, count := range counts {
+		busiest = max(busiest, count)
+		total += count
+	}
+	var b strings.Builder
+	for _, count := range counts {
+		if count == 0 {
+			b.WriteRune(sparkEmpty)
+			continue
+		}
+		b.WriteRune(sparkBlocks[(count*len(sparkBlocks)-1)/busiest])
+	}
+	return b.String(), total
+}
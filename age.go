@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// AgeThresholds are the ages of a branch's last commit at which the branch
+// list starts highlighting it as aging and as stale
+type AgeThresholds struct {
+	Aging time.Duration
+	Stale time.Duration
+}
+
+// ageThresholds is set from -aging-after and -stale-after
+var ageThresholds = AgeThresholds{Aging: 30 * 24 * time.Hour, Stale: 180 * 24 * time.Hour}
+
+// parseAge parses an age in the units shortDuration prints: "45m", "12h", "30d",
+// "2w", "6mo" or "1y". A month is 30 days and a year 365 days.
+func parseAge(s string) (time.Duration, error) {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		// "mo" must be checked before "m"
+		{"mo", 30 * 24 * time.Hour},
+		{"m", time.Minute},
+		{"h", time.Hour},
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+		{"y", 365 * 24 * time.Hour},
+	}
+	s = strings.TrimSpace(s)
+	for _, u := range units {
+		if number, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age: %s", s)
+			}
+			return time.Duration(n) * u.unit, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid age: %s", s)
+}
+
+// ageAnnotation returns the suffix and color the branch list uses for the date
+// of a branch's last commit: nothing for recent and protected branches, the
+// date in yellow for aging ones and "stale (date)" in red for stale ones
+func ageAnnotation(localizer *i18n.Localizer, branch BranchDetail) (string, string) {
+	t := branch.ActivityDate()
+	if t.IsZero() || isProtectedBranch(branch.Name) {
+		return "", ""
+	}
+	age := time.Since(t)
+	switch {
+	case age >= ageThresholds.Stale:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "StaleIndicator",
+			TemplateData: map[string]interface{}{"Age": formatDate(localizer, t)},
+		})
+		return msg, ColorRed
+	case age >= ageThresholds.Aging:
+		return "(" + formatDate(localizer, t) + ")", ColorYellow
+	}
+	return "", ""
+}
+
+// forcePushedAnnotation returns the suffix the branch list adds to a branch
+// recently found rewritten at forcePushed, or nothing for the zero time
+func forcePushedAnnotation(localizer *i18n.Localizer, forcePushed time.Time) string {
+	if forcePushed.IsZero() {
+		return ""
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ForcePushedIndicator",
+		TemplateData: map[string]interface{}{"Age": formatDate(localizer, forcePushed)},
+	})
+	return msg
+}
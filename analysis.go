@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// BranchDetail describes the tip commit of a remote branch
+type BranchDetail struct {
+	Name          string
+	Hash          string
+	Author        string
+	AuthorEmail   string
+	Date          time.Time // author date
+	CommitterDate time.Time
+	Message       string
+}
+
+// dateField selects the date that measures a branch's last activity: the
+// committer date by default, since rebasing keeps old author dates on fresh
+// commits. Set from -date-field.
+var dateField = "committer"
+
+// ActivityDate returns the author or committer date of the tip, as selected
+// by -date-field
+func (b BranchDetail) ActivityDate() time.Time {
+	if dateField == "author" {
+		return b.Date
+	}
+	return b.CommitterDate
+}
+
+// remoteBranchFormat is the for-each-ref format used to gather every field of
+// BranchDetail in a single git invocation; fields are separated by \x1f
+const remoteBranchFormat = "%(refname:lstrip=2)%1f%(symref)%1f%(objectname)%1f%(authorname)%1f%(authoremail:trim)%1f%(authordate:unix)%1f%(committerdate:unix)%1f%(contents:subject)"
+
+// listRemoteBranches returns the details of all remote-tracking branches,
+// skipping symbolic refs such as origin/HEAD
+func listRemoteBranches() ([]BranchDetail, error) {
+	output, err := runGit("for-each-ref", "refs/remotes", "--format="+remoteBranchFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []BranchDetail
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) < 8 || fields[1] != "" || strings.HasSuffix(fields[0], "/HEAD") {
+			continue
+		}
+		branches = append(branches, BranchDetail{
+			Name:          fields[0],
+			Hash:          fields[2],
+			Author:        fields[3],
+			AuthorEmail:   fields[4],
+			Date:          parseUnixTime(fields[5]),
+			CommitterDate: parseUnixTime(fields[6]),
+			Message:       fields[7],
+		})
+	}
+	return branches, nil
+}
+
+// lookupRemoteBranch returns the details of a single remote-tracking branch
+func lookupRemoteBranch(name string) (BranchDetail, error) {
+	output, err := runGit("for-each-ref", "refs/remotes/"+name, "--format="+remoteBranchFormat)
+	if err != nil {
+		return BranchDetail{}, err
+	}
+	// The pattern also matches branches nested below name, so look for the
+	// exact ref
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) < 8 || fields[0] != name {
+			continue
+		}
+		return BranchDetail{
+			Name:          fields[0],
+			Hash:          fields[2],
+			Author:        fields[3],
+			AuthorEmail:   fields[4],
+			Date:          parseUnixTime(fields[5]),
+			CommitterDate: parseUnixTime(fields[6]),
+			Message:       fields[7],
+		}, nil
+	}
+	return BranchDetail{}, fmt.Errorf("no such remote branch: %s", name)
+}
+
+// parseUnixTime converts a unix timestamp as printed by git into a time.Time,
+// returning the zero time if it cannot be parsed
+func parseUnixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// analyzeBranches computes the merged status of each branch and its distance
+// from base, reusing cached results for tips that have not changed.
+// Uncached branches are analyzed concurrently and emit is called (serially)
+// for each branch as soon as its analysis is available.
+func analyzeBranches(branches []BranchDetail, base string, emit func(BranchDetail, BranchAnalysis)) {
+	queue := make(chan BranchDetail)
+	go func() {
+		defer close(queue)
+		for _, branch := range branches {
+			queue <- branch
+		}
+	}()
+	analyzeQueue(queue, base, emit)
+}
+
+// analyzeQueue analyzes the branches read from queue like analyzeBranches
+// until it is closed, so callers can choose which branches go first. The
+// cache keeps the analyses and tips of every remote branch, not only of the
+// ones analyzed, which may be a filtered subset.
+func analyzeQueue(queue <-chan BranchDetail, base string, emit func(BranchDetail, BranchAnalysis)) {
+	baseSHA, err := runGit("rev-parse", base)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not resolve %s: %v\n", base, err)
+	}
+
+	cache := loadAnalysisCache()
+	// Without the list of every remote branch, nothing is pruned. Tips are
+	// kept for every branch still on its remote, since they are the
+	// baseline that tells a later force push.
+	var liveSHAs, liveNames map[string]bool
+	if remoteBranches, err := listRemoteBranches(); err == nil {
+		liveSHAs = make(map[string]bool, len(remoteBranches))
+		liveNames = make(map[string]bool, len(remoteBranches))
+		for _, branch := range remoteBranches {
+			liveSHAs[branch.Hash] = true
+			liveNames[branch.Name] = true
+		}
+	}
+	var mu sync.Mutex
+
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for branch := range queue {
+		mu.Lock()
+		analysis, cached := cache.Branches[branch.Hash]
+		previous, seen := cache.Tips[branch.Name]
+		mu.Unlock()
+
+		g.Go(func() error {
+			if !cached {
+				analysis = BranchAnalysis{SHA: branch.Hash, CommitDate: branch.CommitterDate}
+			}
+			if baseSHA != "" && (!cached || analysis.MergedBase != baseSHA) {
+				merged, err := isAncestor(branch.Hash, baseSHA)
+				var counts AheadBehind
+				if err == nil {
+					counts, err = countAheadBehind(branch.Hash, baseSHA)
+				}
+				if err != nil {
+					// Log error but continue, as this is not critical
+					fmt.Fprintf(os.Stderr, "Warning: Could not get merged status of %s: %v\n", branch.Name, err)
+				} else {
+					analysis.Merged = merged
+					analysis.AheadBehind = counts
+					analysis.MergedBase = baseSHA
+				}
+			}
+
+			// A tip that does not descend from the previous one means the
+			// branch was rewritten, usually by someone still working on it.
+			// An old tip that is no longer in the repository tells nothing.
+			tip := BranchTip{SHA: branch.Hash, ForcePushed: previous.ForcePushed}
+			if seen && previous.SHA != branch.Hash {
+				if descends, err := isAncestor(previous.SHA, branch.Hash); err == nil && !descends {
+					tip.ForcePushed = time.Now().UTC().Truncate(time.Second)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			cache.Branches[branch.Hash] = analysis
+			cache.Tips[branch.Name] = tip
+			if time.Since(tip.ForcePushed) < forcePushedRecency {
+				analysis.ForcePushed = tip.ForcePushed
+			}
+			emit(branch, analysis)
+			return nil
+		})
+	}
+	g.Wait()
+
+	if err := cache.Save(liveSHAs, liveNames); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not save analysis cache: %v\n", err)
+	}
+}
+
+// readBranchNames reads remote branch names, one per line, as printed by
+// `git branch -r` or by this tool. Blank lines and symbolic refs
+// ("origin/HEAD -> origin/main") are skipped.
+func readBranchNames(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := cleanBranchName(scanner.Text())
+		if name == "" || strings.Contains(name, " -> ") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiRequest is one command read by `api`, one JSON object per line
+type apiRequest struct {
+	// ID is echoed in the response so clients can match them up
+	ID       json.RawMessage `json:"id,omitempty"`
+	Command  string          `json:"command"`
+	Filter   string          `json:"filter,omitempty"`
+	Branches []string        `json:"branches,omitempty"`
+	// Confirm is the token returned by an unconfirmed delete of the same
+	// branches; only a delete that carries it deletes anything
+	Confirm string `json:"confirm,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+}
+
+// apiResponse is written for every request, on a single line
+type apiResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	OK     bool            `json:"ok"`
+	Error  string          `json:"error,omitempty"`
+	Result interface{}     `json:"result,omitempty"`
+}
+
+// apiAnalysis is a branch as described by the analyze command: its listing
+// plus the commits the base lacks and how much it adds
+type apiAnalysis struct {
+	apiBranch
+	Commits []uniqueCommit `json:"commits"`
+	// Squashed is true when the base has the content of every file the
+	// branch changed
+	Squashed     bool  `json:"squashed"`
+	SafeToDelete bool  `json:"safeToDelete"`
+	Files        int   `json:"files"`
+	Insertions   int   `json:"insertions"`
+	Deletions    int   `json:"deletions"`
+	DiskUsage    int64 `json:"diskUsage"`
+}
+
+// apiDeletePreview is the result of a delete without a confirmation token
+type apiDeletePreview struct {
+	Confirm  string             `json:"confirm"`
+	Branches []string           `json:"branches"`
+	Skipped  []apiSkippedBranch `json:"skipped"`
+}
+
+// runAPI answers the JSON commands read from in until it ends. Nothing else
+// is ever written to out, and nothing is ever asked: deleting takes two
+// requests, the second repeating the token the first one returned.
+func runAPI(in io.Reader, out io.Writer, base string, options deleteOptions) error {
+	encoder := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxPlanSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var request apiRequest
+		response := apiResponse{OK: true}
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			response = apiResponse{Error: err.Error()}
+		} else {
+			response.ID = request.ID
+			if result, err := handleAPIRequest(request, base, options); err != nil {
+				response.OK = false
+				response.Error = err.Error()
+			} else {
+				response.Result = result
+			}
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handleAPIRequest runs one command of `api`
+func handleAPIRequest(request apiRequest, base string, options deleteOptions) (interface{}, error) {
+	switch request.Command {
+	case "list":
+		return listAPIBranches(base, request.Filter)
+	case "analyze":
+		return analyzeAPIBranches(request.Branches, base)
+	case "delete":
+		return deleteAPIBranches(request.Branches, request.Confirm, options)
+	case "history":
+		entries, err := readHistory()
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		if request.Limit > 0 && len(entries) > request.Limit {
+			entries = entries[:request.Limit]
+		}
+		return entries, err
+	case "":
+		return nil, fmt.Errorf("missing command")
+	default:
+		return nil, fmt.Errorf("unknown command %q", request.Command)
+	}
+}
+
+// lookupAPIBranches returns the details of the named remote branches
+func lookupAPIBranches(names []string) ([]BranchDetail, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no branches given")
+	}
+	branches := make([]BranchDetail, 0, len(names))
+	for _, name := range names {
+		branch, err := lookupRemoteBranch(name)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+// analyzeAPIBranches explains each of the named branches like the explain
+// command and estimates its size
+func analyzeAPIBranches(names []string, base string) ([]apiAnalysis, error) {
+	branches, err := lookupAPIBranches(names)
+	if err != nil {
+		return nil, err
+	}
+	analyses := map[string]BranchAnalysis{}
+	analyzeBranches(branches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		analyses[branch.Name] = analysis
+	})
+
+	results := make([]apiAnalysis, 0, len(branches))
+	for _, branch := range branches {
+		ref, err := remoteBranchRef(branch.Name)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := listUniqueCommits(ref, base)
+		if err != nil {
+			return nil, err
+		}
+		result := apiAnalysis{apiBranch: newAPIBranch(branch, analyses[branch.Name]), Commits: commits}
+		if result.Commits == nil {
+			result.Commits = []uniqueCommit{}
+		}
+		result.Squashed, _ = isContentInBase(ref, base)
+		applied := 0
+		for _, commit := range commits {
+			if commit.Applied {
+				applied++
+			}
+		}
+		result.SafeToDelete = result.Merged || result.Squashed || applied == len(commits)
+		if size, err := estimateBranchSize(branch.Hash, base); err == nil {
+			result.Files, result.Insertions, result.Deletions, result.DiskUsage = size.Files, size.Insertions, size.Deletions, size.DiskUsage
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// deletionToken identifies a set of branches at given tips: a delete is only
+// carried out when its token matches the one returned for the same branches,
+// so nothing moved in between
+func deletionToken(branches []string, tips map[string]string) string {
+	sorted := append([]string{}, branches...)
+	sort.Strings(sorted)
+	hash := sha256.New()
+	for _, branch := range sorted {
+		fmt.Fprintf(hash, "%s %s\n", branch, tips[branch])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// deleteAPIBranches checks the named branches like apply checks a plan. It
+// returns a confirmation token for the deletable ones, and deletes them when
+// given the token that matches them.
+func deleteAPIBranches(names []string, confirm string, options deleteOptions) (interface{}, error) {
+	branches, err := lookupAPIBranches(names)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Version: planVersion, Created: time.Now().UTC()}
+	for _, branch := range branches {
+		plan.Branches = append(plan.Branches, PlannedBranch{Name: branch.Name, SHA: branch.Hash})
+	}
+	toDelete, skipped, err := checkPlanDeletions(plan)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeletionLimit(toDelete, options); err != nil {
+		return nil, err
+	}
+	tips := planTips(plan)
+	token := deletionToken(toDelete, tips)
+
+	if confirm == "" {
+		return apiDeletePreview{Confirm: token, Branches: append([]string{}, toDelete...), Skipped: skipped}, nil
+	}
+	if confirm != token {
+		return nil, fmt.Errorf("confirmation token does not match the branches to delete; request a new one")
+	}
+	result := deleteWithoutAsking(toDelete, tips, options, "api")
+	result.Skipped = skipped
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/atotto/clipboard"
+	"github.com/kballard/go-shellquote"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// fzfBindingVersion is the first fzf release with the change-preview action
+var fzfBindingVersion = [2]int{0, 36}
+
+// fzfBindings returns the --bind options that act on the highlighted branch
+// without leaving fzf: switching the preview between its modes, opening the
+// branch's pull request or its page, copying its name, and, instead of
+// deleting it, opening a pull request for it or merging it into the
+// checked-out branch. The actions run the tool
+// itself like the preview does, with the same flags. Bindings in -fzf-opts
+// come later, so they override these.
+func fzfBindings(localizer *i18n.Localizer, executablePath string, args []string) []string {
+	action := func(name string) string {
+		return shellquote.Join(slices.Concat([]string{executablePath}, args, []string{"-finder-action", name})...) + " {}"
+	}
+	preview := func(mode string) string {
+		return previewCommand(executablePath, slices.Concat(args, []string{"-preview", mode})...)
+	}
+	header, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "FinderBindingsHeader"})
+	return []string{
+		"--bind", "alt-l:change-preview:" + preview("log"),
+		"--bind", "alt-s:change-preview:" + preview("diffstat"),
+		"--bind", "alt-e:change-preview:" + preview("explain"),
+		"--bind", "ctrl-o:execute-silent:" + action("open-pr"),
+		"--bind", "alt-o:execute-silent:" + action("open-branch"),
+		"--bind", "alt-c:execute-silent:" + action("copy-name"),
+		// gh and glab take over the terminal to ask for what they need
+		"--bind", "alt-p:execute:" + action("create-pr"),
+		"--bind", "alt-m:execute:" + action("merge"),
+		"--header", header,
+	}
+}
+
+// runFinderAction runs a key binding of fzfBindings on the branch of a
+// finder line. Except for create-pr and merge, its output is not shown, so
+// failures only go to the log.
+func runFinderAction(action, line, base, hosting string) error {
+	branch := cleanBranchName(line)
+	switch action {
+	case "copy-name":
+		return clipboard.WriteAll(branch)
+	case "open-pr":
+		// The key asks for the pull request, so it is looked up even
+		// without -hosting
+		if hosting == "off" {
+			hosting = "auto"
+		}
+		pulls := lookupPullRequests([]string{branch}, hosting)[branch].PullRequests
+		if len(pulls) == 0 {
+			return errors.New("no pull request found")
+		}
+		return openBrowser(pulls[0].URL)
+	case "open-branch":
+		pageURL, err := branchWebURL(branch, hosting)
+		if err != nil {
+			return err
+		}
+		return openBrowser(pageURL)
+	case "create-pr":
+		return openPullRequest(branch, base, hosting)
+	case "merge":
+		output, err := mergeLocally(branch)
+		fmt.Print(output)
+		return err
+	}
+	return fmt.Errorf("unknown finder action: %s", action)
+}
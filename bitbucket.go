@@ -0,0 +1,204 @@
+package main
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// bitbucketCloudProvider uses the Bitbucket Cloud REST API (bitbucket.org).
+// Credentials are read from BITBUCKET_TOKEN (an access token) or from
+// BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD, which are only sent to
+// api.bitbucket.org.
+type bitbucketCloudProvider struct {
+	apiURL  string
+	repo    RemoteRepo
+	headers map[string]string
+}
+
+func newBitbucketCloudProvider(repo RemoteRepo) *bitbucketCloudProvider {
+	headers := map[string]string{}
+	if token := os.Getenv("BITBUCKET_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	} else if user, password := os.Getenv("BITBUCKET_USERNAME"), os.Getenv("BITBUCKET_APP_PASSWORD"); user != "" && password != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	} else if token := profileToken(repo); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return &bitbucketCloudProvider{apiURL: "https://api.bitbucket.org/2.0", repo: repo, headers: headers}
+}
+
+func (p *bitbucketCloudProvider) Name() string { return "bitbucket" }
+
+func (p *bitbucketCloudProvider) BranchURL(branch string) string {
+	return "https://" + p.repo.Host + "/" + p.repo.Path + "/branch/" + escapeBranchPath(branch)
+}
+
+func (p *bitbucketCloudProvider) PullRequests(branch string) ([]PullRequest, error) {
+	query := url.Values{
+		"q":     {fmt.Sprintf("source.branch.name=%q", branch)},
+		"state": {"OPEN", "MERGED", "DECLINED", "SUPERSEDED"},
+	}
+	requestURL := fmt.Sprintf("%s/repositories/%s/pullrequests?%s", p.apiURL, p.repo.Path, query.Encode())
+
+	var page struct {
+		Values []struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+			State string `json:"state"`
+			Links struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		} `json:"values"`
+	}
+	if err := getJSON(requestURL, p.headers, &page); err != nil {
+		return nil, err
+	}
+
+	var result []PullRequest
+	for _, pull := range page.Values {
+		result = append(result, PullRequest{Number: pull.ID, Title: pull.Title, State: bitbucketPullRequestState(pull.State), URL: pull.Links.HTML.Href})
+	}
+	return result, nil
+}
+
+// ProtectedBranchPatterns returns the glob patterns of the repository's
+// branch restrictions that prevent deletion
+func (p *bitbucketCloudProvider) ProtectedBranchPatterns() ([]string, error) {
+	requestURL := fmt.Sprintf("%s/repositories/%s/branch-restrictions?kind=delete&pagelen=100", p.apiURL, p.repo.Path)
+
+	var page struct {
+		Values []struct {
+			BranchMatchKind string `json:"branch_match_kind"`
+			Pattern         string `json:"pattern"`
+		} `json:"values"`
+	}
+	if err := getJSON(requestURL, p.headers, &page); err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, restriction := range page.Values {
+		// Restrictions on branching model types (e.g. "release") have no pattern
+		if restriction.BranchMatchKind == "glob" && restriction.Pattern != "" {
+			patterns = append(patterns, restriction.Pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// bitbucketServerProvider uses the REST API of a Bitbucket Server or Data
+// Center instance. The token is read from BITBUCKET_TOKEN (an HTTP access
+// token) for the instance named by BITBUCKET_HOST, or else the matching
+// configuration profile.
+type bitbucketServerProvider struct {
+	baseURL string
+	project string
+	slug    string
+	headers map[string]string
+}
+
+func newBitbucketServerProvider(repo RemoteRepo) (*bitbucketServerProvider, error) {
+	// Clone URLs look like https://host/scm/PROJECT/repo.git or
+	// ssh://git@host:7999/project/repo.git
+	project, slug, ok := strings.Cut(strings.TrimPrefix(repo.Path, "scm/"), "/")
+	if !ok || strings.Contains(slug, "/") {
+		return nil, fmt.Errorf("unsupported Bitbucket Server repository path: %s", repo.Path)
+	}
+	// Bitbucket Cloud never gets here, so only BITBUCKET_HOST can match
+	var token string
+	if envTokenHost(repo, "bitbucket.org", "BITBUCKET_HOST") {
+		token = os.Getenv("BITBUCKET_TOKEN")
+	}
+	headers := map[string]string{}
+	if token := cmp.Or(token, profileToken(repo)); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return &bitbucketServerProvider{baseURL: "https://" + repo.Host, project: project, slug: slug, headers: headers}, nil
+}
+
+func (p *bitbucketServerProvider) Name() string { return "bitbucket" }
+
+func (p *bitbucketServerProvider) BranchURL(branch string) string {
+	return fmt.Sprintf("%s/projects/%s/repos/%s/browse?at=%s", p.baseURL, p.project, p.slug, url.QueryEscape("refs/heads/"+branch))
+}
+
+func (p *bitbucketServerProvider) PullRequests(branch string) ([]PullRequest, error) {
+	requestURL := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests?state=ALL&direction=OUTGOING&at=%s",
+		p.baseURL, url.PathEscape(p.project), url.PathEscape(p.slug), url.QueryEscape("refs/heads/"+branch))
+
+	var page struct {
+		Values []struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+			State string `json:"state"`
+			Links struct {
+				Self []struct {
+					Href string `json:"href"`
+				} `json:"self"`
+			} `json:"links"`
+		} `json:"values"`
+	}
+	if err := getJSON(requestURL, p.headers, &page); err != nil {
+		return nil, err
+	}
+
+	var result []PullRequest
+	for _, pull := range page.Values {
+		pullRequest := PullRequest{Number: pull.ID, Title: pull.Title, State: bitbucketPullRequestState(pull.State)}
+		if len(pull.Links.Self) > 0 {
+			pullRequest.URL = pull.Links.Self[0].Href
+		}
+		result = append(result, pullRequest)
+	}
+	return result, nil
+}
+
+// ProtectedBranchPatterns returns the branches and patterns of the
+// repository's "no-deletes" branch permissions
+func (p *bitbucketServerProvider) ProtectedBranchPatterns() ([]string, error) {
+	requestURL := fmt.Sprintf("%s/rest/branch-permissions/2.0/projects/%s/repos/%s/restrictions?type=no-deletes&limit=1000",
+		p.baseURL, url.PathEscape(p.project), url.PathEscape(p.slug))
+
+	var page struct {
+		Values []struct {
+			Matcher struct {
+				ID   string `json:"id"`
+				Type struct {
+					ID string `json:"id"`
+				} `json:"type"`
+			} `json:"matcher"`
+		} `json:"values"`
+	}
+	if err := getJSON(requestURL, p.headers, &page); err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, restriction := range page.Values {
+		switch restriction.Matcher.Type.ID {
+		case "BRANCH":
+			patterns = append(patterns, strings.TrimPrefix(restriction.Matcher.ID, "refs/heads/"))
+		case "PATTERN":
+			patterns = append(patterns, restriction.Matcher.ID)
+		}
+	}
+	return patterns, nil
+}
+
+// bitbucketPullRequestState maps Bitbucket's OPEN, MERGED, DECLINED and
+// SUPERSEDED states to open, merged and closed
+func bitbucketPullRequestState(state string) string {
+	switch state {
+	case "OPEN":
+		return "open"
+	case "MERGED":
+		return "merged"
+	}
+	return "closed"
+}
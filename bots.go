@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// defaultBotPatterns match the branches that dependency bots and automated
+// reverts and backports push, which tend to pile up once their pull requests
+// are closed
+var defaultBotPatterns = []string{"dependabot/*", "renovate/*", "revert-*", "backport/*"}
+
+var (
+	botPatternsOnce sync.Once
+	botPatterns     []string
+)
+
+// loadBotPatterns returns the glob patterns of bot branches: the defaults and
+// every `grbm.bot` value from git config
+func loadBotPatterns() []string {
+	botPatternsOnce.Do(func() {
+		botPatterns = append([]string{}, defaultBotPatterns...)
+		output, err := runGit("config", "--get-all", "grbm.bot")
+		if err != nil {
+			return
+		}
+		for _, pattern := range strings.Split(output, "\n") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				botPatterns = append(botPatterns, pattern)
+			}
+		}
+	})
+	return botPatterns
+}
+
+// botPattern returns the pattern that classifies a branch as pushed by a bot
+func botPattern(branchName string) (string, bool) {
+	for _, pattern := range loadBotPatterns() {
+		if matchesBranchPattern(pattern, branchName) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// onlyBots returns the branches pushed by bots, for -bots-only
+func onlyBots(branches []BranchDetail) []BranchDetail {
+	var bots []BranchDetail
+	for _, branch := range branches {
+		if _, ok := botPattern(branch.Name); ok {
+			bots = append(bots, branch)
+		}
+	}
+	return bots
+}
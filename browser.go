@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// openBrowser opens url in $BROWSER, or else in the platform's default
+// browser. It returns once the browser has been started.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch {
+	case os.Getenv("BROWSER") != "":
+		cmd = exec.Command(os.Getenv("BROWSER"), url)
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("open", url)
+	case runtime.GOOS == "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// branchWebURL returns the web page of a remote branch on the hosting
+// provider of its remote. -hosting off detects the provider from the
+// remote's host name.
+func branchWebURL(branch, hosting string) (string, error) {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return "", fmt.Errorf("invalid branch format: %s", branch)
+	}
+	if hosting == "" || hosting == "off" {
+		hosting = "auto"
+	}
+	provider, err := newHostingProvider(hosting, remoteName)
+	if err != nil {
+		return "", err
+	}
+	return provider.BranchURL(branchName), nil
+}
+
+// openBranchPage opens the web page of a remote branch in the browser,
+// printing its URL so it can be opened by hand where no browser starts
+func openBranchPage(localizer *i18n.Localizer, branch, hosting string) error {
+	pageURL, err := branchWebURL(branch, hosting)
+	if err != nil {
+		return err
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "OpeningBranchPage",
+		TemplateData: map[string]interface{}{"Branch": branch, "URL": pageURL},
+	})
+	fmt.Println(msg)
+	return openBrowser(pageURL)
+}
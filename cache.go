@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// cacheVersion is bumped whenever the layout of the cache file changes so
+// that stale caches are discarded instead of misread
+const cacheVersion = 2
+
+// BranchAnalysis is the per-branch result of analyzing a remote branch tip
+type BranchAnalysis struct {
+	SHA        string    `json:"sha"`
+	CommitDate time.Time `json:"commitDate"`
+	Merged     bool      `json:"merged"`
+	// MergedBase is the SHA of the base commit the merged status and the
+	// ahead/behind counts were computed against
+	MergedBase string `json:"mergedBase"`
+	AheadBehind
+	// ForcePushed is when the branch was found rewritten, if that was within
+	// forcePushedRecency. It belongs to the branch rather than its tip, so it
+	// is kept in AnalysisCache.Tips.
+	ForcePushed time.Time `json:"-"`
+}
+
+// forcePushedRecency is how long a branch found rewritten is marked as
+// recently force-pushed
+const forcePushedRecency = 14 * 24 * time.Hour
+
+// BranchTip is the tip a branch had when it was last analyzed
+type BranchTip struct {
+	SHA string `json:"sha"`
+	// ForcePushed is when the branch was last found at a tip that does not
+	// descend from the previous one
+	ForcePushed time.Time `json:"forcePushed,omitzero"`
+}
+
+// AnalysisCache persists branch analyses between runs, keyed by tip SHA, and
+// the tip of every branch, keyed by name, to tell when one was rewritten
+type AnalysisCache struct {
+	Version  int                       `json:"version"`
+	Branches map[string]BranchAnalysis `json:"branches"`
+	Tips     map[string]BranchTip      `json:"tips"`
+
+	path string
+}
+
+// cachePath returns the location of the analysis cache inside the git directory
+func cachePath() (string, error) {
+	gitDir, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "grbm", "cache.json"), nil
+}
+
+// loadAnalysisCache reads the analysis cache, returning an empty cache if it
+// does not exist or cannot be parsed
+func loadAnalysisCache() *AnalysisCache {
+	cache := &AnalysisCache{Version: cacheVersion, Branches: map[string]BranchAnalysis{}, Tips: map[string]BranchTip{}}
+	path, err := cachePath()
+	if err != nil {
+		return cache
+	}
+	cache.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	var stored AnalysisCache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != cacheVersion || stored.Branches == nil {
+		return cache
+	}
+	cache.Branches = stored.Branches
+	if stored.Tips != nil {
+		cache.Tips = stored.Tips
+	}
+	return cache
+}
+
+// Save writes the cache back to disk, keeping only the given tip SHAs and
+// branch names so the file does not grow with branches that no longer exist.
+// Nil keeps every entry.
+func (c *AnalysisCache) Save(liveSHAs, liveNames map[string]bool) error {
+	if c.path == "" {
+		return nil
+	}
+	for sha := range c.Branches {
+		if liveSHAs != nil && !liveSHAs[sha] {
+			delete(c.Branches, sha)
+		}
+	}
+	for name := range c.Tips {
+		if liveNames != nil && !liveNames[name] {
+			delete(c.Tips, name)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// previewCacheVersion is the cacheVersion of the preview cache
+const previewCacheVersion = 2
+
+// previewCacheLimit bounds the number of ahead/behind entries kept; the
+// preview process cannot tell which tips are still alive, so the cache is
+// simply reset when it grows past this
+const previewCacheLimit = 5000
+
+// pullRequestCacheTTL is how long a pull request lookup is reused, set from
+// -pr-cache-ttl. Hosting APIs rate-limit their clients, and a lookup per
+// branch adds up quickly on repeated runs.
+var pullRequestCacheTTL = 5 * time.Minute
+
+// AheadBehind counts the commits a branch has that its base lacks (Ahead) and
+// the other way around (Behind)
+type AheadBehind struct {
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+}
+
+// cachedPullRequests is a pull request lookup and when it was made
+type cachedPullRequests struct {
+	Fetched      time.Time     `json:"fetched"`
+	PullRequests []PullRequest `json:"pullRequests"`
+}
+
+// PreviewCache persists the lookups of the finder's preview command, which
+// runs as a new process for every highlighted line. Ahead/behind counts and
+// sizes are keyed by "<tip SHA>...<base SHA>" and never change; pull requests
+// are keyed by hosting provider and branch name and expire after
+// pullRequestCacheTTL.
+type PreviewCache struct {
+	Version      int                           `json:"version"`
+	AheadBehind  map[string]AheadBehind        `json:"aheadBehind"`
+	Sizes        map[string]BranchSize         `json:"sizes"`
+	PullRequests map[string]cachedPullRequests `json:"pullRequests"`
+
+	path string
+}
+
+// loadPreviewCache reads the preview cache, returning an empty cache if it
+// does not exist or cannot be parsed
+func loadPreviewCache() *PreviewCache {
+	cache := &PreviewCache{Version: previewCacheVersion, AheadBehind: map[string]AheadBehind{}, Sizes: map[string]BranchSize{}, PullRequests: map[string]cachedPullRequests{}}
+	path, err := previewCachePath()
+	if err != nil {
+		return cache
+	}
+	cache.path = path
+
+	data, err := os.ReadFile(cache.path)
+	if err != nil {
+		return cache
+	}
+	var stored PreviewCache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != previewCacheVersion {
+		return cache
+	}
+	if stored.AheadBehind != nil && len(stored.AheadBehind) < previewCacheLimit {
+		cache.AheadBehind = stored.AheadBehind
+	}
+	if stored.Sizes != nil && len(stored.Sizes) < previewCacheLimit {
+		cache.Sizes = stored.Sizes
+	}
+	for branch, cached := range stored.PullRequests {
+		if time.Since(cached.Fetched) < pullRequestCacheTTL {
+			cache.PullRequests[branch] = cached
+		}
+	}
+	return cache
+}
+
+// Save writes the cache back to disk. The file is replaced atomically since
+// the finder may start a new preview while the previous one is still writing.
+func (c *PreviewCache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "preview-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// pullRequestCacheKey keys the lookups of branch by the -hosting they were
+// made with, since another provider may find other pull requests
+func pullRequestCacheKey(hosting, branch string) string {
+	return hosting + " " + branch
+}
+
+// pullRequests returns the cached lookup of branch's pull requests on
+// hosting, if any. It may be called on a nil cache.
+func (c *PreviewCache) pullRequests(hosting, branch string) (cachedPullRequests, bool) {
+	if c == nil {
+		return cachedPullRequests{}, false
+	}
+	cached, ok := c.PullRequests[pullRequestCacheKey(hosting, branch)]
+	return cached, ok
+}
+
+// storePullRequests caches a lookup of branch's pull requests on hosting made
+// just now
+func (c *PreviewCache) storePullRequests(hosting, branch string, pulls []PullRequest) {
+	c.PullRequests[pullRequestCacheKey(hosting, branch)] = cachedPullRequests{Fetched: time.Now(), PullRequests: pulls}
+}
+
+// pullRequestsCachedMessage says how old a cached pull request lookup made at
+// fetched is, and how to refresh it. Lookups under a minute old are as good
+// as fresh and say nothing.
+func pullRequestsCachedMessage(localizer *i18n.Localizer, fetched time.Time) string {
+	if fetched.IsZero() || time.Since(fetched) < time.Minute {
+		return ""
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "PullRequestsCached",
+		TemplateData: map[string]interface{}{"Age": relativeDate(localizer, fetched)},
+	})
+	return msg
+}
+
+// pullRequestsCachedNote is pullRequestsCachedMessage for the oldest lookup
+// of the inventory
+func pullRequestsCachedNote(localizer *i18n.Localizer, inventory []InventoryBranch) string {
+	var oldest time.Time
+	for _, branch := range inventory {
+		if !branch.PullFetched.IsZero() && (oldest.IsZero() || branch.PullFetched.Before(oldest)) {
+			oldest = branch.PullFetched
+		}
+	}
+	return pullRequestsCachedMessage(localizer, oldest)
+}
+
+// refreshPullRequestCache drops every cached pull request lookup, for
+// -refresh-prs, so this run and the previews it starts look them all up again
+func refreshPullRequestCache() error {
+	cache := loadPreviewCache()
+	if len(cache.PullRequests) == 0 {
+		return nil
+	}
+	clear(cache.PullRequests)
+	return cache.Save()
+}
+
+// previewCachePath returns the location of the preview cache inside the git
+// directory
+func previewCachePath() (string, error) {
+	gitDir, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "grbm", "preview.json"), nil
+}
+
+// CacheStatus describes one of the cache files for `cache status`
+type CacheStatus struct {
+	Name    string     `json:"name"`
+	Path    string     `json:"path"`
+	Exists  bool       `json:"exists"`
+	Size    int64      `json:"size"`
+	Updated *time.Time `json:"updated,omitempty"`
+	// Outdated is true for a file of another cache version, which the next
+	// run discards
+	Outdated bool `json:"outdated"`
+	// Entries counts the cached lookups by kind
+	Entries map[string]int `json:"entries"`
+	// Live is the number of branch analyses for tips that remote branches
+	// point at now; the others are left over from rewritten or deleted
+	// branches
+	Live int `json:"live,omitempty"`
+}
+
+// cacheStatuses inspects the analysis and preview caches without loading
+// them the way a run does, so outdated or unreadable files show as such
+func cacheStatuses() ([]CacheStatus, error) {
+	analysisPath, err := cachePath()
+	if err != nil {
+		return nil, err
+	}
+	previewPath, err := previewCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := CacheStatus{Name: "analysis", Path: analysisPath, Entries: map[string]int{}}
+	var stored AnalysisCache
+	if readCacheFile(&analysis, &stored) {
+		analysis.Outdated = stored.Version != cacheVersion
+		analysis.Entries["branches"] = len(stored.Branches)
+		tips := map[string]bool{}
+		for _, sha := range remoteTips() {
+			tips[sha] = true
+		}
+		for sha := range stored.Branches {
+			if tips[sha] {
+				analysis.Live++
+			}
+		}
+	}
+
+	preview := CacheStatus{Name: "preview", Path: previewPath, Entries: map[string]int{}}
+	var storedPreview PreviewCache
+	if readCacheFile(&preview, &storedPreview) {
+		preview.Outdated = storedPreview.Version != previewCacheVersion
+		preview.Entries["aheadBehind"] = len(storedPreview.AheadBehind)
+		preview.Entries["sizes"] = len(storedPreview.Sizes)
+		preview.Entries["pullRequests"] = len(storedPreview.PullRequests)
+	}
+	return []CacheStatus{analysis, preview}, nil
+}
+
+// readCacheFile fills in the file details of status and decodes the file
+// into v. It reports whether the file could be decoded; one that cannot is
+// marked outdated, since a run starts over from an empty cache too.
+func readCacheFile(status *CacheStatus, v interface{}) bool {
+	info, err := os.Stat(status.Path)
+	if err != nil {
+		return false
+	}
+	status.Exists = true
+	status.Size = info.Size()
+	updated := info.ModTime()
+	status.Updated = &updated
+	data, err := os.ReadFile(status.Path)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		status.Outdated = true
+		return false
+	}
+	return true
+}
+
+// runCacheCommand implements `cache status`, which describes the analysis
+// and preview caches, and `cache clear`, which removes them so that the next
+// run analyzes every branch again, e.g. after history was rewritten
+func runCacheCommand(localizer *i18n.Localizer, args []string, asJSON bool) error {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	if len(args) != 1 || (args[0] != "status" && args[0] != "clear") {
+		return errors.New(localize("CacheUsage", map[string]interface{}{"Program": programName()}))
+	}
+	statuses, err := cacheStatuses()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "clear" {
+		cleared := false
+		for _, status := range statuses {
+			if !status.Exists {
+				continue
+			}
+			if err := os.Remove(status.Path); err != nil {
+				return err
+			}
+			cleared = true
+			fmt.Println(localize("CacheCleared", map[string]interface{}{"Path": status.Path, "Size": formatBytes(status.Size)}))
+		}
+		if !cleared {
+			fmt.Println(localize("CacheNothingToClear", nil))
+		}
+		return nil
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	}
+	for _, status := range statuses {
+		data := map[string]interface{}{"Path": status.Path}
+		if !status.Exists {
+			fmt.Println(localize("CacheMissing", data))
+			continue
+		}
+		data["Size"] = formatBytes(status.Size)
+		data["Updated"] = formatDate(localizer, *status.Updated)
+		fmt.Println(localize("CacheFile", data))
+		switch {
+		case status.Outdated:
+			fmt.Printf("  %s%s%s\n", ColorYellow, localize("CacheOutdated", nil), ColorReset)
+		case status.Name == "analysis":
+			fmt.Println("  " + localize("CacheAnalysisEntries", map[string]interface{}{"Count": status.Entries["branches"], "Live": status.Live}))
+		default:
+			fmt.Println("  " + localize("CachePreviewEntries", map[string]interface{}{"AheadBehind": status.Entries["aheadBehind"], "Sizes": status.Entries["sizes"], "PullRequests": status.Entries["pullRequests"]}))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// checkoutBranch checks out the local branch for a remote branch, creating a
+// local tracking branch first if none exists
+func checkoutBranch(localizer *i18n.Localizer, remoteBranch string) {
+	_, branchName, ok := splitRemoteBranch(remoteBranch)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid branch format: %s\n", remoteBranch)
+		os.Exit(1)
+	}
+
+	var cmd []string
+	if _, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+branchName); err == nil {
+		cmd = []string{"checkout", branchName}
+	} else {
+		cmd = []string{"checkout", "--track", remoteBranch}
+	}
+
+	checkoutCmd := gitCommand(cmd...)
+	checkoutCmd.Stdout = os.Stdout
+	checkoutCmd.Stderr = os.Stderr
+	if err := checkoutCmd.Run(); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "CheckoutFailed",
+			TemplateData: map[string]interface{}{"Branch": remoteBranch, "Error": err},
+		})
+		fmt.Println(msg)
+		os.Exit(1)
+	}
+}
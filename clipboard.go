@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// copyBranchNames copies the branch names to the clipboard, one per line
+func copyBranchNames(localizer *i18n.Localizer, names []string) {
+	if len(names) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
+		fmt.Println(msg)
+		os.Exit(0)
+	}
+	if err := clipboard.WriteAll(strings.Join(names, "\n")); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "CopyFailed",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Println(msg)
+		os.Exit(1)
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "CopySucceeded",
+		TemplateData: map[string]interface{}{"Count": len(names)},
+	})
+	fmt.Println(msg)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/sync/errgroup"
+)
+
+// BranchCluster is a set of remote branches cut from the same commit of the
+// base, e.g. every branch started from a release
+type BranchCluster struct {
+	ForkPoint string    `json:"forkPoint"`
+	Describe  string    `json:"describe,omitempty"`
+	Subject   string    `json:"subject"`
+	Date      time.Time `json:"date"`
+	Branches  []string  `json:"branches"`
+}
+
+// label names the cluster by the tag it was cut from when there is one, or
+// else by its fork point's commit
+func (c BranchCluster) label() string {
+	if c.Describe != "" {
+		return c.Describe
+	}
+	return shortSHA(c.ForkPoint)
+}
+
+// findClusters groups the remote branches by their merge base with base.
+// Branches already merged into base have no fork point of their own and are
+// left out, as are the base, protected branches and the trash. Clusters of
+// a single branch are dropped; the most recent fork point comes first.
+func findClusters(remoteBranches []BranchDetail, base string) ([]BranchCluster, error) {
+	baseSHA, err := runGit("rev-parse", base)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	byForkPoint := map[string][]string{}
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for _, branch := range withoutTrash(remoteBranches) {
+		if branch.Name == base || isProtectedBranch(branch.Name) {
+			continue
+		}
+		g.Go(func() error {
+			forkPoint, err := runGit("merge-base", baseSHA, branch.Hash)
+			if err != nil || forkPoint == branch.Hash {
+				// Unrelated histories have no merge base
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			byForkPoint[forkPoint] = append(byForkPoint[forkPoint], branch.Name)
+			return nil
+		})
+	}
+	g.Wait()
+
+	var clusters []BranchCluster
+	for forkPoint, branches := range byForkPoint {
+		if len(branches) < 2 {
+			continue
+		}
+		sort.Strings(branches)
+		cluster := BranchCluster{ForkPoint: forkPoint, Branches: branches}
+		if output, err := runGit("log", "-1", "--format=%ct%x1f%s", forkPoint); err == nil {
+			date, subject, _ := strings.Cut(output, "\x1f")
+			cluster.Date, cluster.Subject = parseUnixTime(date), subject
+		}
+		cluster.Describe, _ = runGit("describe", "--tags", forkPoint)
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if !clusters[i].Date.Equal(clusters[j].Date) {
+			return clusters[i].Date.After(clusters[j].Date)
+		}
+		return clusters[i].ForkPoint < clusters[j].ForkPoint
+	})
+	return clusters, nil
+}
+
+// printClusters lists the clusters, or prints them as JSON
+func printClusters(localizer *i18n.Localizer, clusters []BranchCluster, asJSON bool) error {
+	if asJSON {
+		if clusters == nil {
+			clusters = []BranchCluster{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(clusters)
+	}
+
+	if len(clusters) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoClusters"})
+		fmt.Println(msg)
+		return nil
+	}
+	for _, cluster := range clusters {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "ClusterHeader",
+			TemplateData: map[string]interface{}{"Label": cluster.label(), "Subject": cluster.Subject, "Date": formatDate(localizer, cluster.Date), "Count": len(cluster.Branches)},
+		})
+		fmt.Println(msg)
+		for _, branch := range cluster.Branches {
+			fmt.Printf("  %s\n", branch)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// chooseClusterToDelete asks which cluster to delete as a whole and returns
+// its branches, or nil when none is chosen
+func chooseClusterToDelete(localizer *i18n.Localizer, clusters []BranchCluster) ([]string, error) {
+	var options []string
+	for _, cluster := range clusters {
+		option, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "ClusterOption",
+			TemplateData: map[string]interface{}{"Label": cluster.label(), "Subject": cluster.Subject, "Count": len(cluster.Branches)},
+		})
+		options = append(options, option)
+	}
+	none, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ClusterDeleteNone"})
+	options = append(options, none)
+	message, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ClusterDeletePrompt"})
+
+	var index int
+	if plainMode {
+		index = askPlainChoice(message, options)
+	} else if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &index, terminalAskOpts()...); err != nil {
+		return nil, err
+	}
+	if index < 0 || index == len(clusters) {
+		return nil, nil
+	}
+	return clusters[index].Branches, nil
+}
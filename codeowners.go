@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// codeownersTeam is the owner, as named in CODEOWNERS (e.g. "@acme/platform"),
+// whose branches are yours; set from -team. Branch ownership is only worked
+// out when it is set.
+var codeownersTeam string
+
+// codeownersPaths are where GitHub and GitLab look for CODEOWNERS, in order
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// codeownersRule maps the area named by a CODEOWNERS pattern to its owners
+type codeownersRule struct {
+	Pattern string
+	// Area is the last path component of the pattern without wildcards,
+	// e.g. "payments" for /services/payments/
+	Area   string
+	Owners []string
+}
+
+var (
+	codeownersOnce  sync.Once
+	codeownersRules []codeownersRule
+)
+
+// loadCodeowners reads the CODEOWNERS file of base, or of HEAD without one
+func loadCodeowners(base string) []codeownersRule {
+	codeownersOnce.Do(func() {
+		ref := cmp.Or(base, "HEAD")
+		for _, file := range codeownersPaths {
+			if content, err := runGit("show", ref+":"+file); err == nil {
+				codeownersRules = parseCodeowners(content)
+				logger.Debug("loaded CODEOWNERS", "file", file, "ref", ref, "rules", len(codeownersRules))
+				return
+			}
+		}
+	})
+	return codeownersRules
+}
+
+// parseCodeowners parses the rules of a CODEOWNERS file that name an area,
+// skipping comments, GitLab sections and patterns made only of wildcards
+// such as *.js
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		var area string
+		for _, component := range strings.Split(strings.Trim(fields[0], "/"), "/") {
+			if component != "" && !strings.ContainsAny(component, "*?[") {
+				area = strings.ToLower(component)
+			}
+		}
+		if area == "" {
+			continue
+		}
+		rules = append(rules, codeownersRule{Pattern: fields[0], Area: area, Owners: fields[1:]})
+	}
+	return rules
+}
+
+// branchOwners returns the owners of the area a branch's name refers to,
+// e.g. those of /services/payments/ for origin/feature/payments-refunds. Like
+// in CODEOWNERS, the last matching rule wins. Names that refer to no area
+// have no owners.
+func branchOwners(branch, base string) []string {
+	if codeownersTeam == "" {
+		return nil
+	}
+	_, name, ok := splitRemoteBranch(branch)
+	if !ok {
+		return nil
+	}
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	})
+	// Areas may span words, e.g. user-settings
+	words = append(words, strings.Split(strings.ToLower(name), "/")...)
+
+	var owners []string
+	for _, rule := range loadCodeowners(base) {
+		if slices.Contains(words, rule.Area) {
+			owners = rule.Owners
+		}
+	}
+	return owners
+}
+
+// isOwnedByTeam reports whether the owners include -team
+func isOwnedByTeam(owners []string) bool {
+	return slices.ContainsFunc(owners, func(owner string) bool { return strings.EqualFold(owner, codeownersTeam) })
+}
+
+// confirmOtherTeamsBranches warns about the branches whose names refer to an
+// area CODEOWNERS assigns to other owners than -team, and asks whether to
+// delete them too. It returns the branches still to be deleted.
+func confirmOtherTeamsBranches(localizer *i18n.Localizer, branches []string, options deleteOptions) []string {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data, PluralCount: data["Count"]})
+		return msg
+	}
+	others := map[string]bool{}
+	for _, branch := range branches {
+		if owners := branchOwners(branch, options.base); len(owners) > 0 && !isOwnedByTeam(owners) {
+			others[branch] = true
+			fmt.Printf("%s%s%s\n", ColorYellow, localize("OtherTeamBranch", map[string]interface{}{"Branch": branch, "Owners": strings.Join(owners, ", ")}), ColorReset)
+		}
+	}
+	if len(others) == 0 || options.assumeYes {
+		return branches
+	}
+
+	message := localize("OtherTeamConfirm", map[string]interface{}{"Count": len(others), "Team": codeownersTeam})
+	var confirm bool
+	if plainMode {
+		confirm = askPlainConfirm(message)
+	} else {
+		survey.AskOne(&survey.Confirm{Message: message, Default: false}, &confirm, terminalAskOpts()...)
+	}
+	if confirm {
+		return branches
+	}
+	return slices.DeleteFunc(branches, func(branch string) bool { return others[branch] })
+}
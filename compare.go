@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// printComparison compares two remote branches before deciding the fate of
+// one of them: the commits only one of them has, marked "<" for a and ">" for
+// b, followed by the files b changed since it forked from a
+func printComparison(localizer *i18n.Localizer, a, b, colorMode string) error {
+	refA, err := remoteBranchRef(a)
+	if err != nil {
+		return err
+	}
+	refB, err := remoteBranchRef(b)
+	if err != nil {
+		return err
+	}
+	counts, err := countAheadBehind(refA, refB)
+	if err != nil {
+		return err
+	}
+
+	localize := func(id string) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    id,
+			TemplateData: map[string]interface{}{"A": a, "B": b, "OnlyA": counts.Ahead, "OnlyB": counts.Behind},
+		})
+		return msg
+	}
+	fmt.Println(localize("CompareHeader"))
+	fmt.Printf("%s\n\n", localize("CompareCounts"))
+
+	if counts.Ahead+counts.Behind > 0 {
+		cmd := gitCommand("log", "--left-right", "--oneline", "--color="+colorMode, refA+"..."+refB, "--")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	if counts.Behind == 0 {
+		fmt.Println(localize("CompareNoChanges"))
+		return nil
+	}
+	fmt.Println(localize("CompareDiffstat"))
+	cmd := gitCommand("diff", "--stat", "--color="+colorMode, refA+"..."+refB, "--")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
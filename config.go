@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// configScopes are the configuration files in increasing order of
+// precedence; the environment and then command-line flags override them all
+var configScopes = []string{"system", "user", "repo"}
+
+// unconfigurableFlags only make sense for a single invocation
+var unconfigurableFlags = map[string]bool{
+	"h": true, "help": true, "C": true, "c": true, "o": true, "from": true, "stdin": true, "from-file": true, "execute": true, "include-protected": true, "self": true, "scope": true, "get-remote-log": true, "finder-action": true, "git-path": true, "no-limit": true, "others": true, "until": true, "refresh-prs": true,
+}
+
+// protectedConfigKey holds additional protected branch patterns. Unlike other
+// keys its values accumulate across scopes instead of overriding each other.
+const protectedConfigKey = "protected"
+
+// configValue is an effective setting and the scope it came from
+type configValue struct {
+	Value  string
+	Source string
+}
+
+// Config is the configuration merged from every scope and the environment
+type Config struct {
+	values    map[string]configValue
+	protected []string
+	policy    []PolicyRule
+	naming    *NamingRules
+	theme     *Theme
+	gitBinary string
+	env       map[string]string
+	messages  map[string]*i18n.Message
+	// profile is the name of the profile matching the remote, with its
+	// patterns and hosting token
+	profile         string
+	profilePatterns []string
+	token           string
+}
+
+// appConfig is loaded at startup; it is empty until then
+var appConfig = &Config{values: map[string]configValue{}}
+
+// configPath returns the path of the configuration file of a scope: a
+// system-wide file, one in the user's config directory, and one in the
+// repository's git directory (which is never committed or shared)
+func configPath(scope string) (string, error) {
+	switch scope {
+	case "system":
+		return "/etc/grbm/config.toml", nil
+	case "user":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "grbm", "config.toml"), nil
+	case "repo":
+		dir, err := gitCommonDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "grbm", "config.toml"), nil
+	}
+	return "", fmt.Errorf("unknown config scope: %s", scope)
+}
+
+// isConfigKey reports whether key can be set in the configuration
+func isConfigKey(key string) bool {
+	return key == protectedConfigKey || (flag.Lookup(key) != nil && !unconfigurableFlags[key])
+}
+
+// configEnvName returns the environment variable that sets key, e.g.
+// GRBM_CONFIRM_THRESHOLD for confirm-threshold
+func configEnvName(key string) string {
+	return "GRBM_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// readConfigFile reads the settings of one scope; a missing file is empty
+func readConfigFile(path string) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	if _, err := toml.DecodeFile(path, &settings); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// formatConfigValue turns a TOML value into the string form a flag accepts
+func formatConfigValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		var items []string
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// loadConfig merges the configuration files of every scope, the profile
+// matching the repository's remote and the GRBM_* environment variables. The
+// repo scope is skipped outside a repository.
+func loadConfig() (*Config, error) {
+	config := &Config{values: map[string]configValue{}, env: map[string]string{}, messages: map[string]*i18n.Message{}}
+	profiles := map[string]map[string]interface{}{}
+	for _, scope := range configScopes {
+		path, err := configPath(scope)
+		if err != nil {
+			continue
+		}
+		settings, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range settings {
+			if key == profilesConfigKey {
+				// Narrower scopes override single keys of a profile
+				if err := mergeProfiles(profiles, value); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				continue
+			}
+			if err := config.set(key, value, scope); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	// The profile overrides the files it is defined in, but not the environment
+	if name, settings := selectProfile(profiles); name != "" {
+		config.profile = name
+		config.profilePatterns, _ = profilePatterns(name, settings)
+		for key, value := range settings {
+			var err error
+			switch key {
+			case profileMatchKey:
+			case profileTokenKey:
+				config.token, err = parseProfileToken(value)
+			default:
+				err = config.set(key, value, "profile "+name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", profilesConfigKey, name, err)
+			}
+		}
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(configEnvName(f.Name)); ok && isConfigKey(f.Name) {
+			config.values[f.Name] = configValue{Value: value, Source: "env"}
+		}
+	})
+	if value, ok := os.LookupEnv(configEnvName(protectedConfigKey)); ok {
+		config.protected = append(config.protected, strings.Split(value, ",")...)
+		config.values[protectedConfigKey] = configValue{Value: value, Source: "env"}
+	}
+	return config, nil
+}
+
+// set applies one key of a configuration file or profile, which source names
+func (c *Config) set(key string, value interface{}, source string) error {
+	var err error
+	switch key {
+	case policyConfigKey:
+		c.policy, err = parsePolicy(value)
+	case namingConfigKey:
+		c.naming, err = parseNaming(value)
+	case envConfigKey:
+		var env map[string]string
+		if env, err = parseEnvConfig(value); err == nil {
+			// Narrower scopes override single variables
+			for name, value := range env {
+				c.env[name] = value
+			}
+		}
+	case messagesConfigKey:
+		var messages map[string]*i18n.Message
+		if messages, err = parseMessagesConfig(value); err == nil {
+			// Like variables, messages of narrower scopes override single ones
+			for id, message := range messages {
+				c.messages[id] = message
+			}
+		}
+	case gitConfigKey:
+		c.gitBinary, err = parseGitConfig(value)
+	case themeConfigKey:
+		c.theme, err = parseTheme(value)
+	default:
+		if !isConfigKey(key) {
+			return fmt.Errorf("unknown key %q", key)
+		}
+		if key == protectedConfigKey {
+			c.protected = append(c.protected, strings.Split(formatConfigValue(value), ",")...)
+		}
+		c.values[key] = configValue{Value: formatConfigValue(value), Source: source}
+	}
+	return err
+}
+
+// applyToFlags sets every flag that was not given on the command line to its
+// configured value
+func (c *Config) applyToFlags() error {
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for key, value := range c.values {
+		if key == protectedConfigKey || given[key] {
+			continue
+		}
+		if err := flag.Set(key, value.Value); err != nil {
+			return fmt.Errorf("%s (%s): %w", key, value.Source, err)
+		}
+	}
+	return nil
+}
+
+// parseConfigValue converts value to the type of the flag named key
+func parseConfigValue(key, value string) (interface{}, error) {
+	if key == protectedConfigKey {
+		return value, nil
+	}
+	switch flag.Lookup(key).Value.(flag.Getter).Get().(type) {
+	case bool:
+		return strconv.ParseBool(value)
+	case int:
+		return strconv.Atoi(value)
+	}
+	return value, nil
+}
+
+// runConfigCommand implements `config list`, `config get <key>`,
+// `config set <key> <value>` and `config unset <key>`. set and unset write to
+// the file of scope.
+func runConfigCommand(localizer *i18n.Localizer, args []string, scope string) error {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	usage := errors.New(localize("ConfigUsage", map[string]interface{}{"Program": programName()}))
+
+	if len(args) == 0 {
+		return usage
+	}
+	action := args[0]
+	if action != "list" {
+		if len(args) < 2 {
+			return usage
+		}
+		if !isConfigKey(args[1]) {
+			return errors.New(localize("ConfigUnknownKey", map[string]interface{}{"Key": args[1]}))
+		}
+	}
+
+	switch action {
+	case "list":
+		keys := make([]string, 0, len(appConfig.values))
+		for key := range appConfig.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := appConfig.values[key]
+			if key == protectedConfigKey {
+				value.Value = strings.Join(appConfig.protected, ",")
+			}
+			fmt.Printf("%s = %s (%s)\n", key, value.Value, value.Source)
+		}
+		return nil
+	case "get":
+		if args[1] == protectedConfigKey {
+			if len(appConfig.protected) == 0 {
+				os.Exit(1)
+			}
+			fmt.Println(strings.Join(appConfig.protected, "\n"))
+			return nil
+		}
+		value, ok := appConfig.values[args[1]]
+		if !ok {
+			// Like git config, an unset key is reported with exit status 1
+			os.Exit(1)
+		}
+		fmt.Println(value.Value)
+		return nil
+	case "set", "unset":
+		if action == "set" && len(args) < 3 {
+			return usage
+		}
+		path, err := configPath(scope)
+		if err != nil {
+			return err
+		}
+		settings, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		key := args[1]
+		if action == "unset" {
+			delete(settings, key)
+		} else {
+			value, err := parseConfigValue(key, args[2])
+			if err != nil {
+				return errors.New(localize("ConfigInvalidValue", map[string]interface{}{"Key": key, "Value": args[2]}))
+			}
+			if key == protectedConfigKey {
+				// Protected patterns accumulate, like git config --add
+				patterns, _ := settings[key].([]interface{})
+				value = append(patterns, value)
+			}
+			settings[key] = value
+		}
+		return writeConfigFile(path, settings)
+	}
+	return usage
+}
+
+// writeConfigFile writes the settings of one scope
+func writeConfigFile(path string, settings map[string]interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(file).Encode(settings); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
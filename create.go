@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// printNamingViolations explains why name does not follow the configured
+// naming convention, reporting whether it does
+func printNamingViolations(localizer *i18n.Localizer, name string) bool {
+	violations := appConfig.naming.check(name)
+	if len(violations) == 0 {
+		return false
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "NamingViolations",
+		TemplateData: map[string]interface{}{"Name": name},
+	})
+	fmt.Println(msg)
+	for _, violation := range violations {
+		fmt.Printf("  - %s\n", violation.localize(localizer))
+	}
+	return true
+}
+
+// creationRemote returns the remote a new branch is created on: the one name
+// starts with, else the remote of from, else origin or the only remote
+func creationRemote(name, from string) (string, string) {
+	output, _ := runGit("remote")
+	remotes := strings.Split(output, "\n")
+	if remoteName, branchName, ok := splitRemoteBranch(name); ok && slices.Contains(remotes, remoteName) {
+		return remoteName, branchName
+	}
+	if remoteName, _, ok := splitRemoteBranch(from); ok && slices.Contains(remotes, remoteName) {
+		return remoteName, name
+	}
+	if len(remotes) == 1 || !slices.Contains(remotes, "origin") {
+		return remotes[0], name
+	}
+	return "origin", name
+}
+
+// createBranch pushes a new remote branch pointing at the commit from
+// resolves to. name may be empty, in which case the user is prompted for it,
+// and may start with the remote to create the branch on.
+func createBranch(localizer *i18n.Localizer, name, from string) {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+
+	sha, err := runGit("rev-parse", "--verify", "--quiet", "--end-of-options", from+"^{commit}")
+	if err != nil {
+		fmt.Println(localize("CreateUnknownRef", map[string]interface{}{"Ref": from}))
+		os.Exit(1)
+	}
+
+	if name == "" {
+		prompt := &survey.Input{Message: localize("CreatePrompt", map[string]interface{}{"From": from})}
+		if err := survey.AskOne(prompt, &name); err != nil {
+			fmt.Println(localize("CreateCancelled", nil))
+			os.Exit(0)
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		fmt.Println(localize("CreateCancelled", nil))
+		os.Exit(0)
+	}
+	remoteName, branchName := creationRemote(name, from)
+	branch := remoteName + "/" + branchName
+
+	if _, err := runGit("check-ref-format", "--branch", branchName); err != nil || strings.HasPrefix(branchName, "-") {
+		fmt.Println(localize("CreateInvalidName", map[string]interface{}{"Name": branchName}))
+		os.Exit(1)
+	}
+	if printNamingViolations(localizer, branchName) {
+		os.Exit(1)
+	}
+	if _, err := lookupRemoteBranch(branch); err == nil {
+		fmt.Println(localize("CreateExists", map[string]interface{}{"Branch": branch}))
+		os.Exit(1)
+	}
+
+	// The empty lease makes the push fail if the branch was created on the
+	// remote since the last fetch instead of moving it
+	ref := "refs/heads/" + branchName
+	if output, err := gitCommand("push", "--force-with-lease="+ref+":", remoteName, sha+":"+ref).CombinedOutput(); err != nil {
+		fmt.Println(localize("CreateFailed", map[string]interface{}{"Branch": branch, "Error": err}))
+		fmt.Println(string(output))
+		os.Exit(1)
+	}
+	fmt.Println(localize("CreateSucceeded", map[string]interface{}{"Branch": branch, "SHA": shortSHA(sha), "From": from}))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// dateFormat is how dates are shown, set from -date-format: "relative"
+// ("3 months ago"), "iso" ("2024-05-01 14:03") or "locale", whose layout
+// comes from the DateLayout message of the current language. JSON output
+// always uses RFC 3339 timestamps.
+var dateFormat = "relative"
+
+// isoDateLayout is the layout of the iso date format
+const isoDateLayout = "2006-01-02 15:04"
+
+// dateColumnWidth fits the dates of every format in table columns
+const dateColumnWidth = 18
+
+// formatDate formats t in the -date-format, or returns "-" for a zero time
+func formatDate(localizer *i18n.Localizer, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	switch dateFormat {
+	case "iso":
+		return t.Local().Format(isoDateLayout)
+	case "locale":
+		layout, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "DateLayout"})
+		if layout == "" {
+			layout = isoDateLayout
+		}
+		return t.Local().Format(layout)
+	}
+	return relativeDate(localizer, t)
+}
+
+// relativeDate describes the time elapsed since t in words, in the units of
+// shortDuration
+func relativeDate(localizer *i18n.Localizer, t time.Time) string {
+	age := time.Since(t)
+	var messageID string
+	var count int
+	switch {
+	case age < time.Minute:
+		messageID = "DateJustNow"
+	case age < time.Hour:
+		messageID, count = "DateMinutesAgo", int(age.Minutes())
+	case age < 24*time.Hour:
+		messageID, count = "DateHoursAgo", int(age.Hours())
+	case age < 30*24*time.Hour:
+		messageID, count = "DateDaysAgo", int(age.Hours()/24)
+	case age < 365*24*time.Hour:
+		messageID, count = "DateMonthsAgo", int(age.Hours()/24/30)
+	default:
+		messageID, count = "DateYearsAgo", int(age.Hours()/24/365)
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    messageID,
+		TemplateData: map[string]interface{}{"Count": count},
+		PluralCount:  count,
+	})
+	return msg
+}
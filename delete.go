@@ -0,0 +1,906 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// splitRemoteBranch splits "origin/feature/x" into its remote ("origin") and
+// branch ("feature/x") parts
+func splitRemoteBranch(branch string) (string, string, bool) {
+	parts := strings.SplitN(branch, "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// deleteRemoteBranch deletes a single remote branch with git push --delete and
+// returns git's combined output
+func deleteRemoteBranch(branch string) (string, error) {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return "", fmt.Errorf("invalid branch format: %s", branch)
+	}
+	output, err := gitCommand("push", remoteName, "--delete", branchName).CombinedOutput()
+	return string(output), err
+}
+
+// retryDelay is the wait before the first retry of a failed deletion; it
+// doubles with every further attempt
+var retryDelay = time.Second
+
+// transientPushErrors are fragments of git push output that indicate a
+// failure worth retrying, as opposed to e.g. a permission error
+var transientPushErrors = []string{
+	"Could not resolve host",
+	"Connection timed out",
+	"Connection reset",
+	"Connection refused",
+	"Operation timed out",
+	"The remote end hung up unexpectedly",
+	"early EOF",
+	"RPC failed",
+	"TLS connection was non-properly terminated",
+}
+
+// isTransientPushError reports whether the output of a failed git push looks
+// like a network problem
+func isTransientPushError(output string) bool {
+	for _, fragment := range transientPushErrors {
+		if strings.Contains(output, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// pushFailureHints map fragments of git push output to the localized hint
+// explaining the failure, checked in order
+var pushFailureHints = []struct {
+	fragments []string
+	messageID string
+}{
+	{[]string{checkRejected}, "PushHintCheck"},
+	{[]string{"protected branch", "GH006", "is protected", "not allowed to delete", "pre-receive hook declined"}, "PushHintProtected"},
+	{[]string{"remote ref does not exist"}, "PushHintAlreadyDeleted"},
+	{[]string{"(stale info)"}, "PushHintMoved"},
+	{[]string{"Permission denied (publickey", "Host key verification failed"}, "PushHintSSH"},
+	{[]string{"Authentication failed", "could not read Username", "Permission to", "permission denied", "returned error: 403"}, "PushHintPermission"},
+	{[]string{"cannot lock ref", "unable to lock", "failed to lock"}, "PushHintRefLock"},
+	{transientPushErrors, "PushHintNetwork"},
+}
+
+// pushFailureHint returns a localized explanation of a failed git push, or
+// an empty string when the failure is not recognized
+func pushFailureHint(localizer *i18n.Localizer, branch, output string) string {
+	remoteName, _, _ := splitRemoteBranch(branch)
+	for _, hint := range pushFailureHints {
+		for _, fragment := range hint.fragments {
+			if strings.Contains(output, fragment) {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    hint.messageID,
+					TemplateData: map[string]interface{}{"Branch": branch, "Remote": remoteName},
+				})
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// pushDelete deletes branches (without the remote prefix) of one remote with
+// a single git push, retrying up to retries times with exponential backoff
+// while the failure looks transient. Several branches are deleted atomically
+// so that a failed push leaves all of them in place. With -soft-delete the
+// branches are moved to the trash namespace instead. -check-command is run
+// once before the push, and the branches are then deleted on the -mirrors.
+//
+// The push carries a lease on the tip of each branch the user was shown, or
+// that a plan recorded: that in tips, keyed by remote branch, or else that of
+// its remote-tracking ref. A branch with neither is not deleted. When git
+// rejects the push as stale info, the remote is asked for the current tips:
+// the push is retried once if they still match, and fails with errBranchMoved
+// if a branch moved since it was selected.
+func pushDelete(remoteName string, branchNames []string, tips map[string]string, retries int) (string, error) {
+	leased := remoteTipsOf(remoteName, branchNames)
+	for _, branchName := range branchNames {
+		if sha := tips[remoteName+"/"+branchName]; sha != "" {
+			leased[branchName] = sha
+		}
+		if leased[branchName] == "" {
+			return "", fmt.Errorf("no known tip of %s/%s to lease its deletion on", remoteName, branchName)
+		}
+	}
+
+	args := []string{"push", remoteName, "--delete"}
+	if len(branchNames) > 1 {
+		args = []string{"push", "--atomic", remoteName, "--delete"}
+	}
+	args = append(args, branchNames...)
+	if softDelete {
+		args = softDeleteArgs(remoteName, branchNames, leased)
+	}
+	if checkCommand != "" {
+		if output, err := runDeletionCheck(remoteName, branchNames); err != nil {
+			return output, err
+		}
+		// The hook has just approved the push; git need not run it again
+		if checkCommand == prePushCheck {
+			args = append([]string{"push", "--no-verify"}, args[1:]...)
+		}
+	}
+
+	var leases []string
+	for _, branchName := range branchNames {
+		leases = append(leases, "--force-with-lease=refs/heads/"+branchName+":"+leased[branchName])
+	}
+	args = append(append([]string{"push"}, leases...), args[1:]...)
+
+	delay := retryDelay
+	reverified := false
+	for attempt := 0; ; attempt++ {
+		output, err := gitCommand(args...).CombinedOutput()
+		if err != nil && !reverified && strings.Contains(string(output), "(stale info)") {
+			reverified = true
+			if err := reverifyTips(remoteName, branchNames, leased); err != nil {
+				return string(output), err
+			}
+			logger.Info("retrying deletion after stale info", "remote", remoteName, "branches", branchNames)
+			continue
+		}
+		if err == nil {
+			pruneTrackingRefs(remoteName, branchNames)
+		} else if len(branchNames) == 1 && strings.Contains(string(output), "remote ref does not exist") {
+			// The branch is gone already; only its remote-tracking ref was left
+			pruneTrackingRefs(remoteName, branchNames)
+		}
+		if err == nil && len(mirrorRemotes) > 0 {
+			output = append(output, deleteOnMirrors(remoteName, branchNames, leased)...)
+		}
+		if err == nil || attempt >= retries || !isTransientPushError(string(output)) {
+			return string(output), err
+		}
+		logger.Info("retrying deletion", "remote", remoteName, "branches", branchNames, "attempt", attempt+1, "delay", delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// pruneTrackingRefs deletes the remote-tracking refs of branches (without the
+// remote prefix) that were deleted on remoteName. git push removes them
+// itself when the remote's fetch refspec maps them, but not otherwise, and a
+// rerun should not list branches that are gone.
+func pruneTrackingRefs(remoteName string, branchNames []string) {
+	var commands strings.Builder
+	for branchName := range remoteTipsOf(remoteName, branchNames) {
+		fmt.Fprintf(&commands, "delete refs/remotes/%s/%s\n", remoteName, branchName)
+	}
+	if commands.Len() == 0 {
+		return
+	}
+	cmd := gitCommand("update-ref", "--stdin")
+	cmd.Stdin = strings.NewReader(commands.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Debug("could not delete remote-tracking refs", "remote", remoteName, "error", err, "output", strings.TrimSpace(string(output)))
+	}
+}
+
+// errBranchMoved is returned when a branch to delete has moved on its remote
+// since it was selected
+var errBranchMoved = errors.New("branch moved since it was selected")
+
+// reverifyTips fetches the branches whose deletion was rejected as stale
+// info and checks that they still point at tips, the commits the user was
+// shown. Branches gone from the remote are not fetched. The retry stays
+// leased on the shown tips, not on the refreshed remote-tracking refs.
+func reverifyTips(remoteName string, branchNames []string, tips map[string]string) error {
+	heads, err := remoteHeads(remoteName)
+	if err != nil {
+		return err
+	}
+	args := []string{"fetch", "--no-tags", remoteName}
+	var present []string
+	for _, branchName := range branchNames {
+		if _, ok := heads[remoteName+"/"+branchName]; ok {
+			args = append(args, "+refs/heads/"+branchName+":refs/remotes/"+remoteName+"/"+branchName)
+			present = append(present, branchName)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+	if _, err := runGit(args...); err != nil {
+		return err
+	}
+	var moved []string
+	for branchName, current := range remoteTipsOf(remoteName, present) {
+		if current != tips[branchName] {
+			moved = append(moved, fmt.Sprintf("%s/%s (%s -> %s)", remoteName, branchName, shortSHA(tips[branchName]), shortSHA(current)))
+		}
+	}
+	if len(moved) > 0 {
+		slices.Sort(moved)
+		return fmt.Errorf("%w: %s", errBranchMoved, strings.Join(moved, ", "))
+	}
+	return nil
+}
+
+// deleteRemoteBranchWithRetries deletes branch like deleteRemoteBranch,
+// leased and retrying transient failures like pushDelete
+func deleteRemoteBranchWithRetries(branch string, tips map[string]string, retries int) (string, error) {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return "", fmt.Errorf("invalid branch format: %s", branch)
+	}
+	return pushDelete(remoteName, []string{branchName}, tips, retries)
+}
+
+// deletionResult is the outcome of deleting one or more branches with a
+// single git push
+type deletionResult struct {
+	branches []string
+	output   string
+	err      error
+}
+
+// deleteInBatches deletes the branches with one git push per batch of up to
+// batchSize branches of the same remote, calling report after every push.
+// When a batch fails, its branches are deleted one by one to pinpoint the
+// culprit. No further push is started once stop returns true. The deletions
+// are leased on tips as described for pushDelete.
+func deleteInBatches(branches []string, tips map[string]string, batchSize, retries int, stop func() bool, report func(deletionResult)) {
+	var remotes []string
+	byRemote := map[string][]string{}
+	for _, branch := range branches {
+		remoteName, _, ok := splitRemoteBranch(branch)
+		if !ok {
+			report(deletionResult{branches: []string{branch}, err: fmt.Errorf("invalid branch format: %s", branch)})
+			continue
+		}
+		if _, seen := byRemote[remoteName]; !seen {
+			remotes = append(remotes, remoteName)
+		}
+		byRemote[remoteName] = append(byRemote[remoteName], branch)
+	}
+	batchSize = max(batchSize, 1)
+
+	for _, remoteName := range remotes {
+		for pending := byRemote[remoteName]; len(pending) > 0; {
+			if stop() {
+				return
+			}
+			batch := pending[:min(batchSize, len(pending))]
+			pending = pending[len(batch):]
+
+			if len(batch) > 1 {
+				var branchNames []string
+				for _, branch := range batch {
+					_, branchName, _ := splitRemoteBranch(branch)
+					branchNames = append(branchNames, branchName)
+				}
+				output, err := pushDelete(remoteName, branchNames, tips, retries)
+				if err == nil {
+					report(deletionResult{branches: batch, output: output})
+					continue
+				}
+				logger.Info("batch deletion failed, deleting one by one", "remote", remoteName, "branches", len(batch), "error", err)
+			}
+			for _, branch := range batch {
+				if stop() {
+					return
+				}
+				output, err := deleteRemoteBranchWithRetries(branch, tips, retries)
+				report(deletionResult{branches: []string{branch}, output: output, err: err})
+			}
+		}
+	}
+}
+
+// confirmLargeDeletion asks the user to type the number of selected branches
+// or the word "delete" before a mass deletion proceeds
+func confirmLargeDeletion(localizer *i18n.Localizer, count int) bool {
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ConfirmLargeDeletion",
+		TemplateData: map[string]interface{}{"Count": count},
+	})
+	var answer string
+	if plainMode {
+		answer, _ = readPlainLine(msg)
+	} else if err := survey.AskOne(&survey.Input{Message: msg}, &answer, terminalAskOpts()...); err != nil {
+		return false
+	}
+	return isLargeDeletionConfirmed(answer, count)
+}
+
+// remoteGroup is the part of a selection on one remote
+type remoteGroup struct {
+	remote   string
+	branches []string
+}
+
+// groupByRemote splits branches by remote, in the order the remotes first
+// appear. Branches without a remote are grouped under an empty name.
+func groupByRemote(branches []string) []remoteGroup {
+	var groups []remoteGroup
+	index := map[string]int{}
+	for _, branch := range branches {
+		remoteName, _, _ := splitRemoteBranch(branch)
+		i, ok := index[remoteName]
+		if !ok {
+			i = len(groups)
+			index[remoteName] = i
+			groups = append(groups, remoteGroup{remote: remoteName})
+		}
+		groups[i].branches = append(groups[i].branches, branch)
+	}
+	return groups
+}
+
+// confirmRemoteDeletion asks whether to delete the branches of a group, with
+// a typed confirmation above threshold branches. With perRemote, the prompt
+// names the remote and its URL.
+func confirmRemoteDeletion(localizer *i18n.Localizer, group remoteGroup, perRemote bool, threshold int) bool {
+	if len(group.branches) > threshold {
+		if perRemote {
+			fmt.Println(remoteGroupLabel(localizer, group))
+		}
+		return confirmLargeDeletion(localizer, len(group.branches))
+	}
+	message, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ConfirmDeletionPrompt",
+		TemplateData: map[string]interface{}{"Count": len(group.branches), "Remote": group.remote},
+		PluralCount:  len(group.branches),
+	})
+	if perRemote {
+		message = remoteGroupLabel(localizer, group)
+	}
+	if plainMode {
+		return askPlainConfirm(message)
+	}
+	var confirm bool
+	survey.AskOne(&survey.Confirm{Message: message, Default: false}, &confirm, terminalAskOpts()...)
+	return confirm
+}
+
+// remoteGroupLabel asks about deleting the branches of one remote
+func remoteGroupLabel(localizer *i18n.Localizer, group remoteGroup) string {
+	url, err := runGit("remote", "get-url", "--push", group.remote)
+	if err != nil {
+		url = group.remote
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ConfirmRemoteDeletion",
+		TemplateData: map[string]interface{}{"Count": len(group.branches), "Remote": group.remote, "URL": url},
+		PluralCount:  len(group.branches),
+	})
+	return msg
+}
+
+// isLargeDeletionConfirmed reports whether answer is an acceptable typed
+// confirmation for deleting count branches
+func isLargeDeletionConfirmed(answer string, count int) bool {
+	answer = strings.TrimSpace(answer)
+	return answer == strconv.Itoa(count) || answer == "delete"
+}
+
+// deleteOptions control how deleteBranches confirms and performs deletions
+type deleteOptions struct {
+	// confirmThreshold is the selection size above which a typed
+	// confirmation is required
+	confirmThreshold int
+	// maxDeletions is the most branches a run deletes; larger selections are
+	// refused. 0 means no limit.
+	maxDeletions int
+	// retries is the number of automatic retries of transient push failures
+	retries int
+	// batchSize is the maximum number of branches deleted by one git push
+	batchSize int
+	// deleteLocal also deletes the local counterparts of the remote branches
+	deleteLocal bool
+	// summaryBranches are all remote branches with -plan-summary, which
+	// shows how many of them the deletion leaves per remote and namespace
+	summaryBranches []string
+	// assumeYes deletes without asking for confirmation or offering to retry
+	// failures, for non-interactive runs
+	assumeYes bool
+	// base and hosting are the -base and -hosting the confirmation explains
+	// why branches are candidates with
+	base    string
+	hosting string
+	// tips are the commits the branches were reviewed at, such as those a
+	// plan recorded. They are shown, checked for drift and leased on instead
+	// of the remote-tracking refs.
+	tips map[string]string
+	// afterDeletion, if set, is called with the remote branches that were
+	// deleted once deleting stops, even when it was interrupted
+	afterDeletion func(deleted []string)
+}
+
+// exceedsLimit reports whether deleting count branches goes past maxDeletions
+func (o deleteOptions) exceedsLimit(count int) bool {
+	return o.maxDeletions > 0 && count > o.maxDeletions
+}
+
+// finish calls afterDeletion if any branch was deleted
+func (o deleteOptions) finish(deleted []string) {
+	if o.afterDeletion != nil && len(deleted) > 0 {
+		o.afterDeletion(deleted)
+	}
+}
+
+// deletionProgress records which of the branches being deleted are gone so
+// that an interrupted run can tell the user where it stopped
+type deletionProgress struct {
+	mu       sync.Mutex
+	branches []string
+	started  bool
+	deleted  map[string]bool
+	// failed holds the reason the last attempt to delete a branch failed
+	failed map[string]string
+}
+
+func newDeletionProgress(branches []string) *deletionProgress {
+	return &deletionProgress{branches: branches, deleted: map[string]bool{}, failed: map[string]string{}}
+}
+
+func (p *deletionProgress) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = true
+}
+
+func (p *deletionProgress) markDeleted(branch string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleted[branch] = true
+	delete(p.failed, branch)
+}
+
+func (p *deletionProgress) markFailed(branch, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed[branch] = reason
+}
+
+// deletedBranches returns the deleted branches in selection order
+func (p *deletionProgress) deletedBranches() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var deleted []string
+	for _, branch := range p.branches {
+		if p.deleted[branch] {
+			deleted = append(deleted, branch)
+		}
+	}
+	return deleted
+}
+
+// printInterrupted summarizes an interrupted deletion: how many branches were
+// deleted and which ones remain
+func (p *deletionProgress) printInterrupted(localizer *i18n.Localizer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"})
+		fmt.Fprintf(os.Stderr, "\n%s\n", msg)
+		emitEvent(Event{Event: "cancelled"})
+		return
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "DeletionInterrupted",
+		TemplateData: map[string]interface{}{"Deleted": len(p.deleted), "Total": len(p.branches)},
+	})
+	fmt.Fprintf(os.Stderr, "\n%s%s%s\n", ColorYellow, msg, ColorReset)
+	if len(p.deleted) == len(p.branches) {
+		return
+	}
+	msg, _ = localizer.Localize(&i18n.LocalizeConfig{MessageID: "DeletionInterruptedRemaining"})
+	fmt.Fprintln(os.Stderr, msg)
+	for _, branch := range p.branches {
+		if !p.deleted[branch] {
+			fmt.Fprintf(os.Stderr, "  %s\n", branch)
+		}
+	}
+}
+
+// deleteLocalCounterpart deletes the local counterpart of a deleted remote
+// branch and reports the outcome
+func deleteLocalCounterpart(localizer *i18n.Localizer, local string) {
+	if output, err := deleteLocalBranch(local); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "ErrorDeletingLocalBranch",
+			TemplateData: map[string]interface{}{"Branch": local, "Error": err},
+		})
+		fmt.Printf("%s\n%s\n", msg, strings.TrimSpace(output))
+		return
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "LocalBranchDeleted",
+		TemplateData: map[string]interface{}{"Branch": local},
+	})
+	fmt.Println(msg)
+}
+
+// deleteBranches filters protected branches out of the selection, asks for
+// confirmation and deletes the remaining remote branches, followed by their
+// local counterparts with options.deleteLocal. Transient failures
+// are retried automatically; deletions that still fail can be retried
+// interactively.
+func deleteBranches(localizer *i18n.Localizer, selected []string, options deleteOptions) {
+	emitBranchEvents("selected", selected)
+	if len(selected) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
+		fmt.Println(msg)
+		os.Exit(0)
+	}
+
+	// Clean selected branch names and filter out protected branches
+	var branchesToDelete []string
+	var protectedBranchesSelected []string
+	for _, cleanedBranch := range selected {
+		if isProtectedBranch(cleanedBranch) {
+			protectedBranchesSelected = append(protectedBranchesSelected, cleanedBranch)
+		} else if namespace, blocked := blockedNamespace(cleanedBranch); blocked {
+			// Release trains are kept unless their namespace is allowed
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "NamespaceBranchSkipped",
+				TemplateData: map[string]interface{}{"Branch": cleanedBranch, "Namespace": namespace},
+			})
+			fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+			emitEvent(Event{Event: "skipped", Branch: cleanedBranch, Reason: "namespace"})
+		} else {
+			branchesToDelete = append(branchesToDelete, cleanedBranch)
+		}
+	}
+
+	// The default branch of a remote is never deleted, even when it is not
+	// protected by name or -include-protected is given
+	heads := remoteHeadBranches(append(append([]string{}, branchesToDelete...), protectedBranchesSelected...))
+	branchesToDelete = slices.DeleteFunc(branchesToDelete, func(branch string) bool { return heads[branch] })
+	protectedBranchesSelected = slices.DeleteFunc(protectedBranchesSelected, func(branch string) bool { return heads[branch] })
+	for _, branch := range selected {
+		if heads[branch] {
+			remoteName, _, _ := splitRemoteBranch(branch)
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "DefaultBranchBlocked",
+				TemplateData: map[string]interface{}{"Branch": branch, "Remote": remoteName},
+			})
+			fmt.Printf("%s%s%s\n", ColorRed, msg, ColorReset)
+			emitEvent(Event{Event: "skipped", Branch: branch, Reason: "default-branch"})
+		}
+	}
+
+	// Notify user about skipped protected branches, unless -include-protected
+	// lets them be deleted and their name is typed
+	for _, protectedBranch := range protectedBranchesSelected {
+		if pattern, frozen := frozenPattern(protectedBranch); frozen {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "FrozenBranchSkipped",
+				TemplateData: map[string]interface{}{"Branch": protectedBranch, "Pattern": pattern, "File": branchProtectFile},
+			})
+			fmt.Printf("%s%s%s\n", ColorRed, msg, ColorReset)
+			emitEvent(Event{Event: "skipped", Branch: protectedBranch, Reason: "frozen"})
+			continue
+		}
+		if includeProtected && !options.assumeYes && acknowledgeProtectedDeletion(localizer, protectedBranch) {
+			branchesToDelete = append(branchesToDelete, protectedBranch)
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "ProtectedBranchSkipped",
+			TemplateData: map[string]interface{}{"Branch": protectedBranch},
+		})
+		fmt.Println(msg)
+		emitEvent(Event{Event: "skipped", Branch: protectedBranch, Reason: "protected"})
+	}
+
+	// With -own-only, the branches others authored are theirs to clean up
+	branchesToDelete = withoutOthersBranches(localizer, branchesToDelete)
+
+	// Branches of areas other teams own are only deleted after asking
+	branchesToDelete = confirmOtherTeamsBranches(localizer, branchesToDelete, options)
+
+	// Unmerged branches can be turned into pull requests or merged instead
+	if !options.assumeYes {
+		branchesToDelete = rescueUnmerged(localizer, branchesToDelete, options)
+	}
+
+	if len(branchesToDelete) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
+		fmt.Println(msg)
+		os.Exit(0)
+	}
+
+	// A runaway select-all is refused outright rather than confirmed
+	if options.exceedsLimit(len(branchesToDelete)) {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "DeletionLimitExceeded",
+			TemplateData: map[string]interface{}{"Count": len(branchesToDelete), "Limit": options.maxDeletions},
+		})
+		fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorRed, msg, ColorReset)
+		emitEvent(Event{Event: "cancelled"})
+		os.Exit(1)
+	}
+
+	// Display confirmation
+	confirmMsg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ConfirmDeletion"})
+	fmt.Printf("\n%s\n", confirmMsg)
+	if softDelete {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "SoftDeleteNotice",
+			TemplateData: map[string]interface{}{"Namespace": trashNamespace + "/" + trashDate + "/"},
+		})
+		fmt.Println(msg)
+	}
+
+	branchHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "Branch"})
+	remoteHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "Remote"})
+
+	dateHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LastCommit"})
+
+	reasonHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "Reason"})
+
+	fmt.Printf("%s %s %s %s\n", padRight(branchHeader, 40), padRight(remoteHeader, 12), padRight(dateHeader, dateColumnWidth), reasonHeader)
+	fmt.Println(strings.Repeat("-", 100))
+
+	var locals map[string]string
+	var skippedLocals []skippedLocalBranch
+	if options.deleteLocal {
+		locals, skippedLocals = planLocalDeletions(branchesToDelete)
+	}
+	localLabel, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LocalBranchLabel"})
+	// A dry-run push shows which branches the current credentials cannot
+	// delete before anything is pushed
+	failures := checkDeletePermissions(branchesToDelete)
+	// The tips shown are checked again right before deleting, in case
+	// someone pushes to a branch in the meantime
+	dates := map[string]string{}
+	shown := map[string]string{}
+	details, _ := listRemoteBranches()
+	for _, detail := range details {
+		dates[detail.Name] = formatDate(localizer, detail.ActivityDate())
+		shown[detail.Name] = detail.Hash
+	}
+	maps.Copy(shown, options.tips)
+	reasons := deletionReasons(localizer, branchesToDelete, details, options)
+
+	// The table lists the branches of each remote together, in the order
+	// they are confirmed and deleted
+	var ordered []string
+	for _, group := range groupByRemote(branchesToDelete) {
+		ordered = append(ordered, group.branches...)
+	}
+	branchesToDelete = ordered
+	for _, branch := range branchesToDelete {
+		parts := strings.SplitN(branch, "/", 2)
+		if len(parts) == 2 {
+			fmt.Printf("%s %s %s %s", padRight(parts[1], 40), padRight(parts[0], 12), padRight(dates[branch], dateColumnWidth), reasons[branch])
+		} else {
+			fmt.Printf("%s %s", padRight(branch, 40), "(unknown)")
+		}
+		if mark := permissionMark(localizer, failures, branch); mark != "" {
+			fmt.Printf(" %s%s%s", ColorRed, mark, ColorReset)
+		}
+		fmt.Println()
+		if local, ok := locals[branch]; ok {
+			fmt.Printf("%s %s\n", padRight(local, 40), localLabel)
+		}
+	}
+	fmt.Println(strings.Repeat("-", 100))
+	if options.summaryBranches != nil {
+		fmt.Println(formatPlanSummary(localizer, summarizePlan(options.summaryBranches, branchesToDelete)))
+	}
+
+	if len(failures) > 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PermissionWarning",
+			TemplateData: map[string]interface{}{"Count": len(failures)},
+		})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+	}
+
+	for _, skipped := range skippedLocals {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    skipped.MessageID,
+			TemplateData: map[string]interface{}{"Branch": skipped.Local, "Remote": skipped.Remote, "Worktree": skipped.Worktree},
+		})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+	}
+
+	// Branches of allowed release-train namespaces are called out
+	for _, branch := range branchesToDelete {
+		if namespace, ok := protectedNamespace(branch); ok {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "NamespaceDeletionWarning",
+				TemplateData: map[string]interface{}{"Branch": branch, "Namespace": namespace},
+			})
+			fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+		}
+	}
+
+	// Deleting the remote of a checked-out branch leaves that worktree's
+	// branch tracking an upstream that is gone
+	for _, branch := range branchesToDelete {
+		if worktree, ok := worktreeForRemoteBranch(branch); ok {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "WorktreeWarning",
+				TemplateData: map[string]interface{}{"Branch": branch, "Worktree": worktree},
+			})
+			fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+		}
+	}
+
+	// Branches stacked on a deleted one lose the base of their pull requests
+	for _, warning := range stackedBranchWarnings(localizer, branchesToDelete, options.base) {
+		fmt.Printf("%s%s%s\n", ColorYellow, warning, ColorReset)
+	}
+
+	// Branches deleted along with them on mirrors are named before anything
+	// is pushed
+	for _, warning := range mirrorWarnings(localizer, branchesToDelete, shown) {
+		fmt.Printf("%s%s%s\n", ColorYellow, warning, ColorReset)
+	}
+
+	// Deleting the only branches that reference large files loses them once
+	// the server collects garbage
+	for _, warning := range largeObjectWarnings(localizer, branchesToDelete) {
+		fmt.Printf("%s%s%s\n", ColorYellow, warning, ColorReset)
+	}
+
+	// From here on, a signal restores the terminal and reports how far the
+	// deletion got instead of killing the process mid-way
+	progress := newDeletionProgress(branchesToDelete)
+	done := sync.OnceFunc(func() {
+		writeJobSummary(localizer, progress)
+		options.finish(progress.deletedBranches())
+	})
+	trap := trapInterrupts(func() {
+		progress.printInterrupted(localizer)
+		done()
+	})
+	defer trap.Stop()
+
+	// A selection spanning several remotes is confirmed one remote at a
+	// time, since deleting from an upstream weighs more than from a fork
+	groups := groupByRemote(branchesToDelete)
+	var confirmed []string
+	for _, group := range groups {
+		if options.assumeYes || confirmRemoteDeletion(localizer, group, len(groups) > 1, options.confirmThreshold) {
+			confirmed = append(confirmed, group.branches...)
+		} else if len(groups) > 1 {
+			for _, branch := range group.branches {
+				emitEvent(Event{Event: "skipped", Branch: branch, Reason: "declined"})
+			}
+		}
+	}
+
+	if len(confirmed) == 0 {
+		cancelMsg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"})
+		fmt.Println(cancelMsg)
+		emitEvent(Event{Event: "cancelled"})
+		os.Exit(0)
+	}
+	if len(confirmed) < len(branchesToDelete) {
+		branchesToDelete = confirmed
+		progress = newDeletionProgress(branchesToDelete)
+	}
+
+	if drifted := detectDrift(branchesToDelete, shown); len(drifted) > 0 {
+		skip := resolveDrift(localizer, drifted, options.assumeYes)
+		// Branches deleted anyway are leased on their new tips
+		for _, branch := range drifted {
+			shown[branch.Branch] = branch.Current
+		}
+		branchesToDelete = slices.DeleteFunc(branchesToDelete, func(branch string) bool {
+			if skip[branch] {
+				emitEvent(Event{Event: "skipped", Branch: branch, Reason: "moved"})
+			}
+			return skip[branch]
+		})
+		if len(branchesToDelete) == 0 {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
+			fmt.Println(msg)
+			os.Exit(0)
+		}
+		progress = newDeletionProgress(branchesToDelete)
+	}
+
+	// Proceed with deletion, offering to retry whatever failed
+	progress.start()
+	tips := remoteTips()
+	for _, branch := range branchesToDelete {
+		if sha := shown[branch]; sha != "" {
+			tips[branch] = sha
+		}
+	}
+	for pending := branchesToDelete; len(pending) > 0; {
+		var failed []string
+		trap.SetBusy(true)
+		deleteInBatches(pending, tips, options.batchSize, options.retries, trap.Interrupted, func(result deletionResult) {
+			var hint string
+			if result.err != nil {
+				hint = pushFailureHint(localizer, result.branches[0], result.output)
+			}
+			for _, branch := range result.branches {
+				if result.err != nil {
+					failed = append(failed, branch)
+					msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+						MessageID:    "ErrorDeletingBranch",
+						TemplateData: map[string]interface{}{"Branch": branch, "Error": result.err},
+					})
+					fmt.Println(msg)
+					reason := result.err.Error()
+					if hint != "" {
+						reason += ": " + hint
+					}
+					progress.markFailed(branch, reason)
+					emitEvent(Event{Event: "failed", Branch: branch, SHA: tips[branch], Error: reason})
+					annotate("warning", branch, msg+"\n"+strings.TrimSpace(result.output))
+				} else {
+					progress.markDeleted(branch)
+					event := Event{Event: "deleted", Branch: branch, SHA: tips[branch]}
+					if softDelete {
+						event.Trash = trashedBranch(branch)
+					}
+					emitEvent(event)
+					msg := deletedMessage(localizer, branch)
+					fmt.Println(msg)
+					annotate("notice", branch, msg)
+				}
+			}
+			fmt.Println(result.output)
+			if result.err == nil {
+				recordDeletions(result.branches, tips, "cli")
+				for _, branch := range result.branches {
+					if local, ok := locals[branch]; ok {
+						deleteLocalCounterpart(localizer, local)
+					}
+				}
+			}
+			if hint != "" {
+				fmt.Printf("%s%s%s\n\n", ColorYellow, hint, ColorReset)
+			}
+		})
+		trap.SetBusy(false)
+		if trap.Interrupted() {
+			trap.exit()
+		}
+		if len(failed) == 0 {
+			break
+		}
+
+		var retry bool
+		if !options.assumeYes {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "RetryFailedDeletions",
+				TemplateData: map[string]interface{}{"Count": len(failed), "Branches": strings.Join(failed, ", ")},
+			})
+			if err := survey.AskOne(&survey.Confirm{Message: msg, Default: false}, &retry, terminalAskOpts()...); err == terminal.InterruptErr {
+				trap.exit()
+			}
+		}
+		if !retry {
+			done()
+			os.Exit(1)
+		}
+		pending = failed
+	}
+	done()
+
+	// A mistake noticed as the last branch goes can still be undone
+	if !options.assumeYes {
+		offerUndo(localizer, progress.deletedBranches(), tips)
+	}
+}
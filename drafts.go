@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// draftFileUnsafe matches the characters of an email address that are not
+// kept in a draft's file name
+var draftFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9@._+-]`)
+
+// notificationDraft is the message telling one author which of their
+// branches were deleted
+type notificationDraft struct {
+	Author string
+	Email  string
+	Text   string
+}
+
+// buildNotificationDrafts groups the deleted branches by author and writes a
+// message for each, listing the branches with the command that restores them
+func buildNotificationDrafts(localizer *i18n.Localizer, deleted []BranchDetail) []notificationDraft {
+	localize := func(id string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		return msg
+	}
+	repository := "."
+	if top, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+		repository = filepath.Base(top)
+	}
+
+	byAuthor := map[string][]BranchDetail{}
+	for _, branch := range deleted {
+		key := strings.ToLower(branch.AuthorEmail)
+		byAuthor[key] = append(byAuthor[key], branch)
+	}
+	keys := make([]string, 0, len(byAuthor))
+	for key := range byAuthor {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var drafts []notificationDraft
+	for _, key := range keys {
+		branches := byAuthor[key]
+		var text strings.Builder
+		text.WriteString(localize("DraftGreeting", map[string]interface{}{"Author": branches[0].Author}) + "\n\n")
+		text.WriteString(localize("DraftIntro", map[string]interface{}{"Count": len(branches), "Repository": repository}) + "\n\n")
+		for _, branch := range branches {
+			text.WriteString(localize("DraftBranch", map[string]interface{}{
+				"Branch": branch.Name,
+				"SHA":    shortSHA(branch.Hash),
+				"Date":   formatDate(localizer, branch.ActivityDate()),
+			}) + "\n")
+		}
+		text.WriteString("\n" + localize("DraftRestore", nil) + "\n\n")
+		for _, branch := range branches {
+			if remoteName, branchName, ok := splitRemoteBranch(branch.Name); ok {
+				fmt.Fprintf(&text, "    git push %s %s:refs/heads/%s\n", remoteName, branch.Hash, branchName)
+			}
+		}
+		drafts = append(drafts, notificationDraft{Author: branches[0].Author, Email: branches[0].AuthorEmail, Text: text.String()})
+	}
+	return drafts
+}
+
+// writeNotificationDrafts writes the notification drafts for the deleted
+// branches to stdout when dest is "-", or else to one file per author in the
+// directory dest
+func writeNotificationDrafts(localizer *i18n.Localizer, deleted []BranchDetail, dest string) error {
+	drafts := buildNotificationDrafts(localizer, deleted)
+	if dest == "-" {
+		for _, draft := range drafts {
+			fmt.Printf("\n----- %s <%s> -----\n%s", draft.Author, draft.Email, draft.Text)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	for _, draft := range drafts {
+		name := draftFileUnsafe.ReplaceAllString(draft.Email, "_")
+		if name == "" {
+			name = "unknown"
+		}
+		if err := os.WriteFile(filepath.Join(dest, name+".txt"), []byte(draft.Text), 0o644); err != nil {
+			return err
+		}
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "DraftsWritten",
+		TemplateData: map[string]interface{}{"Count": len(drafts), "Dir": dest},
+	})
+	fmt.Println(msg)
+	return nil
+}
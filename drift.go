@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// driftedBranch is a branch someone pushed to between being listed and
+// being deleted
+type driftedBranch struct {
+	Branch  string
+	Shown   string
+	Current string
+}
+
+// detectDrift compares the tips the branches were listed with against their
+// remotes right before deleting them and returns the branches that moved.
+// Branches gone from their remote, and remotes that cannot be reached, are
+// left for the deletion to report.
+func detectDrift(branches []string, shown map[string]string) []driftedBranch {
+	heads := map[string]map[string]string{}
+	var drifted []driftedBranch
+	for _, branch := range branches {
+		remoteName, _, ok := splitRemoteBranch(branch)
+		if !ok || shown[branch] == "" {
+			continue
+		}
+		if _, fetched := heads[remoteName]; !fetched {
+			current, err := remoteHeads(remoteName)
+			if err != nil {
+				logger.Debug("could not check for drift", "remote", remoteName, "error", err)
+			}
+			heads[remoteName] = current
+		}
+		if current, ok := heads[remoteName][branch]; ok && current != shown[branch] {
+			drifted = append(drifted, driftedBranch{Branch: branch, Shown: shown[branch], Current: current})
+		}
+	}
+	return drifted
+}
+
+// resolveDrift asks what to do with each drifted branch: delete it anyway,
+// look at the new commits first, or skip it, and returns the branches to
+// skip. Branches deleted anyway are fetched so that the deletion is leased on
+// their new tips. With assumeYes every drifted branch is skipped.
+func resolveDrift(localizer *i18n.Localizer, drifted []driftedBranch, assumeYes bool) map[string]bool {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	skip := map[string]bool{}
+	for _, branch := range drifted {
+		data := map[string]interface{}{"Branch": branch.Branch, "Shown": shortSHA(branch.Shown), "Current": shortSHA(branch.Current)}
+		fmt.Printf("%s%s%s\n", ColorYellow, localize("BranchDrifted", data), ColorReset)
+		if assumeYes {
+			fmt.Println(localize("DriftSkipped", data))
+			skip[branch.Branch] = true
+			continue
+		}
+
+		actions := []struct{ Name, MessageID string }{
+			{"delete", "DriftActionDelete"},
+			{"show", "DriftActionShow"},
+			{"skip", "DriftActionSkip"},
+		}
+		var options []string
+		for _, action := range actions {
+			options = append(options, localize(action.MessageID, nil))
+		}
+		fetched := false
+		for {
+			index := askDriftAction(localize("DriftActionPrompt", data), options)
+			action := "skip"
+			if index >= 0 {
+				action = actions[index].Name
+			}
+			if action == "skip" {
+				fmt.Println(localize("DriftSkipped", data))
+				skip[branch.Branch] = true
+				break
+			}
+			if !fetched {
+				if err := fetchRemoteBranch(branch.Branch); err != nil {
+					fmt.Printf("%s%s%s\n", ColorRed, localize("DriftFetchFailed", map[string]interface{}{"Branch": branch.Branch, "Error": err}), ColorReset)
+					skip[branch.Branch] = true
+					break
+				}
+				fetched = true
+			}
+			if action == "delete" {
+				break
+			}
+			commits, err := runGit("log", "--format=%h %an: %s", branch.Shown+".."+branch.Current)
+			if err != nil || commits == "" {
+				// A force push leaves no new commits on top of the shown tip
+				commits, _ = runGit("log", "-1", "--format=%h %an: %s", branch.Current)
+			}
+			fmt.Println(localize("DriftNewCommits", data))
+			for _, line := range strings.Split(commits, "\n") {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+	}
+	return skip
+}
+
+// askDriftAction asks which of options to take, returning its index or -1
+// when the prompt was cancelled
+func askDriftAction(message string, options []string) int {
+	if plainMode {
+		return askPlainChoice(message, options)
+	}
+	var index int
+	if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &index, terminalAskOpts()...); err != nil {
+		return -1
+	}
+	return index
+}
+
+// fetchRemoteBranch updates the remote-tracking ref of a remote branch
+func fetchRemoteBranch(branch string) error {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return fmt.Errorf("invalid branch format: %s", branch)
+	}
+	_, err := runGit("fetch", "--no-tags", remoteName, "+refs/heads/"+branchName+":refs/remotes/"+branch)
+	return err
+}
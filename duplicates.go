@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// DuplicateGroup is a set of remote branches whose tips have the same tree:
+// usually the same commit, e.g. after a mirror sync or a branch pushed under
+// two names, but also different commits with identical content, e.g. after a
+// force-push that only rewrote history
+type DuplicateGroup struct {
+	Tree string `json:"tree"`
+	// SameCommit is true when every branch points at the same commit
+	SameCommit bool           `json:"sameCommit"`
+	Branches   []DuplicateTip `json:"branches"`
+}
+
+// DuplicateTip is a branch of a duplicate group and its tip
+type DuplicateTip struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"`
+
+	detail BranchDetail
+}
+
+// findDuplicates groups the remote branches whose tips have the same tree.
+// Branches in the trash are left out.
+func findDuplicates(remoteBranches []BranchDetail, base string) ([]DuplicateGroup, error) {
+	output, err := runGit("for-each-ref", "refs/remotes", "--format=%(refname:lstrip=2) %(tree)")
+	if err != nil {
+		return nil, err
+	}
+	trees := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if name, tree, ok := strings.Cut(line, " "); ok {
+			trees[name] = tree
+		}
+	}
+
+	byTree := map[string][]DuplicateTip{}
+	for _, branch := range withoutTrash(remoteBranches) {
+		if tree := trees[branch.Name]; tree != "" {
+			byTree[tree] = append(byTree[tree], DuplicateTip{Name: branch.Name, SHA: branch.Hash, detail: branch})
+		}
+	}
+
+	var groups []DuplicateGroup
+	for tree, tips := range byTree {
+		if len(tips) < 2 {
+			continue
+		}
+		// The branch most likely worth keeping comes first: the base branch,
+		// then protected branches, then the most recently active
+		sort.SliceStable(tips, func(i, j int) bool {
+			if rank, other := duplicateRank(tips[i].Name, base), duplicateRank(tips[j].Name, base); rank != other {
+				return rank < other
+			}
+			if !tips[i].detail.ActivityDate().Equal(tips[j].detail.ActivityDate()) {
+				return tips[i].detail.ActivityDate().After(tips[j].detail.ActivityDate())
+			}
+			return tips[i].Name < tips[j].Name
+		})
+		group := DuplicateGroup{Tree: tree, SameCommit: true, Branches: tips}
+		for _, tip := range tips {
+			group.SameCommit = group.SameCommit && tip.SHA == tips[0].SHA
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Branches[0].Name < groups[j].Branches[0].Name })
+	return groups, nil
+}
+
+// duplicateRank orders the branches of a duplicate group by how likely they
+// are to be kept
+func duplicateRank(branch, base string) int {
+	switch {
+	case branch == base:
+		return 0
+	case isProtectedBranch(branch):
+		return 1
+	}
+	return 2
+}
+
+// printDuplicates lists the duplicate groups, or prints them as JSON
+func printDuplicates(localizer *i18n.Localizer, groups []DuplicateGroup, asJSON bool) error {
+	if asJSON {
+		if groups == nil {
+			groups = []DuplicateGroup{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(groups)
+	}
+
+	if len(groups) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoDuplicates"})
+		fmt.Println(msg)
+		return nil
+	}
+	for _, group := range groups {
+		messageID := "DuplicateSameCommit"
+		if !group.SameCommit {
+			messageID = "DuplicateSameTree"
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    messageID,
+			TemplateData: map[string]interface{}{"SHA": shortSHA(group.Branches[0].SHA), "Tree": shortSHA(group.Tree), "Count": len(group.Branches)},
+		})
+		fmt.Println(msg)
+		for _, tip := range group.Branches {
+			fmt.Printf("  %s %s%s %s %s%s\n", padRight(tip.Name, 40), ColorDim, shortSHA(tip.SHA), padRight(formatDate(localizer, tip.detail.ActivityDate()), dateColumnWidth), tip.detail.Author, ColorReset)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// chooseDuplicatesToDelete asks which branch of each duplicate group to keep
+// and returns the others. The base branch and protected branches are never
+// returned.
+func chooseDuplicatesToDelete(localizer *i18n.Localizer, groups []DuplicateGroup, base string) ([]string, error) {
+	skip, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "DuplicateKeepAll"})
+	var toDelete []string
+	for _, group := range groups {
+		var options []string
+		for _, tip := range group.Branches {
+			options = append(options, tip.Name)
+		}
+		options = append(options, skip)
+		message, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "DuplicateKeepPrompt",
+			TemplateData: map[string]interface{}{"Count": len(group.Branches)},
+		})
+
+		var keep int
+		if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &keep, terminalAskOpts()...); err != nil {
+			return nil, err
+		}
+		if keep == len(group.Branches) {
+			continue
+		}
+		for i, tip := range group.Branches {
+			if i != keep && duplicateRank(tip.Name, base) == 2 {
+				toDelete = append(toDelete, tip.Name)
+			}
+		}
+	}
+	return toDelete, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// emailSettings configure where the deletion report is sent and through
+// which SMTP server. The password is read from GRBM_SMTP_PASSWORD so it never
+// ends up in a configuration file or the shell history.
+type emailSettings struct {
+	// To is a comma-separated list of addresses, or "authors" to send each
+	// author the report of their own branches
+	To   string
+	Host string // host:port
+	User string
+	From string
+}
+
+// sendDeletionReport emails the list of deleted branches with their tip SHAs
+// and authors
+func sendDeletionReport(localizer *i18n.Localizer, settings emailSettings, deleted []BranchDetail) error {
+	from := settings.From
+	if from == "" {
+		from = settings.User
+	}
+	if from == "" {
+		return fmt.Errorf("no sender address: set smtp-from")
+	}
+
+	if settings.To != "authors" {
+		var to []string
+		for _, address := range strings.Split(settings.To, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				to = append(to, address)
+			}
+		}
+		return sendEmail(localizer, settings, from, to, deleted)
+	}
+
+	byAuthor := map[string][]BranchDetail{}
+	for _, branch := range deleted {
+		if branch.AuthorEmail != "" {
+			byAuthor[branch.AuthorEmail] = append(byAuthor[branch.AuthorEmail], branch)
+		}
+	}
+	authors := make([]string, 0, len(byAuthor))
+	for author := range byAuthor {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	for _, author := range authors {
+		if err := sendEmail(localizer, settings, from, []string{author}, byAuthor[author]); err != nil {
+			return fmt.Errorf("%s: %w", author, err)
+		}
+	}
+	return nil
+}
+
+// sendEmail sends the report of branches to the recipients in to
+func sendEmail(localizer *i18n.Localizer, settings emailSettings, from string, to []string, branches []BranchDetail) error {
+	localize := func(id string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		return msg
+	}
+	repository := "."
+	if top, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+		repository = top
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\r\n\r\n", localize("EmailReportIntro", map[string]interface{}{"Count": len(branches), "Repository": repository}))
+	for _, branch := range branches {
+		fmt.Fprintf(&body, "%s  %s  %s <%s>\r\n", shortSHA(branch.Hash), branch.Name, branch.Author, branch.AuthorEmail)
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", localize("EmailReportSubject", map[string]interface{}{"Count": len(branches)})))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(body.String())
+
+	var auth smtp.Auth
+	if settings.User != "" {
+		host, _, err := net.SplitHostPort(settings.Host)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", settings.User, os.Getenv("GRBM_SMTP_PASSWORD"), host)
+	}
+	logger.Debug("smtp", "host", settings.Host, "to", to)
+	return smtp.SendMail(settings.Host, auth, from, to, []byte(message.String()))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// envConfigKey is the configuration table of environment variables every
+// subprocess inherits, such as git, ssh and the finder, e.g.
+//
+//	[env]
+//	GIT_SSH_COMMAND = "ssh -J bastion.example.com -i ~/.ssh/deploy"
+//	HTTPS_PROXY = "http://proxy.example.com:3128"
+//
+// Variables already set in the environment keep their values, like any other
+// setting given in the environment.
+const envConfigKey = "env"
+
+// envNamePattern matches the names of environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvConfig parses the env table of a configuration file
+func parseEnvConfig(value interface{}) (map[string]string, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", envConfigKey)
+	}
+	env := make(map[string]string, len(table))
+	for name, setting := range table {
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%s: invalid variable name %q", envConfigKey, name)
+		}
+		text, ok := setting.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid value for %s: %v", envConfigKey, name, setting)
+		}
+		env[name] = text
+	}
+	return env, nil
+}
+
+// applyEnvironment sets the configured environment variables that are not
+// set yet. It runs right after the configuration is loaded, so the git
+// commands that found the repository's configuration file and picked the
+// profile from its remote (git rev-parse, git remote) do not see the
+// variables; every later subprocess and request does.
+func applyEnvironment(env map[string]string) {
+	for name, value := range env {
+		if _, set := os.LookupEnv(name); set {
+			logger.Debug("environment variable already set, keeping it", "name", name)
+			continue
+		}
+		os.Setenv(name, value)
+		logger.Debug("environment variable set from configuration", "name", name)
+	}
+}
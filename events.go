@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is a line of the -output jsonl event stream. Its fields are stable
+// and never localized, for wrappers to parse.
+type Event struct {
+	// Event is one of listed, selected, skipped, cancelled, deleted, failed and
+	// restored
+	Event  string    `json:"event"`
+	Time   time.Time `json:"time"`
+	Branch string    `json:"branch,omitempty"`
+	SHA    string    `json:"sha,omitempty"`
+	Author string    `json:"author,omitempty"`
+	// Reason is why a branch was skipped, e.g. "protected"
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Trash is the branch a soft deletion moved a deleted branch to
+	Trash string `json:"trash,omitempty"`
+}
+
+var (
+	// eventOutput receives the event stream; it is nil unless -output jsonl
+	// is given
+	eventOutput  io.Writer
+	eventEncoder *json.Encoder
+	eventMu      sync.Mutex
+)
+
+// startEventStream makes stdout carry only the event stream: everything
+// else the tool prints, its prompts included, goes to stderr from now on
+func startEventStream() {
+	eventOutput = os.Stdout
+	eventEncoder = json.NewEncoder(eventOutput)
+	os.Stdout = os.Stderr
+}
+
+// emitEvent writes an event to the event stream, if there is one
+func emitEvent(event Event) {
+	if eventOutput == nil {
+		return
+	}
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	event.Time = time.Now().UTC()
+	eventEncoder.Encode(event)
+}
+
+// emitBranchEvents writes an event of the same kind for each of the branches
+func emitBranchEvents(kind string, branches []string) {
+	for _, branch := range branches {
+		emitEvent(Event{Event: kind, Branch: branch})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// uniqueCommit is a commit of a branch that is not reachable from the base branch
+type uniqueCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	// Applied is true when base has a commit with the same patch, e.g.
+	// because the commit was cherry-picked or the branch was rebased
+	Applied bool `json:"applied"`
+}
+
+// listUniqueCommits returns the commits of branch not reachable from base,
+// oldest first, as reported by git cherry
+func listUniqueCommits(branch, base string) ([]uniqueCommit, error) {
+	output, err := runGit("cherry", "-v", base, branch)
+	if err != nil {
+		return nil, err
+	}
+	var commits []uniqueCommit
+	for _, line := range strings.Split(output, "\n") {
+		mark, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		hash, subject, _ := strings.Cut(rest, " ")
+		commits = append(commits, uniqueCommit{Hash: hash, Subject: subject, Applied: mark == "-"})
+	}
+	return commits, nil
+}
+
+// isContentInBase reports whether every file branch changed since it forked
+// from base has the same content in base, which is what a squash merge
+// leaves behind
+func isContentInBase(branch, base string) (bool, error) {
+	files, err := runGit("diff", "--name-only", base+"..."+branch)
+	if err != nil || files == "" {
+		return false, err
+	}
+	args := append([]string{"diff", "--quiet", base, branch, "--"}, strings.Split(files, "\n")...)
+	if err := gitCommand(args...).Run(); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// printExplain explains why branch is reported as unmerged: it lists the
+// commits base lacks, marking those whose patch base already has, and tells
+// whether the branch's changes appear in base anyway, so they can be checked
+// before the branch is deleted
+func printExplain(localizer *i18n.Localizer, branch, base string) error {
+	commits, err := listUniqueCommits(branch, base)
+	if err != nil {
+		return err
+	}
+	localize := func(id string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		return msg
+	}
+	data := map[string]interface{}{"Branch": branch, "Base": base, "Count": len(commits)}
+	if len(commits) == 0 {
+		fmt.Printf("%s%s%s\n", ColorGreen, localize("ExplainMerged", data), ColorReset)
+		return nil
+	}
+
+	fmt.Println(localize("ExplainUniqueCommits", data))
+	applied := 0
+	for _, commit := range commits {
+		if commit.Applied {
+			applied++
+			fmt.Printf("  %s%s %s %s%s\n", ColorGreen, shortSHA(commit.Hash), commit.Subject, localize("ExplainApplied", nil), ColorReset)
+		} else {
+			fmt.Printf("  %s%s%s %s\n", ColorYellow, shortSHA(commit.Hash), ColorReset, commit.Subject)
+		}
+	}
+	fmt.Println()
+
+	data["Applied"] = applied
+	data["Remaining"] = len(commits) - applied
+	switch squashed, _ := isContentInBase(branch, base); {
+	case applied == len(commits):
+		fmt.Printf("%s%s%s\n", ColorGreen, localize("ExplainAllApplied", data), ColorReset)
+	case squashed:
+		fmt.Printf("%s%s%s\n", ColorGreen, localize("ExplainSquashed", data), ColorReset)
+	default:
+		fmt.Printf("%s%s%s\n", ColorRed, localize("ExplainNotApplied", data), ColorReset)
+	}
+	return nil
+}
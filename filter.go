@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// branchFilters are the presets accepted by -filter, in the order the filter
+// menu lists them
+var branchFilters = []struct {
+	Name      string
+	MessageID string
+}{
+	{"all", "FilterAll"},
+	{"merged", "FilterMerged"},
+	{"stale", "FilterStale"},
+	{"mine", "FilterMine"},
+	{"team", "FilterTeam"},
+}
+
+// isBranchFilter reports whether name is a filter preset or "menu"
+func isBranchFilter(name string) bool {
+	if name == "menu" {
+		return true
+	}
+	for _, filter := range branchFilters {
+		if filter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseBranchFilter asks which preset to load branches with
+func chooseBranchFilter(localizer *i18n.Localizer) (string, error) {
+	var options, names []string
+	for _, filter := range branchFilters {
+		if filter.Name == "team" && codeownersTeam == "" {
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    filter.MessageID,
+			TemplateData: map[string]interface{}{"Age": shortDuration(ageThresholds.Stale), "Team": codeownersTeam},
+		})
+		options = append(options, msg)
+		names = append(names, filter.Name)
+	}
+	message, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "FilterPrompt"})
+
+	var index int
+	if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &index, terminalAskOpts()...); err != nil {
+		return "", err
+	}
+	return names[index], nil
+}
+
+// filterBranches returns the branches matching a filter preset. Presets other
+// than "all" leave out protected branches, which could not be deleted anyway.
+func filterBranches(remoteBranches []BranchDetail, base, filter string) []BranchDetail {
+	if filter == "all" {
+		return remoteBranches
+	}
+
+	var keep func(BranchDetail, BranchAnalysis) bool
+	switch filter {
+	case "merged":
+		keep = func(_ BranchDetail, analysis BranchAnalysis) bool { return analysis.Merged }
+	case "stale":
+		keep = func(branch BranchDetail, _ BranchAnalysis) bool {
+			return time.Since(branch.ActivityDate()) >= ageThresholds.Stale
+		}
+	case "mine":
+		email, _ := runGit("config", "--get", "user.email")
+		keep = func(branch BranchDetail, _ BranchAnalysis) bool {
+			return email != "" && strings.EqualFold(branch.AuthorEmail, email)
+		}
+	case "team":
+		keep = func(branch BranchDetail, _ BranchAnalysis) bool {
+			return isOwnedByTeam(branchOwners(branch.Name, base))
+		}
+	}
+
+	matched := map[string]bool{}
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if (!isProtectedBranch(branch.Name) || includeProtected && !isFrozenBranch(branch.Name)) && keep(branch, analysis) {
+			matched[branch.Name] = true
+		}
+	})
+	var filtered []BranchDetail
+	for _, branch := range remoteBranches {
+		if matched[branch.Name] {
+			filtered = append(filtered, branch)
+		}
+	}
+	return filtered
+}
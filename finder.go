@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// Finder is an interactive fuzzy finder used to pick branches from a list
+// streamed to its standard input. Selected lines are read from its standard
+// output.
+type Finder interface {
+	// Name returns the executable name of the finder
+	Name() string
+	// Command builds the finder invocation. previewCommand is a shell command
+	// in which {} is replaced by the highlighted line; finders without a
+	// preview window ignore it.
+	Command(previewCommand string) *exec.Cmd
+	// SupportsANSI reports whether the finder renders ANSI colors in items
+	SupportsANSI() bool
+	// IsCancelled reports whether exitCode means the user aborted the selection
+	IsCancelled(exitCode int) bool
+}
+
+// reloadableFinder is implemented by finders whose list can be replaced
+// while they run
+type reloadableFinder interface {
+	Finder
+	// ListenCommand is like Command, additionally making the finder accept
+	// actions over HTTP on addr ("host:port") from clients sending key; ok
+	// is false if it cannot
+	ListenCommand(previewCommand, addr, key string) (cmd *exec.Cmd, ok bool)
+	// Reload replaces the list of the finder listening on addr with the
+	// lines of the file at path
+	Reload(addr, key, path string) error
+}
+
+// fzfFinder drives fzf (https://github.com/junegunn/fzf). fzf also applies
+// FZF_DEFAULT_OPTS itself; options are appended after ours so they win.
+type fzfFinder struct {
+	options []string
+	// bindings are the options of fzfBindings, for fzf releases that
+	// support them
+	bindings []string
+}
+
+func (fzfFinder) Name() string { return "fzf" }
+
+func (f fzfFinder) Command(previewCommand string) *exec.Cmd {
+	args := append([]string{"--multi", "--ansi", "--preview", previewCommand}, f.bindings...)
+	return exec.Command("fzf", append(args, f.options...)...)
+}
+
+func (fzfFinder) SupportsANSI() bool { return true }
+
+func (fzfFinder) IsCancelled(exitCode int) bool { return exitCode == 130 }
+
+// fzfListenVersion is the first fzf release with --listen
+var fzfListenVersion = [2]int{0, 36}
+
+// fzfAtLeast reports whether the installed fzf is the given release or later
+func fzfAtLeast(version [2]int) bool {
+	output, err := exec.Command("fzf", "--version").Output()
+	if err != nil {
+		return false
+	}
+	// e.g. "0.44.1 (d7d2ac3)"
+	var major, minor int
+	if _, err := fmt.Sscanf(string(output), "%d.%d", &major, &minor); err != nil {
+		return false
+	}
+	return major > version[0] || (major == version[0] && minor >= version[1])
+}
+
+// ListenCommand makes fzf listen on addr. Without FZF_API_KEY, any local
+// process could post actions such as execute(...) to the port, so fzf is
+// given key to require.
+func (f fzfFinder) ListenCommand(previewCommand, addr, key string) (*exec.Cmd, bool) {
+	if !fzfAtLeast(fzfListenVersion) {
+		return nil, false
+	}
+	_, port, _ := net.SplitHostPort(addr)
+	cmd := f.Command(previewCommand)
+	cmd.Args = append(cmd.Args, "--listen="+port)
+	cmd.Env = append(os.Environ(), "FZF_API_KEY="+key)
+	return cmd, true
+}
+
+// Reload posts a reload action to fzf. fzf may not be listening yet when the
+// analysis is quick, so the request is retried for a moment.
+func (fzfFinder) Reload(addr, key, path string) error {
+	action := "reload(" + shellquote.Join("cat", path) + ")"
+	var err error
+	for attempt := 0; attempt < 20; attempt++ {
+		if attempt > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		var req *http.Request
+		if req, err = http.NewRequest(http.MethodPost, "http://"+addr, strings.NewReader(action)); err != nil {
+			return err
+		}
+		req.Header.Set("X-Api-Key", key)
+		var resp *http.Response
+		if resp, err = http.DefaultClient.Do(req); err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("fzf rejected reload: %s", resp.Status)
+		}
+		return nil
+	}
+	return err
+}
+
+// freeLocalPort returns the address of a TCP port on the loopback interface
+// that is free at the time of the call
+func freeLocalPort() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
+
+// skimFinder drives skim (https://github.com/lotabout/skim), whose flags
+// mirror fzf's
+type skimFinder struct {
+	options []string
+}
+
+func (skimFinder) Name() string { return "sk" }
+
+func (f skimFinder) Command(previewCommand string) *exec.Cmd {
+	args := append([]string{"--multi", "--ansi", "--preview", previewCommand}, f.options...)
+	return exec.Command("sk", args...)
+}
+
+func (skimFinder) SupportsANSI() bool { return true }
+
+func (skimFinder) IsCancelled(exitCode int) bool { return exitCode == 130 }
+
+// pecoFinder drives peco (https://github.com/peco/peco). Multiple lines are
+// selected with Ctrl+Space; peco has no preview window.
+type pecoFinder struct{}
+
+func (pecoFinder) Name() string { return "peco" }
+
+func (pecoFinder) Command(string) *exec.Cmd {
+	return exec.Command("peco")
+}
+
+func (pecoFinder) SupportsANSI() bool { return false }
+
+func (pecoFinder) IsCancelled(exitCode int) bool { return exitCode == 1 }
+
+// gumFinder drives gum choose (https://github.com/charmbracelet/gum), which
+// has no preview window
+type gumFinder struct{}
+
+func (gumFinder) Name() string { return "gum" }
+
+func (gumFinder) Command(string) *exec.Cmd {
+	return exec.Command("gum", "choose", "--no-limit")
+}
+
+func (gumFinder) SupportsANSI() bool { return false }
+
+func (gumFinder) IsCancelled(exitCode int) bool { return exitCode == 1 || exitCode == 130 }
+
+// lookupFinder returns the finder with the given name, or the first installed
+// finder when name is empty. fzfOptions are passed through to fzf-compatible
+// finders (fzf and sk).
+func lookupFinder(name string, fzfOptions []string) (Finder, error) {
+	// Supported finders in auto-detection order
+	finders := []Finder{fzfFinder{options: fzfOptions}, skimFinder{fzfOptions}, pecoFinder{}, gumFinder{}}
+	for _, finder := range finders {
+		if name != "" && finder.Name() != name {
+			continue
+		}
+		if _, err := exec.LookPath(finder.Name()); err != nil {
+			if name != "" {
+				return nil, err
+			}
+			continue
+		}
+		return finder, nil
+	}
+	if name != "" {
+		return nil, fmt.Errorf("unsupported finder: %s", name)
+	}
+	return nil, exec.ErrNotFound
+}
+
+// placeholderThreshold is the number of branches above which a reloadable
+// finder is shown every branch right away, with a placeholder indicator, and
+// reloaded once the analysis completes
+const placeholderThreshold = 200
+
+// selectWithFinder streams the remote branches into the finder and returns the
+// cleaned names of the branches the user selected. previewCommand renders the
+// preview of the highlighted line.
+func selectWithFinder(localizer *i18n.Localizer, finder Finder, remoteBranches []BranchDetail, base, previewCommand string) []string {
+	if _, ok := finder.(plainFinder); ok {
+		return selectPlain(localizer, remoteBranches, base)
+	}
+	if reloadable, ok := finder.(reloadableFinder); ok && len(remoteBranches) > placeholderThreshold {
+		// Every run gets its own key for the finder's HTTP server
+		addr, err := freeLocalPort()
+		key, keyErr := generateAPIToken()
+		if err == nil && keyErr == nil {
+			if finderCmd, ok := reloadable.ListenCommand(previewCommand, addr, key); ok {
+				// The finder reads the reloaded list from this file, possibly
+				// after the reload request has returned
+				if list, err := os.CreateTemp("", "grbm-branches-*.txt"); err == nil {
+					list.Close()
+					defer os.Remove(list.Name())
+					return runFinder(localizer, finder, finderCmd, func(w io.WriteCloser) {
+						feedWithPlaceholders(localizer, reloadable, addr, key, list.Name(), w, remoteBranches, base)
+					})
+				}
+			}
+		}
+	}
+
+	// Stream branches to the finder's stdin as their analysis completes
+	return runFinder(localizer, finder, finder.Command(previewCommand), func(w io.WriteCloser) {
+		defer w.Close()
+		analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+			fmt.Fprintln(w, finderLine(localizer, finder, branch, analysis))
+		})
+	})
+}
+
+// feedWithPlaceholders writes every branch to the finder at once with a
+// placeholder indicator, so that the list appears immediately even on
+// repositories with thousands of branches, and replaces the list with the
+// analyzed branches, in the same order, written to listPath, once the
+// analysis completes
+func feedWithPlaceholders(localizer *i18n.Localizer, finder reloadableFinder, addr, key, listPath string, w io.WriteCloser, remoteBranches []BranchDetail, base string) {
+	placeholder := localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "TUIAnalyzing"})
+	index := make(map[string]int, len(remoteBranches))
+	for i, branch := range remoteBranches {
+		index[branch.Name] = i
+		fmt.Fprintf(w, "%s %s%s\n", branch.Name, placeholder, subjectSuffix(branch, false))
+	}
+	w.Close()
+
+	lines := make([]string, len(remoteBranches))
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		lines[index[branch.Name]] = finderLine(localizer, finder, branch, analysis)
+	})
+
+	err := os.WriteFile(listPath, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	if err == nil {
+		err = finder.Reload(addr, key, listPath)
+	}
+	if err != nil {
+		// The finder may simply have exited before the analysis completed
+		logger.Debug("finder reload", "error", err)
+	}
+}
+
+// showSubjects is set from -subjects
+var showSubjects bool
+
+// subjectSuffix returns the subject of the last commit of a branch to append
+// to its finder line with -subjects, so that searching for e.g. a ticket
+// number finds the branches that contain it
+func subjectSuffix(branch BranchDetail, ansi bool) string {
+	if !showSubjects || branch.Message == "" {
+		return ""
+	}
+	if ansi {
+		return "  " + ColorDim + branch.Message + ColorReset
+	}
+	return "  " + branch.Message
+}
+
+// finderLine formats a branch for the finder: its name colored by status
+// followed by its indicator, distance from the base branch, age, whether it
+// was recently force-pushed or is snoozed, the tickets it refers to with -tickets and, with
+// -subjects, the subject of its last commit
+func finderLine(localizer *i18n.Localizer, finder Finder, branch BranchDetail, analysis BranchAnalysis) string {
+	indicator, color := branchIndicator(localizer, branch, analysis)
+	var suffix string
+	if distance := formatAheadBehind(analysis.AheadBehind); distance != "" && !isProtectedBranch(branch.Name) {
+		suffix = " " + distance
+	}
+	if ageSuffix, ageColor := ageAnnotation(localizer, branch); ageSuffix != "" {
+		suffix += " " + ageColor + ageSuffix + ColorReset
+	}
+	if forcePushed := forcePushedAnnotation(localizer, analysis.ForcePushed); forcePushed != "" {
+		suffix += " " + ColorYellow + forcePushed + ColorReset
+	}
+	if until, snoozed := snoozedUntil(branch); snoozed {
+		suffix += " " + ColorDim + snoozeAnnotation(localizer, until) + ColorReset
+	}
+	if tickets := ticketAnnotation(localizer, branch, analysis.Merged); tickets != "" {
+		suffix += " [" + tickets + "]"
+	}
+	if finder.SupportsANSI() {
+		return fmt.Sprintf("%s%s %s%s%s%s", color, branch.Name, indicator, ColorReset, suffix, subjectSuffix(branch, true))
+	}
+	return fmt.Sprintf("%s %s%s%s", branch.Name, indicator, ansiStripper.ReplaceAllString(suffix, ""), subjectSuffix(branch, false))
+}
+
+// runFinder runs finderCmd while feed writes its items to the finder's
+// stdin, and returns the cleaned names of the selected branches. feed must
+// close the writer once every item is written; it may keep working after
+// that, and runFinder waits for it so the analysis cache gets written out.
+func runFinder(localizer *i18n.Localizer, finder Finder, finderCmd *exec.Cmd, feed func(io.WriteCloser)) []string {
+	finderCmd.Stderr = os.Stderr // Show finder errors
+
+	finderStdin, err := finderCmd.StdinPipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating stdin pipe for %s: %v\n", finder.Name(), err)
+		os.Exit(1)
+	}
+	feedDone := make(chan struct{})
+	go func() {
+		defer close(feedDone)
+		feed(finderStdin)
+	}()
+
+	// Capture finder stdout
+	var finderStdout bytes.Buffer
+	finderCmd.Stdout = &finderStdout
+
+	// Run the finder, then let the analysis finish so the cache is written out
+	err = finderCmd.Run()
+	<-feedDone
+	if err != nil {
+		// Finders return a non-zero exit code if no selection or cancelled
+		if exitError, ok := err.(*exec.ExitError); ok && finder.IsCancelled(exitError.ExitCode()) {
+			// User cancelled (Ctrl+C or Esc)
+			fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"}))
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", finder.Name(), err)
+		os.Exit(1)
+	}
+
+	var selected []string
+	for _, selectedItem := range strings.Split(strings.TrimSpace(finderStdout.String()), "\n") {
+		if cleanedBranch := cleanBranchName(selectedItem); cleanedBranch != "" {
+			selected = append(selected, cleanedBranch)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// shortDuration formats a duration compactly (e.g. "5d", "3mo", "2y")
+func shortDuration(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case age < 30*24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo", int(age.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy", int(age.Hours()/24/365))
+	}
+}
+
+// formatAheadBehind formats the distance of a branch from its base compactly,
+// e.g. "↑2 ↓400", leaving out zero counts
+func formatAheadBehind(counts AheadBehind) string {
+	var parts []string
+	if counts.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", counts.Ahead))
+	}
+	if counts.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", counts.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// padRight pads s with spaces to width terminal columns. Unlike %-*s it
+// counts wide characters such as CJK ones as two columns, so tables with
+// Japanese branch names or headers line up.
+func padRight(s string, width int) string {
+	return runewidth.FillRight(s, width)
+}
+
+// padLeft right-aligns s in width terminal columns
+func padLeft(s string, width int) string {
+	return runewidth.FillLeft(s, width)
+}
+
+// displayWidth returns the number of terminal columns s occupies
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
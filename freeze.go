@@ -0,0 +1,109 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// branchProtectFile lists, in the top-level directory of the repository, the
+// patterns of branches this tool must never delete. It is committed with the
+// repository so the whole team shares it, and is read from the base branch
+// like a .gitignore:
+//
+//	# Branches of live deployments
+//	deploy/*
+//	!deploy/scratch-*
+//
+// Blank lines and lines starting with # are ignored, a leading ! excludes
+// the branches matching the pattern again, and the last matching pattern
+// wins. A leading backslash escapes a # or ! that is part of the pattern.
+const branchProtectFile = ".branchprotect"
+
+// freezePattern is a pattern of the branch protect file
+type freezePattern struct {
+	pattern string
+	negated bool
+}
+
+// freezeBase is the branch the branch protect file is read from; set from
+// -base, origin's default branch without it
+var freezeBase string
+
+var (
+	freezePatternsOnce sync.Once
+	freezePatterns     []freezePattern
+)
+
+// loadFreezePatterns returns the patterns of the branch protect file, if the
+// repository has one. The file is read from the base branch, so that a
+// checked-out branch cannot unfreeze branches by changing it, and from the
+// work tree only when the base branch cannot be read.
+func loadFreezePatterns() []freezePattern {
+	freezePatternsOnce.Do(func() {
+		base := cmp.Or(freezeBase, defaultBase())
+		if content, err := runGit("show", base+":"+branchProtectFile); err == nil {
+			freezePatterns = parseFreezePatterns(content)
+			return
+		}
+		toplevel, err := runGit("rev-parse", "--show-toplevel")
+		if err != nil {
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(toplevel, branchProtectFile))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.Debug("could not read branch protect file", "error", err)
+			}
+			return
+		}
+		freezePatterns = parseFreezePatterns(string(data))
+	})
+	return freezePatterns
+}
+
+// parseFreezePatterns parses the contents of a branch protect file
+func parseFreezePatterns(data string) []freezePattern {
+	var patterns []freezePattern
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var pattern freezePattern
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			pattern.negated = true
+			line = rest
+		} else {
+			line = strings.TrimPrefix(line, `\`)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			pattern.pattern = line
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// frozenPattern returns the pattern of the branch protect file that freezes a
+// branch, which is then never deleted, not even with -include-protected
+func frozenPattern(branchName string) (string, bool) {
+	var frozen string
+	for _, pattern := range loadFreezePatterns() {
+		if matchesBranchPattern(pattern.pattern, branchName) {
+			frozen = ""
+			if !pattern.negated {
+				frozen = pattern.pattern
+			}
+		}
+	}
+	return frozen, frozen != ""
+}
+
+// isFrozenBranch reports whether the branch protect file freezes a branch
+func isFrozenBranch(branchName string) bool {
+	_, frozen := frozenPattern(branchName)
+	return frozen
+}
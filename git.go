@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitBinary is the git executable every git command runs, set from
+// -git-path or the binary of the git configuration table
+var gitBinary = "git"
+
+// gitConfigKey is the configuration table of the git executable, e.g.
+//
+//	[git]
+//	binary = "/opt/git/bin/git"
+const gitConfigKey = "git"
+
+// parseGitConfig parses the git table of a configuration file, returning
+// the configured binary
+func parseGitConfig(value interface{}) (string, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("%s must be a table", gitConfigKey)
+	}
+	var binary string
+	for key, setting := range table {
+		if key != "binary" {
+			return "", fmt.Errorf("%s: unknown key %q", gitConfigKey, key)
+		}
+		if binary, ok = setting.(string); !ok || binary == "" {
+			return "", fmt.Errorf("%s: invalid value for %s: %v", gitConfigKey, key, setting)
+		}
+	}
+	return binary, nil
+}
+
+// gitCmd is a git command whose executions are logged with -verbose
+type gitCmd struct {
+	*exec.Cmd
+}
+
+// gitCommand builds a git command; all git invocations go through here
+func gitCommand(args ...string) *gitCmd {
+	return &gitCmd{exec.Command(gitBinary, args...)}
+}
+
+func (c *gitCmd) exitCode() int {
+	if c.ProcessState == nil {
+		return -1
+	}
+	return c.ProcessState.ExitCode()
+}
+
+func (c *gitCmd) Run() error {
+	return logCommand(c.Args, c.Cmd.Run, c.exitCode)
+}
+
+func (c *gitCmd) Output() ([]byte, error) {
+	var output []byte
+	err := logCommand(c.Args, func() (err error) {
+		output, err = c.Cmd.Output()
+		return err
+	}, c.exitCode)
+	return output, err
+}
+
+func (c *gitCmd) CombinedOutput() ([]byte, error) {
+	var output []byte
+	err := logCommand(c.Args, func() (err error) {
+		output, err = c.Cmd.CombinedOutput()
+		return err
+	}, c.exitCode)
+	return output, err
+}
+
+// changeRepository switches to dir, like `git -C dir`, and makes relative
+// GIT_DIR and GIT_WORK_TREE paths absolute so that every git subprocess,
+// including the finder's preview command, sees the same repository
+func changeRepository(dir string) error {
+	if dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE"} {
+		value := os.Getenv(name)
+		if value == "" || filepath.IsAbs(value) {
+			continue
+		}
+		absolute, err := filepath.Abs(value)
+		if err != nil {
+			return err
+		}
+		os.Setenv(name, absolute)
+	}
+	return nil
+}
+
+// runGit runs a git command and returns its trimmed standard output
+func runGit(args ...string) (string, error) {
+	output, err := gitCommand(args...).Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isAncestor reports whether commit is reachable from base
+func isAncestor(commit, base string) (bool, error) {
+	err := gitCommand("merge-base", "--is-ancestor", commit, base).Run()
+	if err == nil {
+		return true, nil
+	}
+	if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("git merge-base --is-ancestor %s %s failed: %w", commit, base, err)
+}
+
+// countAheadBehind counts the commits of tip that base lacks and vice versa
+func countAheadBehind(tip, base string) (AheadBehind, error) {
+	output, err := runGit("rev-list", "--left-right", "--count", tip+"..."+base)
+	if err != nil {
+		return AheadBehind{}, err
+	}
+	var counts AheadBehind
+	if _, err := fmt.Sscanf(output, "%d %d", &counts.Ahead, &counts.Behind); err != nil {
+		return AheadBehind{}, fmt.Errorf("unexpected git rev-list output %q: %w", output, err)
+	}
+	return counts, nil
+}
+
+// defaultBase returns origin's default branch (e.g. "origin/main") as recorded
+// by refs/remotes/origin/HEAD, falling back to HEAD when it is not set
+func defaultBase() string {
+	if base, err := runGit("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil && base != "" {
+		return base
+	}
+	return "HEAD"
+}
+
+// gitCommonDir returns the path of the repository's common git directory,
+// which is shared by all of its worktrees
+func gitCommonDir() (string, error) {
+	return runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+}
+
+// remoteBranchRef validates that name is an existing remote-tracking branch,
+// e.g. "origin/feature", and returns its full ref name. The name is compared
+// with the actual refs instead of being handed to git, so input such as an
+// option ("--output=...") or a revision expression ("main~3", "@{-1}") never
+// reaches a git command. Callers that take branch names from finder lines or
+// other untrusted input should pass the returned ref on to git.
+func remoteBranchRef(name string) (string, error) {
+	if name != "" && !strings.HasPrefix(name, "-") {
+		output, err := runGit("for-each-ref", "--format=%(refname)", "refs/remotes/")
+		if err != nil {
+			return "", err
+		}
+		ref := "refs/remotes/" + name
+		for _, line := range strings.Split(output, "\n") {
+			if line == ref {
+				return ref, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no such remote branch: %q", name)
+}
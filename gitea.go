@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// giteaPageSize is the number of pull requests requested per page; Gitea's
+// default maximum is 50
+const giteaPageSize = 50
+
+// giteaMaxPages bounds how far back the pull request list is searched, since
+// the API cannot filter pull requests by head branch
+const giteaMaxPages = 10
+
+// giteaProvider uses the REST API of a Gitea or Forgejo instance. The base URL
+// defaults to https://<host> and can be set with `git config grbm.giteaUrl`
+// for instances served under a path; the token is read from GITEA_TOKEN,
+// FORGEJO_TOKEN or `git config grbm.giteaToken` for codeberg.org, the host
+// named by GITEA_HOST and that of grbm.giteaUrl, or else the matching
+// configuration profile.
+type giteaProvider struct {
+	apiURL string
+	repo   RemoteRepo
+	token  string
+}
+
+func newGiteaProvider(repo RemoteRepo) *giteaProvider {
+	baseURL := "https://" + repo.Host
+	configured, ok := giteaConfiguredURL()
+	isConfigured := ok && configured.Hostname() == repo.Host
+	if isConfigured {
+		baseURL = strings.TrimSuffix(configured.String(), "/")
+		// HTTPS remotes of an instance served under a path include that path
+		if prefix := strings.Trim(configured.Path, "/"); prefix != "" {
+			repo.Path = strings.TrimPrefix(repo.Path, prefix+"/")
+		}
+	}
+	var token string
+	if isConfigured || envTokenHost(repo, "codeberg.org", "GITEA_HOST") {
+		token = firstEnv("GITEA_TOKEN", "FORGEJO_TOKEN")
+		if token == "" {
+			token, _ = runGit("config", "--get", "grbm.giteaToken")
+		}
+	}
+	if token == "" {
+		token = profileToken(repo)
+	}
+	return &giteaProvider{apiURL: baseURL + "/api/v1", repo: repo, token: token}
+}
+
+// giteaConfiguredURL returns the instance URL set with grbm.giteaUrl
+func giteaConfiguredURL() (*url.URL, bool) {
+	value, err := runGit("config", "--get", "grbm.giteaUrl")
+	if err != nil || value == "" {
+		return nil, false
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// isGiteaHost reports whether host looks like a Gitea or Forgejo instance
+func isGiteaHost(host string) bool {
+	if strings.Contains(host, "gitea") || strings.Contains(host, "forgejo") || host == "codeberg.org" {
+		return true
+	}
+	configured, ok := giteaConfiguredURL()
+	return ok && configured.Hostname() == host
+}
+
+func (p *giteaProvider) Name() string { return "gitea" }
+
+func (p *giteaProvider) BranchURL(branch string) string {
+	return strings.TrimSuffix(p.apiURL, "/api/v1") + "/" + p.repo.Path + "/src/branch/" + escapeBranchPath(branch)
+}
+
+func (p *giteaProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.token != "" {
+		headers["Authorization"] = "token " + p.token
+	}
+	return headers
+}
+
+func (p *giteaProvider) PullRequests(branch string) ([]PullRequest, error) {
+	var result []PullRequest
+	for page := 1; page <= giteaMaxPages; page++ {
+		requestURL := fmt.Sprintf("%s/repos/%s/pulls?state=all&sort=recentupdate&limit=%d&page=%d", p.apiURL, p.repo.Path, giteaPageSize, page)
+		var pulls []struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			State   string `json:"state"`
+			Merged  bool   `json:"merged"`
+			HTMLURL string `json:"html_url"`
+			Head    struct {
+				Ref  string `json:"ref"`
+				Repo *struct {
+					FullName string `json:"full_name"`
+				} `json:"repo"`
+			} `json:"head"`
+		}
+		if err := getJSON(requestURL, p.headers(), &pulls); err != nil {
+			return nil, err
+		}
+
+		for _, pull := range pulls {
+			// Skip pull requests from forks that happen to use the same branch name
+			if pull.Head.Ref != branch || (pull.Head.Repo != nil && !strings.EqualFold(pull.Head.Repo.FullName, p.repo.Path)) {
+				continue
+			}
+			state := pull.State
+			if pull.Merged {
+				state = "merged"
+			}
+			result = append(result, PullRequest{Number: pull.Number, Title: pull.Title, State: state, URL: pull.HTMLURL})
+		}
+		if len(pulls) < giteaPageSize {
+			break
+		}
+	}
+	return result, nil
+}
+
+// ProtectedBranchPatterns returns the branch protection rules of the
+// repository. Rule names are branch names or, on newer versions, glob patterns.
+func (p *giteaProvider) ProtectedBranchPatterns() ([]string, error) {
+	requestURL := fmt.Sprintf("%s/repos/%s/branch_protections", p.apiURL, p.repo.Path)
+	var protections []struct {
+		RuleName   string `json:"rule_name"`
+		BranchName string `json:"branch_name"`
+	}
+	if err := getJSON(requestURL, p.headers(), &protections); err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, protection := range protections {
+		if protection.RuleName != "" {
+			patterns = append(patterns, protection.RuleName)
+		} else if protection.BranchName != "" {
+			patterns = append(patterns, protection.BranchName)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HistoryEntry records the deletion of one remote branch in the audit history
+type HistoryEntry struct {
+	Time   time.Time `json:"time"`
+	Branch string    `json:"branch"`
+	// SHA is the tip the branch had when it was deleted, which is all it
+	// takes to push it back
+	SHA string `json:"sha,omitempty"`
+	// Trash is the branch a soft deletion moved it to
+	Trash string `json:"trash,omitempty"`
+	// Source is "cli" for deletions confirmed in a terminal, in the finder or
+	// the TUI, and "api" for those submitted to `serve`
+	Source string `json:"source"`
+	User   string `json:"user,omitempty"`
+}
+
+// historyPath returns the location of the audit history inside the git
+// directory, next to the analysis cache
+func historyPath() (string, error) {
+	gitDir, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "grbm", "history.jsonl"), nil
+}
+
+// remoteTips returns the tip SHA of every remote-tracking branch, keyed by
+// its name (e.g. "origin/feature/x")
+func remoteTips() map[string]string {
+	tips := map[string]string{}
+	output, err := runGit("for-each-ref", "refs/remotes", "--format=%(refname:lstrip=2) %(objectname)")
+	if err != nil {
+		return tips
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if name, sha, ok := strings.Cut(line, " "); ok {
+			tips[name] = sha
+		}
+	}
+	return tips
+}
+
+// recordDeletions appends the deleted branches to the audit history, looking
+// their SHAs up in tips, and counts them in the usage file. Every way of
+// deleting branches goes through it, so `stats -self` counts them all. The
+// history is best effort: failing to write it never fails a deletion.
+func recordDeletions(branches []string, tips map[string]string, source string) {
+	if len(branches) == 0 {
+		return
+	}
+	recordUsage(0, len(branches))
+	path, err := historyPath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Debug("could not write history", "error", err)
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		logger.Debug("could not write history", "error", err)
+		return
+	}
+	defer file.Close()
+
+	user, _ := runGit("config", "--get", "user.email")
+	now := time.Now().UTC().Truncate(time.Second)
+	encoder := json.NewEncoder(file)
+	for _, branch := range branches {
+		entry := HistoryEntry{Time: now, Branch: branch, SHA: tips[branch], Source: source, User: user}
+		if softDelete {
+			entry.Trash = trashedBranch(branch)
+		}
+		if err := encoder.Encode(entry); err != nil {
+			logger.Debug("could not write history", "error", err)
+			return
+		}
+	}
+}
+
+// readHistory returns the audit history, most recent deletion first. A
+// missing history is empty; unreadable lines are skipped.
+func readHistory() ([]HistoryEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checkCommand is set from -check-command
+var checkCommand string
+
+// prePushCheck is the -check-command that runs the repository's own pre-push
+// hook
+const prePushCheck = "pre-push"
+
+// checkRejected ends the output of a check command that rejected a push
+const checkRejected = "rejected by the check command"
+
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// prePushHookPath returns the repository's pre-push hook, honoring
+// core.hooksPath, or an empty string when none is installed
+func prePushHookPath() string {
+	path, err := runGit("rev-parse", "--git-path", "hooks/pre-push")
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return ""
+	}
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return absolute
+}
+
+// prePushInput returns what a pre-push hook reads on stdin for a push that
+// deletes branchNames (without the remote prefix) of remoteName, or moves
+// them to the trash with -soft-delete: one "<local ref> <local sha> <remote
+// ref> <remote sha>" line per updated ref
+func prePushInput(remoteName string, branchNames []string) string {
+	var b strings.Builder
+	for _, branchName := range branchNames {
+		sha, err := runGit("rev-parse", "--verify", "refs/remotes/"+remoteName+"/"+branchName)
+		if err != nil {
+			sha = zeroSHA
+		}
+		if softDelete {
+			fmt.Fprintf(&b, "%s %s refs/heads/%s %s\n", sha, sha, trashBranchName(branchName), zeroSHA)
+		}
+		fmt.Fprintf(&b, "(delete) %s refs/heads/%s %s\n", zeroSHA, branchName, sha)
+	}
+	return b.String()
+}
+
+// runDeletionCheck runs -check-command before a push that deletes
+// branchNames of remoteName, so that policies gating pushes also gate
+// deletions made by this tool. The command is run like a pre-push hook: in
+// the top-level directory of the work tree, with the remote's name and URL as
+// arguments and the refs being updated on stdin. A non-zero exit status
+// rejects the push, and the command's output is returned.
+func runDeletionCheck(remoteName string, branchNames []string) (string, error) {
+	url, err := runGit("remote", "get-url", "--push", remoteName)
+	if err != nil {
+		url = remoteName
+	}
+
+	var cmd *exec.Cmd
+	if checkCommand == prePushCheck {
+		hook := prePushHookPath()
+		if hook == "" {
+			return "", nil
+		}
+		cmd = exec.Command(hook, remoteName, url)
+	} else {
+		cmd = exec.Command("sh", "-c", checkCommand, "sh", remoteName, url)
+	}
+	if toplevel, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+		cmd.Dir = toplevel
+	}
+	cmd.Stdin = strings.NewReader(prePushInput(remoteName, branchNames))
+
+	var output []byte
+	exitCode := func() int {
+		if cmd.ProcessState == nil {
+			return -1
+		}
+		return cmd.ProcessState.ExitCode()
+	}
+	err = logCommand(cmd.Args, func() (err error) {
+		output, err = cmd.CombinedOutput()
+		return err
+	}, exitCode)
+	if err != nil {
+		return strings.TrimRight(string(output), "\n") + "\n" + checkRejected, fmt.Errorf("%s: %w", checkRejected, err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// PullRequest is a pull/merge request on a hosting provider
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"` // open, closed or merged
+	URL    string `json:"url"`
+}
+
+// HostingProvider looks up information about branches on a code hosting service
+type HostingProvider interface {
+	// Name returns the provider name as accepted by -hosting
+	Name() string
+	// PullRequests returns the pull requests whose source is the given branch
+	// (without the remote prefix), most recent first
+	PullRequests(branch string) ([]PullRequest, error)
+	// BranchURL returns the web page of the given branch (without the remote
+	// prefix)
+	BranchURL(branch string) string
+}
+
+// BranchProtectionProvider is implemented by providers that can look up the
+// branches the server refuses to delete
+type BranchProtectionProvider interface {
+	// ProtectedBranchPatterns returns branch names and glob patterns (without
+	// the remote prefix) of the protected branches
+	ProtectedBranchPatterns() ([]string, error)
+}
+
+// hostingHTTPClient is shared by all providers so requests time out instead of
+// hanging the preview window
+var hostingHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// RemoteRepo identifies a repository on a hosting service, parsed from a remote URL
+type RemoteRepo struct {
+	Host string // e.g. "github.com"
+	Path string // e.g. "owner/repo"
+}
+
+// parseRemoteURL extracts the host and repository path from an https, ssh or
+// scp-like ("git@host:owner/repo.git") remote URL
+func parseRemoteURL(remoteURL string) (RemoteRepo, error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		parsed, err := url.Parse(remoteURL)
+		if err != nil {
+			return RemoteRepo{}, err
+		}
+		host, path = parsed.Hostname(), parsed.Path
+	} else if at := strings.Index(remoteURL, "@"); at >= 0 && strings.Contains(remoteURL[at:], ":") {
+		hostAndPath := remoteURL[at+1:]
+		colon := strings.Index(hostAndPath, ":")
+		host, path = hostAndPath[:colon], hostAndPath[colon+1:]
+	} else {
+		return RemoteRepo{}, fmt.Errorf("unsupported remote URL: %s", remoteURL)
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return RemoteRepo{}, fmt.Errorf("unsupported remote URL: %s", remoteURL)
+	}
+	return RemoteRepo{Host: host, Path: path}, nil
+}
+
+// newHostingProvider returns the provider for remote. name is the value of
+// -hosting: "auto" detects the provider from the remote's host name.
+func newHostingProvider(name, remote string) (HostingProvider, error) {
+	remoteURL, err := runGit("remote", "get-url", remote)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := parseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "auto" {
+		switch {
+		case strings.Contains(repo.Host, "github"):
+			name = "github"
+		case strings.Contains(repo.Host, "gitlab"):
+			name = "gitlab"
+		case strings.Contains(repo.Host, "bitbucket"):
+			name = "bitbucket"
+		case isGiteaHost(repo.Host):
+			name = "gitea"
+		default:
+			return nil, fmt.Errorf("cannot detect hosting provider for %s", repo.Host)
+		}
+	}
+
+	switch name {
+	case "github":
+		return newGitHubProvider(repo), nil
+	case "gitlab":
+		return newGitLabProvider(repo), nil
+	case "bitbucket":
+		if repo.Host == "bitbucket.org" {
+			return newBitbucketCloudProvider(repo), nil
+		}
+		return newBitbucketServerProvider(repo)
+	case "gitea", "forgejo":
+		return newGiteaProvider(repo), nil
+	}
+	return nil, fmt.Errorf("unsupported hosting provider: %s", name)
+}
+
+// escapeBranchPath escapes a branch name for a URL path, keeping the slashes
+// that separate its parts
+func escapeBranchPath(branch string) string {
+	parts := strings.Split(branch, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// getJSON performs an authenticated GET request and decodes the JSON response into v
+func getJSON(requestURL string, headers map[string]string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	start := time.Now()
+	resp, err := hostingHTTPClient.Do(req)
+	if err != nil {
+		logger.Debug("http", "url", requestURL, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+	logger.Debug("http", "url", requestURL, "status", resp.StatusCode, "duration", time.Since(start).Round(time.Millisecond))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", requestURL, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// firstEnv returns the value of the first non-empty environment variable
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// envTokenHost reports whether a token from the environment may be sent to
+// repo's host: the provider's public host, or the host named by hostEnv (a
+// host name or URL). Remotes can point anywhere, and a host that merely
+// contains "github" must not receive the github.com token.
+func envTokenHost(repo RemoteRepo, publicHost, hostEnv string) bool {
+	if repo.Host == publicHost {
+		return true
+	}
+	configured := strings.TrimSpace(os.Getenv(hostEnv))
+	if strings.Contains(configured, "://") {
+		if parsed, err := url.Parse(configured); err == nil {
+			configured = parsed.Hostname()
+		}
+	} else if host, _, err := net.SplitHostPort(configured); err == nil {
+		configured = host
+	}
+	return configured != "" && strings.EqualFold(configured, repo.Host)
+}
+
+// gitHubProvider uses the GitHub REST API. The token is read from GITHUB_TOKEN
+// or GH_TOKEN for github.com and the GitHub Enterprise host named by GH_HOST,
+// or else the matching configuration profile; GitHub Enterprise hosts use
+// https://<host>/api/v3.
+type gitHubProvider struct {
+	apiURL string
+	repo   RemoteRepo
+	token  string
+}
+
+func newGitHubProvider(repo RemoteRepo) *gitHubProvider {
+	apiURL := "https://api.github.com"
+	if repo.Host != "github.com" {
+		apiURL = "https://" + repo.Host + "/api/v3"
+	}
+	var token string
+	if envTokenHost(repo, "github.com", "GH_HOST") {
+		token = firstEnv("GITHUB_TOKEN", "GH_TOKEN")
+	}
+	return &gitHubProvider{apiURL: apiURL, repo: repo, token: cmp.Or(token, profileToken(repo))}
+}
+
+func (p *gitHubProvider) Name() string { return "github" }
+
+func (p *gitHubProvider) BranchURL(branch string) string {
+	return "https://" + p.repo.Host + "/" + p.repo.Path + "/tree/" + escapeBranchPath(branch)
+}
+
+func (p *gitHubProvider) PullRequests(branch string) ([]PullRequest, error) {
+	owner, _, _ := strings.Cut(p.repo.Path, "/")
+	requestURL := fmt.Sprintf("%s/repos/%s/pulls?state=all&head=%s", p.apiURL, p.repo.Path, url.QueryEscape(owner+":"+branch))
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if p.token != "" {
+		headers["Authorization"] = "Bearer " + p.token
+	}
+
+	var pulls []struct {
+		Number   int     `json:"number"`
+		Title    string  `json:"title"`
+		State    string  `json:"state"`
+		HTMLURL  string  `json:"html_url"`
+		MergedAt *string `json:"merged_at"`
+	}
+	if err := getJSON(requestURL, headers, &pulls); err != nil {
+		return nil, err
+	}
+
+	var result []PullRequest
+	for _, pull := range pulls {
+		state := pull.State
+		if pull.MergedAt != nil {
+			state = "merged"
+		}
+		result = append(result, PullRequest{Number: pull.Number, Title: pull.Title, State: state, URL: pull.HTMLURL})
+	}
+	return result, nil
+}
+
+// gitLabProvider uses the GitLab REST API of the remote's host. The token is
+// read from GITLAB_TOKEN for gitlab.com and the self-hosted instance named by
+// GITLAB_HOST, or else the matching configuration profile.
+type gitLabProvider struct {
+	apiURL string
+	repo   RemoteRepo
+	token  string
+}
+
+func newGitLabProvider(repo RemoteRepo) *gitLabProvider {
+	var token string
+	if envTokenHost(repo, "gitlab.com", "GITLAB_HOST") {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+	return &gitLabProvider{apiURL: "https://" + repo.Host + "/api/v4", repo: repo, token: cmp.Or(token, profileToken(repo))}
+}
+
+func (p *gitLabProvider) Name() string { return "gitlab" }
+
+func (p *gitLabProvider) BranchURL(branch string) string {
+	return "https://" + p.repo.Host + "/" + p.repo.Path + "/-/tree/" + escapeBranchPath(branch)
+}
+
+func (p *gitLabProvider) PullRequests(branch string) ([]PullRequest, error) {
+	requestURL := fmt.Sprintf("%s/projects/%s/merge_requests?state=all&source_branch=%s", p.apiURL, url.PathEscape(p.repo.Path), url.QueryEscape(branch))
+	headers := map[string]string{}
+	if p.token != "" {
+		headers["PRIVATE-TOKEN"] = p.token
+	}
+
+	var mergeRequests []struct {
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		State  string `json:"state"`
+		WebURL string `json:"web_url"`
+	}
+	if err := getJSON(requestURL, headers, &mergeRequests); err != nil {
+		return nil, err
+	}
+
+	var result []PullRequest
+	for _, mr := range mergeRequests {
+		state := mr.State
+		if state == "opened" {
+			state = "open"
+		}
+		result = append(result, PullRequest{Number: mr.IID, Title: mr.Title, State: state, URL: mr.WebURL})
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// interruptTrap catches SIGINT and SIGTERM while branches are being confirmed
+// and deleted. A signal during a deletion lets the current git push finish
+// and stops before the next one; a signal at any other time, or a second
+// one, exits right away. Either way the terminal is put back into the state
+// it was in when the trap was set, since a prompt may have left it raw.
+type interruptTrap struct {
+	signals chan os.Signal
+	state   *term.State
+	onExit  func()
+
+	mu     sync.Mutex
+	busy   bool
+	caught bool
+}
+
+// trapInterrupts installs the trap. onExit is called before exiting because
+// of a signal, e.g. to summarize what was done so far.
+func trapInterrupts(onExit func()) *interruptTrap {
+	t := &interruptTrap{signals: make(chan os.Signal, 2), onExit: onExit}
+	if state, err := term.GetState(int(os.Stdin.Fd())); err == nil {
+		t.state = state
+	}
+	signal.Notify(t.signals, os.Interrupt, syscall.SIGTERM)
+	go t.wait()
+	return t
+}
+
+func (t *interruptTrap) wait() {
+	for sig := range t.signals {
+		logger.Info("caught signal", "signal", sig)
+		if t.state != nil {
+			term.Restore(int(os.Stdin.Fd()), t.state)
+		}
+		t.mu.Lock()
+		if t.busy && !t.caught {
+			t.caught = true
+			t.mu.Unlock()
+			continue
+		}
+		t.mu.Unlock()
+		t.exit()
+	}
+}
+
+// exit calls onExit and exits with the conventional status of a process
+// killed by SIGINT
+func (t *interruptTrap) exit() {
+	t.onExit()
+	os.Exit(130)
+}
+
+// SetBusy marks whether work that should be allowed to finish is in progress
+func (t *interruptTrap) SetBusy(busy bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.busy = busy
+}
+
+// Interrupted reports whether a signal was caught while busy
+func (t *interruptTrap) Interrupted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.caught
+}
+
+// Stop uninstalls the trap
+func (t *interruptTrap) Stop() {
+	signal.Stop(t.signals)
+	close(t.signals)
+}
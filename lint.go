@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// LintViolation is a message about a rule of the naming convention a branch
+// name breaks
+type LintViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// LintResult is a remote branch whose name breaks the naming convention
+type LintResult struct {
+	Branch     string          `json:"branch"`
+	Author     string          `json:"author"`
+	Email      string          `json:"email"`
+	Violations []LintViolation `json:"violations"`
+
+	detail BranchDetail
+}
+
+// lintBranches checks the names of the remote branches against the naming
+// convention. The base branch, protected branches, branches in the trash and
+// bot branches are left out, since they are not named by the people the
+// convention is for.
+func lintBranches(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string) []LintResult {
+	var results []LintResult
+	for _, branch := range remoteBranches {
+		if branch.Name == base || isProtectedBranch(branch.Name) || isTrashBranch(branch.Name) {
+			continue
+		}
+		if _, bot := botPattern(branch.Name); bot {
+			continue
+		}
+		_, name, _ := splitRemoteBranch(branch.Name)
+		violations := appConfig.naming.check(name)
+		if len(violations) == 0 {
+			continue
+		}
+		result := LintResult{Branch: branch.Name, Author: branch.Author, Email: branch.AuthorEmail, detail: branch}
+		for _, violation := range violations {
+			result.Violations = append(result.Violations, LintViolation{Rule: violation.Rule, Message: violation.localize(localizer)})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// printLint prints the branches breaking the naming convention with their
+// authors, as a list or as JSON
+func printLint(localizer *i18n.Localizer, results []LintResult, asJSON bool) error {
+	if asJSON {
+		if results == nil {
+			results = []LintResult{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	if len(results) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LintClean"})
+		fmt.Printf("%s%s%s\n", ColorGreen, msg, ColorReset)
+		return nil
+	}
+	for _, result := range results {
+		fmt.Printf("%s%s%s %s<%s> (%s)%s\n", ColorYellow, result.Branch, ColorReset, ColorDim, result.Email, result.Author, ColorReset)
+		for _, violation := range result.Violations {
+			fmt.Printf("  - %s\n", violation.Message)
+		}
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "LintSummary",
+		TemplateData: map[string]interface{}{"Count": len(results)},
+	})
+	fmt.Printf("\n%s\n", msg)
+	return nil
+}
+
+// chooseLintAction asks what to do with the branches breaking the naming
+// convention: "delete", "rename" or "" to leave them
+func chooseLintAction(localizer *i18n.Localizer) string {
+	actions := []struct{ Name, MessageID string }{
+		{"delete", "LintActionDelete"},
+		{"rename", "LintActionRename"},
+		{"", "LintActionNothing"},
+	}
+	var options []string
+	for _, action := range actions {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: action.MessageID})
+		options = append(options, msg)
+	}
+	message, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LintActionPrompt"})
+
+	var index int
+	if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &index, terminalAskOpts()...); err != nil {
+		return ""
+	}
+	return actions[index].Name
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// InventoryBranch is a remote branch as exported by `list`
+type InventoryBranch struct {
+	Name        string    `json:"name"`
+	Remote      string    `json:"remote"`
+	Branch      string    `json:"branch"`
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author"`
+	Email       string    `json:"email"`
+	Date        time.Time `json:"date"`
+	Merged      bool      `json:"merged"`
+	Protected   bool      `json:"protected"`
+	StaleDays   int       `json:"staleDays"`
+	PullRequest int       `json:"pullRequest,omitempty"`
+	PullState   string    `json:"pullState,omitempty"`
+	// PullFetched is when the pull requests were looked up, which is earlier
+	// than now when the lookup was cached
+	PullFetched time.Time `json:"pullRequestFetched,omitzero"`
+	// ForcePushed is when the branch was recently found rewritten
+	ForcePushed time.Time `json:"forcePushed,omitzero"`
+	// SnoozedUntil is when the snooze of the branch expires
+	SnoozedUntil time.Time `json:"snoozedUntil,omitzero"`
+}
+
+// inventoryCSVHeader names the columns of `list -csv`, which are meant for
+// spreadsheets and therefore not localized. delete -from-file reads them back.
+var inventoryCSVHeader = []string{"name", "remote", "branch", "sha", "author", "email", "last_commit", "merged", "protected", "stale_days", "pr_number", "pr_state", "force_pushed", "snoozed_until"}
+
+// buildInventory describes every remote branch, with the state of its latest
+// pull request when -hosting is on
+func buildInventory(remoteBranches []BranchDetail, base, hosting string) []InventoryBranch {
+	analyses := make(map[string]BranchAnalysis, len(remoteBranches))
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		analyses[branch.Name] = analysis
+	})
+	names := make([]string, len(remoteBranches))
+	for i, branch := range remoteBranches {
+		names[i] = branch.Name
+	}
+	pulls := lookupPullRequests(names, hosting)
+
+	inventory := make([]InventoryBranch, 0, len(remoteBranches))
+	for _, branch := range remoteBranches {
+		remoteName, branchName, _ := splitRemoteBranch(branch.Name)
+		entry := InventoryBranch{
+			Name:        branch.Name,
+			Remote:      remoteName,
+			Branch:      branchName,
+			SHA:         branch.Hash,
+			Author:      branch.Author,
+			Email:       branch.AuthorEmail,
+			Date:        branch.ActivityDate(),
+			Merged:      analyses[branch.Name].Merged,
+			Protected:   isProtectedBranch(branch.Name),
+			ForcePushed: analyses[branch.Name].ForcePushed,
+		}
+		if !entry.Date.IsZero() {
+			entry.StaleDays = int(time.Since(entry.Date).Hours() / 24)
+		}
+		if until, snoozed := snoozedUntil(branch); snoozed {
+			entry.SnoozedUntil = until
+		}
+		if prs := pulls[branch.Name].PullRequests; len(prs) > 0 {
+			entry.PullRequest, entry.PullState = prs[0].Number, prs[0].State
+		}
+		if fetched := pulls[branch.Name].Fetched; !fetched.IsZero() {
+			entry.PullFetched = fetched
+		}
+		inventory = append(inventory, entry)
+	}
+	return inventory
+}
+
+// writeInventoryCSV writes the inventory as CSV with a header row
+func writeInventoryCSV(w io.Writer, inventory []InventoryBranch) error {
+	writer := csv.NewWriter(w)
+	writer.Write(inventoryCSVHeader)
+	for _, branch := range inventory {
+		var date, pullRequest, forcePushed, snoozedUntil string
+		if !branch.Date.IsZero() {
+			date = branch.Date.UTC().Format(time.RFC3339)
+		}
+		if !branch.ForcePushed.IsZero() {
+			forcePushed = branch.ForcePushed.UTC().Format(time.RFC3339)
+		}
+		if !branch.SnoozedUntil.IsZero() {
+			snoozedUntil = branch.SnoozedUntil.Format(time.DateOnly)
+		}
+		if branch.PullRequest != 0 {
+			pullRequest = strconv.Itoa(branch.PullRequest)
+		}
+		writer.Write([]string{
+			branch.Name, branch.Remote, branch.Branch, branch.SHA, branch.Author, branch.Email, date,
+			strconv.FormatBool(branch.Merged), strconv.FormatBool(branch.Protected), strconv.Itoa(branch.StaleDays),
+			pullRequest, branch.PullState, forcePushed, snoozedUntil,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// printList prints the inventory of the remote branches as a table, or as
+// CSV or JSON written to path (stdout when path is empty or "-")
+func printList(localizer *i18n.Localizer, remoteBranches []BranchDetail, base, hosting, path string, asCSV, asJSON bool) error {
+	inventory := buildInventory(remoteBranches, base, hosting)
+	if !asCSV && !asJSON {
+		header := func(messageID string) string {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID})
+			return msg
+		}
+		fmt.Printf("%s %s %s %s %s\n", padRight(header("Branch"), 40), padRight(header("ReportAuthor"), 24), padRight(header("LastCommit"), dateColumnWidth), padRight(header("ReportMerged"), 8), header("ListPullRequest"))
+		fmt.Println(strings.Repeat("-", 100))
+		for _, branch := range inventory {
+			merged, pullRequest := "", ""
+			if branch.Merged {
+				merged = statusIndicator(localizer, "MergedIndicator")
+			}
+			if branch.PullRequest != 0 {
+				pullRequest = fmt.Sprintf("#%d %s", branch.PullRequest, branch.PullState)
+			}
+			if snoozed := snoozeAnnotation(localizer, branch.SnoozedUntil); snoozed != "" {
+				pullRequest = strings.TrimSpace(pullRequest + " " + ColorDim + snoozed + ColorReset)
+			}
+			if forcePushed := forcePushedAnnotation(localizer, branch.ForcePushed); forcePushed != "" {
+				pullRequest = strings.TrimSpace(pullRequest + " " + ColorYellow + forcePushed + ColorReset)
+			}
+			fmt.Printf("%s %s %s %s %s\n", padRight(branch.Name, 40), padRight(branch.Author, 24), padRight(formatDate(localizer, branch.Date), dateColumnWidth), padRight(merged, 8), pullRequest)
+		}
+		if cached := pullRequestsCachedNote(localizer, inventory); cached != "" {
+			fmt.Printf("\n%s%s%s\n", ColorDim, cached, ColorReset)
+		}
+		return nil
+	}
+
+	toFile := path != "" && path != "-"
+	out := io.Writer(os.Stdout)
+	if toFile {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	var err error
+	if asCSV {
+		err = writeInventoryCSV(out, inventory)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(inventory)
+	}
+	if err != nil || !toFile {
+		return err
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ListWritten",
+		TemplateData: map[string]interface{}{"Count": len(inventory), "Path": path},
+	})
+	fmt.Println(msg)
+	return nil
+}
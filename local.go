@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// localBranch is a local branch along with the remote-tracking branch it tracks
+type localBranch struct {
+	Name     string // e.g. "feature/x"
+	Hash     string
+	Upstream string // e.g. "origin/feature/x", empty if none is set
+}
+
+var (
+	localBranchesOnce sync.Once
+	localBranches     []localBranch
+)
+
+// loadLocalBranches lists the repository's local branches
+func loadLocalBranches() []localBranch {
+	localBranchesOnce.Do(func() {
+		output, err := runGit("for-each-ref", "refs/heads", "--format=%(refname:short)%1f%(objectname)%1f%(upstream:short)")
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Split(line, "\x1f")
+			if len(fields) < 3 {
+				continue
+			}
+			localBranches = append(localBranches, localBranch{Name: fields[0], Hash: fields[1], Upstream: fields[2]})
+		}
+	})
+	return localBranches
+}
+
+// isLocalCounterpart reports whether local is the local counterpart of
+// remoteBranch: a branch tracking it or, when no upstream is set, a branch of
+// the same name
+func isLocalCounterpart(local, upstream, remoteBranch string) bool {
+	_, branchName, ok := splitRemoteBranch(remoteBranch)
+	return ok && (upstream == remoteBranch || (upstream == "" && local == branchName))
+}
+
+// localCounterpart returns the local counterpart of remoteBranch, preferring
+// a branch tracking it over an untracked branch of the same name
+func localCounterpart(remoteBranch string) (localBranch, bool) {
+	var sameName *localBranch
+	for i, local := range loadLocalBranches() {
+		if local.Upstream == remoteBranch {
+			return local, true
+		}
+		if sameName == nil && isLocalCounterpart(local.Name, local.Upstream, remoteBranch) {
+			sameName = &loadLocalBranches()[i]
+		}
+	}
+	if sameName == nil {
+		return localBranch{}, false
+	}
+	return *sameName, true
+}
+
+// skippedLocalBranch is a local counterpart that will be kept when its remote
+// branch is deleted, with the message explaining why
+type skippedLocalBranch struct {
+	Local     string
+	Remote    string
+	MessageID string
+	Worktree  string
+}
+
+// planLocalDeletions finds the local counterparts of the remote branches that
+// can be deleted along with them, returned by remote branch. Branches checked
+// out in a worktree and branches with commits their remote branch lacks are
+// kept and returned as skipped.
+func planLocalDeletions(remoteBranches []string) (map[string]string, []skippedLocalBranch) {
+	locals := map[string]string{}
+	var skipped []skippedLocalBranch
+	for _, remoteBranch := range remoteBranches {
+		local, ok := localCounterpart(remoteBranch)
+		if !ok {
+			continue
+		}
+		if worktree, ok := worktreeForLocalBranch(local.Name); ok {
+			skipped = append(skipped, skippedLocalBranch{Local: local.Name, Remote: remoteBranch, MessageID: "LocalBranchCheckedOut", Worktree: worktree})
+		} else if pushed, err := isAncestor(local.Hash, remoteBranch); err != nil || !pushed {
+			skipped = append(skipped, skippedLocalBranch{Local: local.Name, Remote: remoteBranch, MessageID: "LocalBranchUnpushed"})
+		} else {
+			locals[remoteBranch] = local.Name
+		}
+	}
+	return locals, skipped
+}
+
+// deleteLocalBranch force-deletes a local branch. planLocalDeletions has
+// already checked that its commits are on the remote branch, which git
+// cannot verify once that branch is gone.
+func deleteLocalBranch(name string) (string, error) {
+	output, err := gitCommand("branch", "-D", name).CombinedOutput()
+	return string(output), err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// localeInfo is a language messages were loaded for and where they came from
+type localeInfo struct {
+	Tag    string
+	Source string // "built-in" or the path of a user locale file
+}
+
+// userLocaleDir is where additional or overriding translations can be put,
+// one <language>.json file per language in the format of locales/en.json
+func userLocaleDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "grbm", "locales"), nil
+}
+
+// loadLocales loads the embedded locales followed by those in the user locale
+// directory, whose messages take precedence, and returns the languages
+// available sorted by tag. Unreadable user files are skipped with a warning
+// in the debug log, since the embedded locales still work.
+func loadLocales(bundle *i18n.Bundle) []localeInfo {
+	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+	sources := map[string]string{}
+
+	embedded, _ := fs.Glob(localeFS, "locales/*.json")
+	for _, path := range embedded {
+		if file, err := bundle.LoadMessageFileFS(localeFS, path); err == nil {
+			sources[file.Tag.String()] = "built-in"
+		}
+	}
+
+	if dir, err := userLocaleDir(); err == nil {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, path := range paths {
+			file, err := bundle.LoadMessageFile(path)
+			if err != nil {
+				logger.Debug("locale", "path", path, "error", err)
+				continue
+			}
+			sources[file.Tag.String()] = path
+		}
+	}
+
+	locales := make([]localeInfo, 0, len(sources))
+	for tag, source := range sources {
+		locales = append(locales, localeInfo{Tag: tag, Source: source})
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i].Tag < locales[j].Tag })
+	return locales
+}
+
+// matchLocale returns the available language matching lang, which may be a
+// tag ("ja") or a POSIX locale ("ja_JP.UTF-8")
+func matchLocale(locales []localeInfo, lang string) (string, bool) {
+	lang = strings.ReplaceAll(strings.SplitN(lang, ".", 2)[0], "_", "-")
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "", false
+	}
+	base, _ := tag.Base()
+	for _, candidate := range []string{tag.String(), base.String()} {
+		for _, locale := range locales {
+			if strings.EqualFold(locale.Tag, candidate) {
+				return locale.Tag, true
+			}
+		}
+	}
+	return "", false
+}
+
+// localeTags returns the tags of the available languages
+func localeTags(locales []localeInfo) []string {
+	tags := make([]string, len(locales))
+	for i, locale := range locales {
+		tags[i] = locale.Tag
+	}
+	return tags
+}
+
+// languageName returns the name of the language with the given tag in that
+// language, e.g. "日本語" for "ja"
+func languageName(tag string) string {
+	if name := display.Self.Name(language.Make(tag)); name != "" {
+		return name
+	}
+	return tag
+}
+
+// printLanguages lists the available languages, marking the current one
+func printLanguages(localizer *i18n.Localizer, locales []localeInfo, current string) {
+	builtIn, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LanguageBuiltIn"})
+	for _, locale := range locales {
+		marker := " "
+		if locale.Tag == current {
+			marker = "*"
+		}
+		source := locale.Source
+		if source == "built-in" {
+			source = builtIn
+		}
+		fmt.Printf("%s %-8s %s %s\n", marker, locale.Tag, padRight(languageName(locale.Tag), 12), source)
+	}
+}
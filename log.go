@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// logger receives diagnostic output; it discards everything unless -verbose
+// or -debug is given
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// setupLogging directs diagnostics to stderr: -verbose logs every git command
+// with its duration and exit status, -debug additionally logs command errors
+// and hosting API requests
+func setupLogging(verbose, debug bool) {
+	level := slog.LevelInfo
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case !verbose:
+		return
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// logCommand runs a command through run and logs it along with its duration
+// and exit status
+func logCommand(args []string, run func() error, exitCode func() int) error {
+	start := time.Now()
+	err := run()
+	logger.Info("exec",
+		"cmd", strings.Join(args, " "),
+		"duration", time.Since(start).Round(time.Millisecond),
+		"exit", exitCode())
+	if err != nil {
+		logger.Debug("exec failed", "cmd", strings.Join(args, " "), "error", err)
+	}
+	return err
+}
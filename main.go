@@ -1,351 +1,1267 @@
 package main
 
 import (
-	"bytes"
 	"embed"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/AlecAivazis/survey/v2"
+	"github.com/kballard/go-shellquote"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/term"
 	"golang.org/x/text/language"
 )
 
 //go:embed locales/*.json
 var localeFS embed.FS
 
-// ANSI escape code for colors
-const (
-	ColorGreen   = "\033[32m"
-	ColorRed     = "\033[31m"
-	ColorYellow  = "\033[33m"
-	ColorReset   = "\033[0m"
+// ANSI escape code for colors; they are blanked by disableColors
+var (
+	ColorGreen  = "\033[32m"
+	ColorRed    = "\033[31m"
+	ColorYellow = "\033[33m"
+	ColorDim    = "\033[2m"
+	ColorReset  = "\033[0m"
+
+	// Colors of the status indicators, which the theme may change
+	ColorMerged    = ColorGreen
+	ColorUnmerged  = ColorRed
+	ColorProtected = ColorYellow
 )
 
-// Regex to remove ANSI color codes
-var ansiStripper = regexp.MustCompile("\033[[0-9;]*m")
+// disableColors turns every color code into an empty string
+func disableColors() {
+	ColorGreen, ColorRed, ColorYellow, ColorDim, ColorReset = "", "", "", "", ""
+	ColorMerged, ColorUnmerged, ColorProtected = "", "", ""
+}
 
-type BranchDetail struct {
-	Name    string
-	Hash    string
-	Author  string
-	Date    string
-	Message string
+// resolveColorMode turns the -color flag value into "always" or "never".
+// "auto" honors the NO_COLOR convention and otherwise enables colors only
+// when stdout is a terminal.
+func resolveColorMode(mode string) (string, error) {
+	switch mode {
+	case "always", "never":
+		return mode, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return "never", nil
+		}
+		return "always", nil
+	}
+	return "", fmt.Errorf("invalid color mode: %s", mode)
 }
 
+// Regex to remove ANSI color codes
+var ansiStripper = regexp.MustCompile("\033[[0-9;]*m")
+
 // cleanBranchName removes color codes and merge indicators from a branch name
 func cleanBranchName(branchName string) string {
 	// First, remove ANSI color codes
-	cleaned := ansiStripper.ReplaceAllString(branchName, "")
-	// Then, remove the merge indicator (e.g., " (merged)" or " (unmerged)")
-	parts := strings.SplitN(cleaned, " (", 2)
-	return strings.TrimSpace(parts[0])
+	cleaned := strings.TrimSpace(ansiStripper.ReplaceAllString(branchName, ""))
+	// Then, remove everything after the name: the merge indicator (e.g.
+	// " (merged)"), distance, age and commit subject. Branch names cannot
+	// contain spaces.
+	name, _, _ := strings.Cut(cleaned, " ")
+	return name
 }
 
-func getRemoteBranchDetail(branchName string) (BranchDetail, error) {
-	cleanName := cleanBranchName(branchName)
-	cmd := exec.Command("git", "log", "-1", "--pretty=format:%H%n%an%n%ad%n%s", cleanName)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return BranchDetail{}, fmt.Errorf("git log failed: %w\n%s", err, string(output))
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) < 4 {
-		return BranchDetail{}, fmt.Errorf("unexpected git log output: %s", string(output))
+// parseFlags parses command-line flags that may be interspersed with
+// positional arguments (e.g. "report -json") and returns the positional ones.
+// Invalid flags exit with the usage error status.
+func parseFlags(args []string) []string {
+	flag.CommandLine.Init(flag.CommandLine.Name(), flag.ContinueOnError)
+	var positional []string
+	for {
+		if err := flag.CommandLine.Parse(args); err != nil {
+			os.Exit(usageExitCode())
+		}
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
+}
 
-	return BranchDetail{
-		Name:    cleanName,
-		Hash:    lines[0],
-		Author:  lines[1],
-		Date:    lines[2],
-		Message: lines[3],
-	}, nil
+// helpCommands lists the commands shown by -h along with their localized descriptions
+var helpCommands = []struct {
+	Command   string
+	MessageID string
+}{
+	{"delete", "HelpDeleteCommand"},
+	{"report", "HelpReportCommand"},
+	{"list [-csv | -json] [-o file]", "HelpListCommand"},
+	{"stats [-self]", "HelpStatsCommand"},
+	{"lint", "HelpLintCommand"},
+	{"duplicates", "HelpDuplicatesCommand"},
+	{"clusters", "HelpClustersCommand"},
+	{"diff-snapshot [date | age]", "HelpDiffSnapshotCommand"},
+	{"rename [branch [new-name]]", "HelpRenameCommand"},
+	{"sync", "HelpSyncCommand"},
+	{"snooze [branch] -until date", "HelpSnoozeCommand"},
+	{"open [branch]", "HelpOpenCommand"},
+	{"checkout [branch]", "HelpCheckoutCommand"},
+	{"create [name] [-from ref]", "HelpCreateCommand"},
+	{"copy", "HelpCopyCommand"},
+	{"explain [branch...]", "HelpExplainCommand"},
+	{"compare [branch [branch]]", "HelpCompareCommand"},
+	{"empty-trash [-older-than age]", "HelpEmptyTrashCommand"},
+	{"plan [-o file]", "HelpPlanCommand"},
+	{"review [-o file]", "HelpReviewCommand"},
+	{"apply <file>", "HelpApplyCommand"},
+	{"enforce [-o file | -execute]", "HelpEnforceCommand"},
+	{"serve [-listen address]", "HelpServeCommand"},
+	{"api", "HelpAPICommand"},
+	{"config list|get|set|unset", "HelpConfigCommand"},
+	{"cache status|clear", "HelpCacheCommand"},
+	{"languages", "HelpLanguagesCommand"},
 }
 
-func isMergedToHead(branch string) bool {
-	cmd := exec.Command("git", "branch", "-r", "--merged", "HEAD")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Log error but continue, as this is not critical
-		fmt.Fprintf(os.Stderr, "Warning: Could not get merged branches: %v\n", err)
-		return false
-	}
-	mergedBranches := strings.Split(string(output), "\n")
-	for _, mergedBranch := range mergedBranches {
-		if strings.TrimSpace(mergedBranch) == strings.TrimSpace(branch) {
-			return true
-		}
-	}
-	return false
+// helpOptions lists the options shown by -h along with their localized descriptions
+var helpOptions = []struct {
+	Flag      string
+	MessageID string
+}{
+	{"-h, --help", "HelpFlag"},
+	{"-lang string", "HelpLangFlag"},
+	{"-C path", "HelpDirFlag"},
+	{"-c name=value", "HelpGitConfigFlag"},
+	{"-ui string", "HelpUIFlag"},
+	{"-finder string", "HelpFinderFlag"},
+	{"-fzf-opts string", "HelpFzfOptsFlag"},
+	{"-base string", "HelpBaseFlag"},
+	{"-hosting string", "HelpHostingFlag"},
+	{"-pr-cache-ttl string", "HelpPRCacheTTLFlag"},
+	{"-refresh-prs", "HelpRefreshPRsFlag"},
+	{"-preview string", "HelpPreviewFlag"},
+	{"-preview-detail", "HelpPreviewDetailFlag"},
+	{"-tickets string", "HelpTicketsFlag"},
+	{"-ticket-pattern string", "HelpTicketPatternFlag"},
+	{"-jira-url string", "HelpJiraURLFlag"},
+	{"-confirm-threshold int", "HelpConfirmThresholdFlag"},
+	{"-max-deletions int", "HelpMaxDeletionsFlag"},
+	{"-no-limit", "HelpNoLimitFlag"},
+	{"-retries int", "HelpRetriesFlag"},
+	{"-batch-size int", "HelpBatchSizeFlag"},
+	{"-delete-local", "HelpDeleteLocalFlag"},
+	{"-soft-delete", "HelpSoftDeleteFlag"},
+	{"-own-only", "HelpOwnOnlyFlag"},
+	{"-others", "HelpOthersFlag"},
+	{"-mirrors string", "HelpMirrorsFlag"},
+	{"-check-command string", "HelpCheckCommandFlag"},
+	{"-older-than string", "HelpOlderThanFlag"},
+	{"-large-object size", "HelpLargeObjectFlag"},
+	{"-allow-namespace string", "HelpAllowNamespaceFlag"},
+	{"-report-email string", "HelpReportEmailFlag"},
+	{"-smtp-host string", "HelpSMTPHostFlag"},
+	{"-smtp-user string", "HelpSMTPUserFlag"},
+	{"-smtp-from string", "HelpSMTPFromFlag"},
+	{"-drafts dir", "HelpDraftsFlag"},
+	{"-aging-after string", "HelpAgingAfterFlag"},
+	{"-stale-after string", "HelpStaleAfterFlag"},
+	{"-date-field string", "HelpDateFieldFlag"},
+	{"-date-format string", "HelpDateFormatFlag"},
+	{"-theme string", "HelpThemeFlag"},
+	{"-plain", "HelpPlainFlag"},
+	{"-include-protected", "HelpIncludeProtectedFlag"},
+	{"-filter string", "HelpFilterFlag"},
+	{"-team owner", "HelpTeamFlag"},
+	{"-bots-only", "HelpBotsOnlyFlag"},
+	{"-subjects", "HelpSubjectsFlag"},
+	{"-stdin", "HelpStdinFlag"},
+	{"-from-file path", "HelpFromFileFlag"},
+	{"-json", "HelpJSONFlag"},
+	{"-csv", "HelpCSVFlag"},
+	{"-self", "HelpSelfFlag"},
+	{"-o string", "HelpOutputFlag"},
+	{"-execute", "HelpExecuteFlag"},
+	{"-from ref", "HelpFromFlag"},
+	{"-plan-summary", "HelpPlanSummaryFlag"},
+	{"-listen address", "HelpListenFlag"},
+	{"-scope string", "HelpScopeFlag"},
+	{"-color string", "HelpColorFlag"},
+	{"-output string", "HelpOutputFormatFlag"},
+	{"-verbose", "HelpVerboseFlag"},
+	{"-git-path path", "HelpGitPathFlag"},
+	{"-debug", "HelpDebugFlag"},
 }
 
-// isProtectedBranch checks if a given branch name is a protected branch (e.g., main, master)
-func isProtectedBranch(branchName string) bool {
-	protectedBranches := []string{"main", "master"}
+func printHelp(localizer *i18n.Localizer) {
+	usage, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "HelpUsage",
+		TemplateData: map[string]interface{}{"Program": programName()},
+	})
+	description, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "HelpDescription"})
 
-	// Extract just the branch name without the remote prefix (e.g., "origin/main" -> "main")
-	parts := strings.SplitN(branchName, "/", 2)
-	cleanBranchName := branchName
-	if len(parts) == 2 {
-		cleanBranchName = parts[1]
+	fmt.Printf("%s\n\n%s\n\nCommands:\n", usage, description)
+	for _, command := range helpCommands {
+		help, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: command.MessageID})
+		fmt.Printf("  %-28s %s\n", command.Command, help)
 	}
 
-	for _, protected := range protectedBranches {
-		if cleanBranchName == protected {
-			return true
-		}
+	fmt.Printf("\nOptions:\n")
+	for _, option := range helpOptions {
+		help, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: option.MessageID})
+		fmt.Printf("  %-28s %s\n", option.Flag, help)
 	}
-	return false
 }
 
 func main() {
 	bundle := i18n.NewBundle(language.English)
-	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
-	bundle.LoadMessageFileFS(localeFS, "locales/en.json")
-	bundle.LoadMessageFileFS(localeFS, "locales/ja.json")
 
-	langFlag := flag.String("lang", "", "Specify the language (e.g., en, ja)")
+	langFlag := flag.String("lang", "", "Specify the language (e.g., en, ja), or list to list the available ones")
+	dirFlag := flag.String("C", "", "Run as if started in this directory instead of the current one")
+	flag.Var(configParameters{}, "c", "Set a git configuration variable (name=value) for the git commands the tool runs, like git -c")
 	helpFlag := flag.Bool("h", false, "Show help")
 	flag.BoolVar(helpFlag, "help", false, "Show help")
 
+	uiFlag := flag.String("ui", "finder", "User interface to use: finder or tui")
+	finderFlag := flag.String("finder", "", "Finder to use: fzf, sk, peco or gum (default: auto-detect)")
+	fzfOptsFlag := flag.String("fzf-opts", "", "Extra options passed to fzf (or sk), e.g. '--height=80% --layout=reverse'")
+	baseFlag := flag.String("base", "", "Branch that merged status is computed against (default: origin's default branch)")
+	hostingFlag := flag.String("hosting", "off", "Hosting integration: off, auto, github, gitlab, bitbucket or gitea")
+	prCacheTTLFlag := flag.String("pr-cache-ttl", "5m", "How long pull request lookups are reused from the cache (e.g. 30m, 1d)")
+	refreshPRsFlag := flag.Bool("refresh-prs", false, "Look up every pull request again instead of reusing cached lookups")
+	filterFlag := flag.String("filter", "all", "Branches to pick from: all, merged, stale, mine, team, or menu to choose interactively")
+	dateFieldFlag := flag.String("date-field", "committer", "Date that measures a branch's age: committer or author")
+	dateFormatFlag := flag.String("date-format", "relative", "How dates are shown: relative, iso or locale")
+	themeFlag := flag.String("theme", "text", "Status indicators of the branch list: text or emoji")
+	includeProtectedFlag := flag.Bool("include-protected", false, "Let protected branches be picked; each is deleted only after its full name is typed at confirmation")
+	plainFlag := flag.Bool("plain", false, "Pick branches from a numbered list by typing their numbers instead of using a finder or the TUI, without colors")
+	previewFlag := flag.String("preview", "log", "Finder preview: log, diffstat or explain")
+	ticketsFlag := flag.String("tickets", "off", "Show the tickets branches refer to: off, show, or jira or github to also look up whether they are closed")
+	ticketPatternFlag := flag.String("ticket-pattern", defaultTicketPattern, "Regular expression matching ticket references in branch names and commit subjects")
+	jiraURLFlag := flag.String("jira-url", "", "Base URL of the Jira server used by -tickets jira, e.g. https://example.atlassian.net")
+	previewDetailFlag := flag.Bool("preview-detail", false, "Show the branch's commit, author, age, ahead/behind counts and merged status above the preview")
+	agingAfterFlag := flag.String("aging-after", "1mo", "Highlight branches whose last commit is older than this (e.g. 2w, 1mo)")
+	staleAfterFlag := flag.String("stale-after", "6mo", "Mark branches whose last commit is older than this as stale (e.g. 6mo, 1y)")
+	confirmThresholdFlag := flag.Int("confirm-threshold", 10, "Require typed confirmation when more than this many branches are selected")
+	maxDeletionsFlag := flag.Int("max-deletions", 100, "Refuse to delete more than this many branches in one run (0 for no limit)")
+	noLimitFlag := flag.Bool("no-limit", false, "Lift -max-deletions for this run")
+	retriesFlag := flag.Int("retries", 0, "Retry deletions that fail with a network error up to this many times, with backoff")
+	batchSizeFlag := flag.Int("batch-size", 20, "Maximum number of branches deleted by a single git push")
+	reportEmailFlag := flag.String("report-email", "", "Email the list of deleted branches to these comma-separated addresses, or to their authors with authors")
+	smtpHostFlag := flag.String("smtp-host", "", "SMTP server (host:port) used by -report-email")
+	smtpUserFlag := flag.String("smtp-user", "", "SMTP user name; the password is read from GRBM_SMTP_PASSWORD")
+	smtpFromFlag := flag.String("smtp-from", "", "Sender address of -report-email (default: -smtp-user)")
+	draftsFlag := flag.String("drafts", "", "After deleting, write a message to each author about their deleted branches into this directory, or - for stdout")
+	allowNamespaceFlag := flag.String("allow-namespace", "", "Comma-separated protected namespaces (e.g. 'release/*') whose branches may be deleted, or all")
+	deleteLocalFlag := flag.Bool("delete-local", false, "Also delete the local branches tracking (or named like) the deleted remote branches")
+	softDeleteFlag := flag.Bool("soft-delete", false, "Move branches to trash/<date>/ on their remote instead of deleting them")
+	ownOnlyFlag := flag.Bool("own-only", false, "Only delete branches whose last commit you authored (by user.email), unless -others is given")
+	othersFlag := flag.Bool("others", false, "Also delete branches authored by others despite -own-only")
+	botsOnlyFlag := flag.Bool("bots-only", false, "Only pick from branches pushed by bots, such as dependabot/* and renovate/*")
+	mirrorsFlag := flag.String("mirrors", "", "Comma-separated mirror remotes on which branches deleted on other remotes are deleted too")
+	subjectsFlag := flag.Bool("subjects", false, "Append the subject of each branch's last commit to the picker lines so the finder matches it too")
+	teamFlag := flag.String("team", "", "Your team as named in CODEOWNERS (e.g. @acme/platform), to warn before deleting branches of areas other teams own and for -filter team")
+	checkCommandFlag := flag.String("check-command", "", "Shell command run like a pre-push hook before every deletion push, or pre-push for the repository's hook; a failure skips the push")
+	largeObjectFlag := flag.String("large-object", "10M", "Warn before deleting the only branches that reference files at least this large (e.g. 512K, 10M), or 0 to never warn")
+	olderThanFlag := flag.String("older-than", "30d", "Age at which empty-trash deletes trashed branches (e.g. 30d, 2w)")
+
 	// Internal flag for fzf preview
 	getLogFlag := flag.String("get-remote-log", "", "Internal flag to get log for a remote branch")
+	finderActionFlag := flag.String("finder-action", "", "Internal flag to run a finder key binding on the branch of a finder line: open-pr, open-branch, copy-name, create-pr or merge")
 
-	flag.Parse()
+	fromFileFlag := flag.String("from-file", "", "Delete the branches approved in a CSV or JSON file with the tips they were approved at, e.g. written by list -csv")
+	gitPathFlag := flag.String("git-path", "", "Git executable to run (default: git from PATH)")
+	stdinFlag := flag.Bool("stdin", false, "Read the branches to delete from stdin instead of running the finder")
+	verboseFlag := flag.Bool("verbose", false, "Log every git command with its duration and exit status")
+	debugFlag := flag.Bool("debug", false, "Log git command errors and hosting API requests in addition to -verbose output")
+	outputFormatFlag := flag.String("output", "text", "What stdout carries: text, or jsonl for a JSON event per listed, selected, skipped, deleted or failed branch, with everything else on stderr")
+	colorFlag := flag.String("color", "auto", "When to use colors: auto, always or never")
+	jsonFlag := flag.Bool("json", false, "Print report output as JSON")
+	csvFlag := flag.Bool("csv", false, "Make list write the branch inventory as CSV")
+	selfFlag := flag.Bool("self", false, "Make stats show your own usage of the tool, recorded locally, instead of branch statistics")
+	outputFlag := flag.String("o", "", "File the plan and list commands write to (default: stdout)")
+	planSummaryFlag := flag.Bool("plan-summary", false, "Show how many branches each remote and namespace has before and after the deletion")
+	untilFlag := flag.String("until", "", "Date (e.g. 2025-09-01) or age from now (e.g. 2w) until which snooze defers a branch's cleanup")
+	executeFlag := flag.Bool("execute", false, "Make enforce delete the branches its policy selects without asking instead of writing a plan")
+	fromFlag := flag.String("from", "", "Commit the create command creates the branch at (default: pick a branch in the finder)")
+	listenFlag := flag.String("listen", defaultListenAddress, "Address the serve command listens on")
+	scopeFlag := flag.String("scope", "user", "Configuration file written by config set/unset: system, user or repo")
 
-	var selectedLang string
-	if *langFlag != "" {
-		selectedLang = *langFlag
-	} else {
-		envLang := os.Getenv("LANG")
-		if strings.Contains(envLang, "ja") {
-			selectedLang = "ja"
+	args := parseFlags(os.Args[1:])
+	if *gitPathFlag != "" {
+		gitBinary = *gitPathFlag
+	}
+	var command string
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	// Settings from the configuration files and environment apply to every
+	// flag not given on the command line; errors are reported once the
+	// localizer is ready
+	dirErr := changeRepository(*dirFlag)
+	var configErr error
+	if dirErr == nil {
+		if appConfig, configErr = loadConfig(); configErr == nil {
+			configErr = appConfig.applyToFlags()
+			if *gitPathFlag == "" && appConfig.gitBinary != "" {
+				gitBinary = appConfig.gitBinary
+			}
+		}
+	}
+
+	// The language comes from -lang, or else from LANG, falling back to
+	// English when LANG names a language without translations
+	setupLogging(*verboseFlag, *debugFlag)
+	if configErr == nil {
+		applyEnvironment(appConfig.env)
+	}
+	locales := loadLocales(bundle)
+	if configErr == nil {
+		configErr = applyMessageOverrides(bundle, locales, appConfig.messages)
+	}
+	selectedLang, ok := matchLocale(locales, os.Getenv("LANG"))
+	if !ok {
+		selectedLang = "en"
+	}
+	langKnown := true
+	if *langFlag != "" && *langFlag != "list" {
+		if lang, ok := matchLocale(locales, *langFlag); ok {
+			selectedLang = lang
 		} else {
-			selectedLang = "en" // Default to English if parsing fails
+			langKnown = false
 		}
 	}
 
+	localizer := i18n.NewLocalizer(bundle, selectedLang, "en")
 
-	// Fallback to English if the selected language is not explicitly supported
-	if selectedLang != "ja" {
-		selectedLang = "en"
+	// An unknown -lang is reported in the language of LANG
+	if !langKnown {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownLanguage",
+			TemplateData: map[string]interface{}{"Lang": *langFlag, "Available": strings.Join(localeTags(locales), ", ")},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if *langFlag == "list" || command == "languages" {
+		printLanguages(localizer, locales, selectedLang)
+		return
+	}
+
+	if dirErr != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "ChangeDirectoryFailed",
+			TemplateData: map[string]interface{}{"Path": *dirFlag, "Error": dirErr},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if configErr != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "InvalidConfig",
+			TemplateData: map[string]interface{}{"Error": configErr},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	switch *outputFormatFlag {
+	case "text":
+	case "jsonl":
+		startEventStream()
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownOutputFormat",
+			TemplateData: map[string]interface{}{"Format": *outputFormatFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	colorMode, err := resolveColorMode(*colorFlag)
+	if err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "InvalidColorMode",
+			TemplateData: map[string]interface{}{"Mode": *colorFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if err := applyTheme(*themeFlag, appConfig.theme); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "InvalidTheme",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	// -plain replaces the finder and the TUI by a numbered list and drops
+	// colors, which screen readers spell out
+	plainMode = *plainFlag
+	includeProtected = *includeProtectedFlag
+	if plainMode {
+		colorMode = "never"
+		*uiFlag = "finder"
+	}
+	if colorMode == "never" {
+		disableColors()
+	}
+
+	var trashAge time.Duration
+	for _, age := range []struct {
+		value     string
+		threshold *time.Duration
+	}{{*agingAfterFlag, &ageThresholds.Aging}, {*staleAfterFlag, &ageThresholds.Stale}, {*olderThanFlag, &trashAge}, {*prCacheTTLFlag, &pullRequestCacheTTL}} {
+		threshold, err := parseAge(age.value)
+		if err != nil {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "InvalidAge",
+				TemplateData: map[string]interface{}{"Age": age.value},
+			})
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(usageExitCode())
+		}
+		*age.threshold = threshold
+	}
+
+	switch *dateFieldFlag {
+	case "committer", "author":
+		dateField = *dateFieldFlag
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownDateField",
+			TemplateData: map[string]interface{}{"Field": *dateFieldFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
 	}
 
-	localizer := i18n.NewLocalizer(bundle, selectedLang)
+	switch *dateFormatFlag {
+	case "relative", "iso", "locale":
+		dateFormat = *dateFormatFlag
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownDateFormat",
+			TemplateData: map[string]interface{}{"Format": *dateFormatFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	switch *ticketsFlag {
+	case "off", "show", "jira", "github":
+		ticketMode = *ticketsFlag
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownTicketMode",
+			TemplateData: map[string]interface{}{"Mode": *ticketsFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if ticketPattern, err = regexp.Compile(*ticketPatternFlag); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "InvalidTicketPattern",
+			TemplateData: map[string]interface{}{"Pattern": *ticketPatternFlag, "Error": err},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if ticketMode == "jira" && *jiraURLFlag == "" {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "TicketsNeedJiraURL"})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if ticketTracker, err = newTicketTracker(ticketMode, *jiraURLFlag); err != nil {
+		// The tickets are still shown, just without their status
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "TicketTrackerUnavailable",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorYellow, msg, ColorReset)
+	}
+
+	if largeObjectThreshold, err = parseByteSize(*largeObjectFlag); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "InvalidLargeObjectSize",
+			TemplateData: map[string]interface{}{"Size": *largeObjectFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	if *reportEmailFlag != "" && *smtpHostFlag == "" {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ReportEmailNeedsHost"})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	for _, namespace := range strings.Split(*allowNamespaceFlag, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			allowedNamespaces = append(allowedNamespaces, namespace)
+		}
+	}
+	softDelete = *softDeleteFlag
+	ownBranchesOnly = *ownOnlyFlag && !*othersFlag
+	for _, mirror := range strings.Split(*mirrorsFlag, ",") {
+		if mirror = strings.TrimSpace(mirror); mirror != "" {
+			mirrorRemotes = append(mirrorRemotes, mirror)
+		}
+	}
+	showSubjects = *subjectsFlag
+	checkCommand = *checkCommandFlag
+	codeownersTeam = *teamFlag
+	freezeBase = *baseFlag
+	if *refreshPRsFlag {
+		if err := refreshPullRequestCache(); err != nil {
+			logger.Debug("could not refresh the pull request cache", "error", err)
+		}
+	}
+
+	if !isBranchFilter(*filterFlag) {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownFilter",
+			TemplateData: map[string]interface{}{"Filter": *filterFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	if *filterFlag == "team" && codeownersTeam == "" {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "FilterTeamNeedsTeam"})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	switch *previewFlag {
+	case "log", "diffstat", "explain":
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownPreview",
+			TemplateData: map[string]interface{}{"Preview": *previewFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
 
 	// Handle internal fzf preview request
 	if *getLogFlag != "" {
 		cleanName := cleanBranchName(*getLogFlag)
-		cmd := exec.Command("git", "log", "--color=always", cleanName)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
+		base := *baseFlag
+		if base == "" {
+			base = defaultBase()
+		}
+		options := previewOptions{base: base, mode: *previewFlag, hosting: *hostingFlag, colorMode: colorMode, detail: *previewDetailFlag}
+		if err := printPreview(localizer, cleanName, options); err != nil {
 			fmt.Fprintf(os.Stderr, "Error getting log for %s: %v\n", cleanName, err)
 			os.Exit(1)
 		}
 		os.Exit(0)
 	}
 
-	if *helpFlag {
-		usage, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "HelpUsage"})
-		description, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "HelpDescription"})
-		help, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "HelpFlag"})
-		langHelp, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "HelpLangFlag"})
+	// Handle internal fzf key binding
+	if *finderActionFlag != "" {
+		var line string
+		if len(args) > 0 {
+			line = args[0]
+		}
+		base := *baseFlag
+		if base == "" {
+			base = defaultBase()
+		}
+		if err := runFinderAction(*finderActionFlag, line, base, *hostingFlag); err != nil {
+			logger.Debug("finder action failed", "action", *finderActionFlag, "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
-		fmt.Printf("%s\n\n%s\n\nOptions:\n  -h, --help    %s\n  -lang string  %s\n", usage, description, help, langHelp)
+	if *helpFlag {
+		printHelp(localizer)
 		os.Exit(0)
 	}
 
-	// Check if fzf is installed
-	if _, err := exec.LookPath("fzf"); err != nil {
-		fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "FzfNotFound"}))
-		fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "InstallFzf"}))
-		os.Exit(1)
+	switch command {
+	case "", "delete", "list", "report", "stats", "lint", "duplicates", "clusters", "diff-snapshot", "rename", "sync", "checkout", "create", "copy", "explain", "compare", "empty-trash", "plan", "review", "apply", "enforce", "serve", "api", "config", "cache", "snooze", "open", "languages":
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownCommand",
+			TemplateData: map[string]interface{}{"Command": command},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+
+	// Runs are counted in the local usage file that stats -self shows
+	recordUsage(1, 0)
+	if command == "stats" && *selfFlag {
+		if err := printUsage(localizer, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing usage: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "config" {
+		if err := runConfigCommand(localizer, args[1:], *scopeFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(usageExitCode())
+		}
+		return
+	}
+
+	// Fail early with actionable messages instead of raw git errors
+	if path, err := exec.LookPath(gitBinary); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "GitNotFound",
+			TemplateData: map[string]interface{}{"Path": gitBinary},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	} else {
+		gitBinary = path
+		logger.Info("using git", "path", path)
+	}
+	workingDir, _ := os.Getwd()
+	if _, err := runGit("rev-parse", "--git-dir"); err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "NotAGitRepository",
+			TemplateData: map[string]interface{}{"Path": workingDir},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(fatalExitCode())
+	}
+	if remotes, err := runGit("remote"); err == nil {
+		if remotes == "" {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "NoRemotes",
+				TemplateData: map[string]interface{}{"Path": workingDir},
+			})
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(fatalExitCode())
+		}
+		for _, mirror := range mirrorRemotes {
+			if !slices.Contains(strings.Split(remotes, "\n"), mirror) {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "UnknownMirror",
+					TemplateData: map[string]interface{}{"Remote": mirror},
+				})
+				fmt.Fprintln(os.Stderr, msg)
+				os.Exit(usageExitCode())
+			}
+		}
+	}
+
+	if command == "sync" {
+		syncRenamedBranches(localizer)
+		return
+	}
+
+	if command == "cache" {
+		if err := runCacheCommand(localizer, args[1:], *jsonFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(usageExitCode())
+		}
+		return
 	}
 
 	// Get all remote branches
-	cmd := exec.Command("git", "branch", "-r")
-	output, err := cmd.CombinedOutput()
+	remoteBranches, err := listRemoteBranches()
 	if err != nil {
 		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
-			MessageID: "ErrorGettingRemoteBranches",
+			MessageID:    "ErrorGettingRemoteBranches",
 			TemplateData: map[string]interface{}{"Error": err},
 		})
 		fmt.Println(msg)
 		os.Exit(1)
 	}
 
-	allRemoteBranches := strings.Split(string(output), "\n")
-
-	var fzfItems []string
-	for _, branch := range allRemoteBranches {
-		trimmedBranch := strings.TrimSpace(branch)
-		if trimmedBranch != "" && !strings.HasSuffix(trimmedBranch, "/HEAD") {
-			var indicator string
-			var color string
+	for _, branch := range remoteBranches {
+		emitEvent(Event{Event: "listed", Branch: branch.Name, SHA: branch.Hash, Author: branch.Author})
+	}
 
-			if isProtectedBranch(trimmedBranch) {
-				indicator = localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "ProtectedIndicator"})
-				color = ColorYellow
-			} else if isMergedToHead(trimmedBranch) {
-				indicator = localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "MergedIndicator"})
-				color = ColorGreen
+	// Every run snapshots the inventory; diff-snapshot compares it with the
+	// snapshots of earlier runs before taking its own
+	if command == "diff-snapshot" {
+		var since time.Time
+		if len(args) > 1 {
+			if date, err := time.ParseInLocation(time.DateOnly, args[1], time.Local); err == nil {
+				since = date
+			} else if age, err := parseAge(args[1]); err == nil {
+				since = time.Now().Add(-age)
 			} else {
-				indicator = localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "UnmergedIndicator"})
-				color = ColorRed
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "InvalidSnapshotSince",
+					TemplateData: map[string]interface{}{"Since": args[1]},
+				})
+				fmt.Fprintln(os.Stderr, msg)
+				os.Exit(usageExitCode())
 			}
-			fzfItems = append(fzfItems, fmt.Sprintf("%s%s %s%s", color, trimmedBranch, indicator, ColorReset))
 		}
+		if err := printSnapshotDiff(localizer, remoteBranches, since, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		recordSnapshot(remoteBranches)
+		return
 	}
+	recordSnapshot(remoteBranches)
 
-	if len(fzfItems) == 0 {
+	if len(remoteBranches) == 0 {
 		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoRemoteBranches"})
 		fmt.Println(msg)
 		os.Exit(0)
 	}
 
-	// Prepare fzf command
-	executablePath, err := os.Executable()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting executable path: %v\n", err)
-		os.Exit(1)
+	if *hostingFlag != "off" {
+		loadHostingProtection(localizer, *hostingFlag, remoteBranches)
 	}
 
-	fzfCmd := exec.Command("fzf", "--multi", "--ansi", "--preview", fmt.Sprintf("%s -get-remote-log {}", executablePath))
-	fzfCmd.Stderr = os.Stderr // Show fzf errors
+	// Merged status is computed against -base, or origin's default branch
+	base := *baseFlag
+	if base == "" {
+		base = defaultBase()
+	}
 
-	// Pass branches to fzf stdin
-	fzfStdin, err := fzfCmd.StdinPipe()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating stdin pipe for fzf: %v\n", err)
-		os.Exit(1)
+	// previewArgs returns the executable and the flags the finder's preview
+	// and key bindings run it with
+	previewArgs := func() (string, []string) {
+		executablePath, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting executable path: %v\n", err)
+			os.Exit(1)
+		}
+		args := []string{"-lang", selectedLang, "-base", base, "-preview", *previewFlag, "-hosting", *hostingFlag, "-pr-cache-ttl", *prCacheTTLFlag, "-color", colorMode, "-date-field", dateField, "-date-format", dateFormat, "-theme", *themeFlag, "-git-path", gitBinary}
+		if *previewDetailFlag {
+			args = append(args, "-preview-detail")
+		}
+		if ticketMode != "off" {
+			args = append(args, "-tickets", ticketMode, "-ticket-pattern", *ticketPatternFlag, "-jira-url", *jiraURLFlag)
+		}
+		return executablePath, args
+	}
+
+	// finder resolves the finder chosen with -finder, exiting if it is unavailable
+	finder := func() Finder {
+		if plainMode {
+			return plainFinder{}
+		}
+		fzfOptions, err := shellquote.Split(*fzfOptsFlag)
+		if err != nil {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "InvalidFzfOpts",
+				TemplateData: map[string]interface{}{"Error": err},
+			})
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(usageExitCode())
+		}
+		finder, err := lookupFinder(*finderFlag, fzfOptions)
+		if err != nil {
+			if *finderFlag != "" {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "FinderNotFound",
+					TemplateData: map[string]interface{}{"Finder": *finderFlag},
+				})
+				fmt.Println(msg)
+			} else {
+				fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "FzfNotFound"}))
+				fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "InstallFzf"}))
+			}
+			os.Exit(1)
+		}
+		if fzf, ok := finder.(fzfFinder); ok && fzfAtLeast(fzfBindingVersion) {
+			executablePath, args := previewArgs()
+			fzf.bindings = fzfBindings(localizer, executablePath, args)
+			finder = fzf
+		}
+		return finder
 	}
-	go func() {
-		defer fzfStdin.Close()
-		for _, item := range fzfItems {
-			fmt.Fprintln(fzfStdin, item)
+
+	// preview prepares the finder's preview command, forwarding the flags it depends on
+	preview := func() string {
+		executablePath, args := previewArgs()
+		return previewCommand(executablePath, args...)
+	}
+
+	options := deleteOptions{
+		confirmThreshold: *confirmThresholdFlag,
+		maxDeletions:     *maxDeletionsFlag,
+		retries:          *retriesFlag,
+		batchSize:        *batchSizeFlag,
+		deleteLocal:      *deleteLocalFlag,
+		base:             base,
+		hosting:          *hostingFlag,
+	}
+	if *noLimitFlag {
+		options.maxDeletions = 0
+	}
+	if *planSummaryFlag {
+		for _, branch := range remoteBranches {
+			options.summaryBranches = append(options.summaryBranches, branch.Name)
+		}
+	}
+	// afterDeletion reports the deleted branches by email and drafts
+	// messages to their authors
+	options.afterDeletion = func(deleted []string) {
+		details := make(map[string]BranchDetail, len(remoteBranches))
+		for _, branch := range remoteBranches {
+			details[branch.Name] = branch
+		}
+		var branches []BranchDetail
+		for _, name := range deleted {
+			branches = append(branches, details[name])
 		}
-	}()
 
-	// Capture fzf stdout
-	var fzfStdout bytes.Buffer
-	fzfCmd.Stdout = &fzfStdout
+		if *reportEmailFlag != "" {
+			settings := emailSettings{To: *reportEmailFlag, Host: *smtpHostFlag, User: *smtpUserFlag, From: *smtpFromFlag}
+			if err := sendDeletionReport(localizer, settings, branches); err != nil {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "ReportEmailFailed",
+					TemplateData: map[string]interface{}{"Error": err},
+				})
+				fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorYellow, msg, ColorReset)
+			} else {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "ReportEmailSent",
+					TemplateData: map[string]interface{}{"To": *reportEmailFlag},
+				})
+				fmt.Println(msg)
+			}
+		}
 
-	// Run fzf
-	err = fzfCmd.Run()
-	if err != nil {
-		// fzf returns non-zero exit code if no selection or cancelled
-		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 130 {
-			// User cancelled (Ctrl+C or Esc)
-			fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"}))
-			os.Exit(0)
+		if *draftsFlag != "" {
+			if err := writeNotificationDrafts(localizer, branches, *draftsFlag); err != nil {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+					MessageID:    "DraftsFailed",
+					TemplateData: map[string]interface{}{"Error": err},
+				})
+				fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorYellow, msg, ColorReset)
+			}
 		}
-		fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
-		os.Exit(1)
 	}
 
-	selectedBranchesStr := strings.TrimSpace(fzfStdout.String())
-	if selectedBranchesStr == "" {
-		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
-		fmt.Println(msg)
-		os.Exit(0)
+	// candidates returns the branches matching -filter, asking for the preset
+	// first with -filter menu
+	candidates := func() []BranchDetail {
+		filter := *filterFlag
+		if filter == "menu" {
+			if filter, err = chooseBranchFilter(localizer); err != nil {
+				fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"}))
+				os.Exit(0)
+			}
+		}
+		branches := withoutSnoozed(localizer, withoutTrash(remoteBranches))
+		if *botsOnlyFlag {
+			branches = onlyBots(branches)
+		}
+		branches = filterBranches(branches, base, filter)
+		if len(branches) == 0 {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesMatchFilter"})
+			fmt.Println(msg)
+			os.Exit(0)
+		}
+		return branches
 	}
 
-	// Clean selected branch names and filter out protected branches
-	var branchesToDelete []string
-	var protectedBranchesSelected []string
-	for _, selectedItem := range strings.Split(selectedBranchesStr, "\n") {
-		cleanedBranch := cleanBranchName(selectedItem)
-		if isProtectedBranch(cleanedBranch) {
-			protectedBranchesSelected = append(protectedBranchesSelected, cleanedBranch)
-		} else {
-			branchesToDelete = append(branchesToDelete, cleanedBranch)
+	// pickOne returns the branch given as the first argument of the command,
+	// or lets the user pick exactly one branch in the finder
+	pickOne := func(selectOneMessageID string) string {
+		if len(args) > 1 {
+			return args[1]
 		}
+		selected := selectWithFinder(localizer, finder(), remoteBranches, base, preview())
+		if len(selected) != 1 {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: selectOneMessageID})
+			fmt.Println(msg)
+			os.Exit(1)
+		}
+		return selected[0]
 	}
 
-	// Notify user about skipped protected branches
-	for _, protectedBranch := range protectedBranchesSelected {
-		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
-			MessageID: "ProtectedBranchSkipped",
-			TemplateData: map[string]interface{}{"Branch": protectedBranch},
-		})
-		fmt.Println(msg)
+	if command == "checkout" {
+		checkoutBranch(localizer, pickOne("CheckoutSelectOne"))
+		return
 	}
 
-	if len(branchesToDelete) == 0 {
-		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
-		fmt.Println(msg)
-		os.Exit(0)
+	if command == "snooze" {
+		until, err := time.ParseInLocation(time.DateOnly, *untilFlag, time.Local)
+		if age, ageErr := parseAge(*untilFlag); ageErr == nil {
+			until, err = time.Now().Add(age), nil
+		}
+		if err != nil || !until.After(time.Now()) {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "InvalidSnoozeUntil",
+				TemplateData: map[string]interface{}{"Until": *untilFlag},
+			})
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(usageExitCode())
+		}
+		if err := snoozeBranch(localizer, pickOne("SnoozeSelectOne"), until); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Display confirmation
-	confirmMsg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ConfirmDeletion"})
-	fmt.Printf("\n%s\n", confirmMsg)
+	if command == "open" {
+		if err := openBranchPage(localizer, pickOne("OpenSelectOne"), *hostingFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	branchHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "Branch"})
-	remoteHeader, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "Remote"})
+	if command == "create" {
+		var name string
+		if len(args) > 1 {
+			name = args[1]
+		}
+		from := *fromFlag
+		if from == "" {
+			selected := selectWithFinder(localizer, finder(), withoutTrash(remoteBranches), base, preview())
+			if len(selected) != 1 {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "CreateSelectOne"})
+				fmt.Println(msg)
+				os.Exit(1)
+			}
+			from = selected[0]
+		}
+		createBranch(localizer, name, from)
+		return
+	}
 
-	fmt.Printf("%-40s %s\n", branchHeader, remoteHeader)
-	fmt.Println(strings.Repeat("-", 60))
+	if command == "copy" {
+		copyBranchNames(localizer, selectWithFinder(localizer, finder(), candidates(), base, preview()))
+		return
+	}
 
-	for _, branch := range branchesToDelete {
-		parts := strings.SplitN(branch, "/", 2)
-		if len(parts) == 2 {
-			fmt.Printf("%-40s %s\n", parts[1], parts[0])
+	if command == "explain" {
+		var selected []string
+		if len(args) > 1 {
+			selected = knownBranches(localizer, args[1:], remoteBranches)
 		} else {
-			fmt.Printf("%-40s %s\n", branch, "(unknown)")
+			selected = selectWithFinder(localizer, finder(), candidates(), base, preview())
+		}
+		for i, branch := range selected {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s\n", branch)
+			if err := printExplain(localizer, branch, base); err != nil {
+				fmt.Fprintf(os.Stderr, "Error explaining %s: %v\n", branch, err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	if command == "compare" {
+		var selected []string
+		if len(args) > 1 {
+			selected = knownBranches(localizer, args[1:], remoteBranches)
+		} else {
+			selected = selectWithFinder(localizer, finder(), remoteBranches, base, preview())
+		}
+		// A single branch named on the command line is compared with the base
+		if len(args) == 2 && len(selected) == 1 {
+			selected = []string{base, selected[0]}
+		}
+		if len(selected) != 2 {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "CompareSelectTwo"})
+			fmt.Println(msg)
+			os.Exit(1)
 		}
+		if err := printComparison(localizer, selected[0], selected[1], colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing %s and %s: %v\n", selected[0], selected[1], err)
+			os.Exit(1)
+		}
+		return
 	}
-	fmt.Println(strings.Repeat("-", 60))
 
-	// Use survey.Confirm for final confirmation
-	confirmPrompt := &survey.Confirm{
-		Message: "Proceed with deletion?",
-		Default: false,
+	if command == "empty-trash" {
+		emptyTrash(localizer, remoteBranches, *olderThanFlag, trashAge, options)
+		return
 	}
-	var confirm bool
-	survey.AskOne(confirmPrompt, &confirm)
 
-	if !confirm {
-		cancelMsg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"})
-		fmt.Println(cancelMsg)
-		os.Exit(0)
+	if command == "rename" {
+		oldName := pickOne("RenameSelectOne")
+		var newName string
+		if len(args) > 2 {
+			newName = args[2]
+		}
+		renameBranch(localizer, oldName, newName)
+		return
 	}
 
-	// Proceed with deletion
-	for _, branch := range branchesToDelete {
-		parts := strings.SplitN(branch, "/", 2)
-		if len(parts) != 2 {
-			fmt.Printf("Skipping invalid branch format: %s\n", branch)
-			continue
+	if command == "report" {
+		if err := printReport(localizer, remoteBranches, base, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing report: %v\n", err)
+			os.Exit(1)
 		}
-		remoteName := parts[0]
-		branchName := parts[1]
+		return
+	}
+
+	if command == "list" {
+		if err := printList(localizer, remoteBranches, base, *hostingFlag, *outputFlag, *csvFlag, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing branches: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-		deleteCmd := exec.Command("git", "push", remoteName, "--delete", branchName)
-		deleteOutput, err := deleteCmd.CombinedOutput()
+	if command == "stats" {
+		if err := printStats(localizer, remoteBranches, base, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing stats: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "lint" {
+		if appConfig.naming == nil {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "LintNoConvention"})
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(usageExitCode())
+		}
+		results := lintBranches(localizer, remoteBranches, base)
+		if err := printLint(localizer, results, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing lint results: %v\n", err)
+			os.Exit(1)
+		}
+		if len(results) == 0 {
+			return
+		}
+		// Offer to clean up the offending branches right away
+		if *jsonFlag || !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			os.Exit(1)
+		}
+		violators := make([]BranchDetail, len(results))
+		for i, result := range results {
+			violators[i] = result.detail
+		}
+		switch chooseLintAction(localizer) {
+		case "delete":
+			deleteBranches(localizer, selectWithFinder(localizer, finder(), violators, base, preview()), options)
+		case "rename":
+			selected := selectWithFinder(localizer, finder(), violators, base, preview())
+			if len(selected) != 1 {
+				msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "RenameSelectOne"})
+				fmt.Println(msg)
+				os.Exit(1)
+			}
+			renameBranch(localizer, selected[0], "")
+		default:
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "duplicates" {
+		groups, err := findDuplicates(remoteBranches, base)
+		if err == nil {
+			err = printDuplicates(localizer, groups, *jsonFlag)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding duplicates: %v\n", err)
+			os.Exit(1)
+		}
+		if len(groups) == 0 || *jsonFlag || !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return
+		}
+		toDelete, err := chooseDuplicatesToDelete(localizer, groups, base)
+		if err != nil {
+			fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"}))
+			os.Exit(0)
+		}
+		deleteBranches(localizer, toDelete, options)
+		return
+	}
+
+	if command == "clusters" {
+		clusters, err := findClusters(remoteBranches, base)
+		if err == nil {
+			err = printClusters(localizer, clusters, *jsonFlag)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding clusters: %v\n", err)
+			os.Exit(1)
+		}
+		if len(clusters) == 0 || *jsonFlag || !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return
+		}
+		toDelete, err := chooseClusterToDelete(localizer, clusters)
 		if err != nil {
+			fmt.Println(localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "DeletionCancelled"}))
+			os.Exit(0)
+		}
+		if len(toDelete) > 0 {
+			deleteBranches(localizer, toDelete, options)
+		}
+		return
+	}
+
+	if command == "apply" {
+		if len(args) < 2 {
 			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
-				MessageID: "ErrorDeletingBranch",
-				TemplateData: map[string]interface{}{"Branch": branch, "Error": err},
+				MessageID:    "ApplyUsage",
+				TemplateData: map[string]interface{}{"Program": programName()},
 			})
-			fmt.Println(msg)
-			fmt.Println(string(deleteOutput))
+			fmt.Fprintln(os.Stderr, msg)
+			os.Exit(usageExitCode())
+		}
+		applyPlan(localizer, args[1], readPlan, options)
+		return
+	}
+
+	if command == "enforce" {
+		enforcePolicy(localizer, remoteBranches, base, *outputFlag, *executeFlag, options)
+		return
+	}
+
+	if command == "serve" {
+		if err := serveAPI(localizer, *listenFlag, base, options); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving the API: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "api" {
+		// Only responses go to stdout, anything else printed along the way
+		// (e.g. by -report-email) goes to stderr
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		if err := runAPI(os.Stdin, out, base, options); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading API commands: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *fromFileFlag != "" && (command == "" || command == "delete") {
+		applyPlan(localizer, *fromFileFlag, readTriageFile, options)
+		return
+	}
+
+	// stdinBranches returns the existing remote branches named on stdin
+	stdinBranches := func() []string {
+		names, err := readBranchNames(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading branches from stdin: %v\n", err)
+			os.Exit(1)
+		}
+		return knownBranches(localizer, names, remoteBranches)
+	}
+
+	if command == "plan" {
+		var selected []string
+		if *stdinFlag {
+			selected = stdinBranches()
 		} else {
+			selected = selectWithFinder(localizer, finder(), candidates(), base, preview())
+		}
+		if err := writePlan(localizer, selected, remoteBranches, base, *outputFlag, *planSummaryFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing plan: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "review" {
+		var selected []string
+		if *uiFlag == "tui" {
+			if selected, err = runReviewTUI(localizer, candidates(), base); err != nil {
+				fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			selected = selectWithFinder(localizer, finder(), candidates(), base, preview())
+		}
+		if err := writePlan(localizer, selected, remoteBranches, base, *outputFlag, *planSummaryFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing plan: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *stdinFlag {
+		deleteBranches(localizer, stdinBranches(), options)
+		return
+	}
+
+	switch *uiFlag {
+	case "tui":
+		if err := runTUI(localizer, candidates(), base, options); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+			os.Exit(1)
+		}
+	case "finder", "fzf":
+		selected := selectWithFinder(localizer, finder(), candidates(), base, preview())
+		deleteBranches(localizer, selected, options)
+	default:
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "UnknownUI",
+			TemplateData: map[string]interface{}{"UI": *uiFlag},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+}
+
+// knownBranches returns the names that are existing remote branches, warning
+// about and skipping the rest
+func knownBranches(localizer *i18n.Localizer, names []string, remoteBranches []BranchDetail) []string {
+	existing := make(map[string]bool, len(remoteBranches))
+	for _, branch := range remoteBranches {
+		existing[branch.Name] = true
+	}
+	var known []string
+	for _, name := range names {
+		if !existing[name] {
 			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
-				MessageID: "BranchDeletedSuccessfully",
-				TemplateData: map[string]interface{}{"Branch": branch},
+				MessageID:    "UnknownBranchSkipped",
+				TemplateData: map[string]interface{}{"Branch": name},
 			})
 			fmt.Println(msg)
-			fmt.Println(string(deleteOutput))
+			continue
+		}
+		known = append(known, name)
+	}
+	return known
+}
+
+// branchIndicator returns the localized status indicator and color for a
+// branch, noting its protected namespace and the worktree its local
+// counterpart is checked out in
+func branchIndicator(localizer *i18n.Localizer, branch BranchDetail, analysis BranchAnalysis) (string, string) {
+	var indicator, color string
+	if isProtectedBranch(branch.Name) {
+		indicator, color = statusIndicator(localizer, "ProtectedIndicator"), ColorProtected
+		if includeProtected {
+			// Protected branches can be picked: warn about them
+			indicator, color = localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: "ProtectedOverrideIndicator"}), ColorRed
 		}
+	} else if analysis.Merged {
+		indicator, color = statusIndicator(localizer, "MergedIndicator"), ColorMerged
+	} else {
+		indicator, color = statusIndicator(localizer, "UnmergedIndicator"), ColorUnmerged
+	}
+	if namespace, ok := protectedNamespace(branch.Name); ok && !isProtectedBranch(branch.Name) {
+		indicator += " " + localizer.MustLocalize(&i18n.LocalizeConfig{
+			MessageID:    "NamespaceIndicator",
+			TemplateData: map[string]interface{}{"Namespace": namespace},
+		})
+	}
+	if pattern, ok := botPattern(branch.Name); ok {
+		indicator += " " + localizer.MustLocalize(&i18n.LocalizeConfig{
+			MessageID:    "BotIndicator",
+			TemplateData: map[string]interface{}{"Pattern": pattern},
+		})
+	}
+	if worktree, ok := worktreeForRemoteBranch(branch.Name); ok {
+		indicator += " " + localizer.MustLocalize(&i18n.LocalizeConfig{
+			MessageID:    "WorktreeIndicator",
+			TemplateData: map[string]interface{}{"Worktree": worktree},
+		})
 	}
+	return indicator, color
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"text/template"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// messagesConfigKey is the configuration table overriding the wording of
+// messages by their ID in locales/en.json, in every language, e.g.
+//
+//	[messages]
+//	ConfirmDeletionPrompt = "Delete {{.Count}} branches from {{.Remote}}?"
+//
+// A table of plural forms words a counted message for each form:
+//
+//	[messages.ConfirmDeletionPrompt]
+//	one = "Delete this branch from {{.Remote}}?"
+//	other = "Delete {{.Count}} branches from {{.Remote}}?"
+const messagesConfigKey = "messages"
+
+// parseMessagesConfig parses the messages table of a configuration file
+func parseMessagesConfig(value interface{}) (map[string]*i18n.Message, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", messagesConfigKey)
+	}
+	messages := make(map[string]*i18n.Message, len(table))
+	for id, setting := range table {
+		message := &i18n.Message{ID: id}
+		switch setting := setting.(type) {
+		case string:
+			// Without forms the wording is the same for any count
+			message.Zero, message.One, message.Two, message.Few, message.Many, message.Other = setting, setting, setting, setting, setting, setting
+		case map[string]interface{}:
+			for form, text := range setting {
+				text, ok := text.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s.%s: invalid value for %s: %v", messagesConfigKey, id, form, setting[form])
+				}
+				switch form {
+				case "zero":
+					message.Zero = text
+				case "one":
+					message.One = text
+				case "two":
+					message.Two = text
+				case "few":
+					message.Few = text
+				case "many":
+					message.Many = text
+				case "other":
+					message.Other = text
+				default:
+					return nil, fmt.Errorf("%s.%s: unknown plural form %q", messagesConfigKey, id, form)
+				}
+			}
+			if message.Other == "" {
+				return nil, fmt.Errorf("%s.%s: the other form is required", messagesConfigKey, id)
+			}
+			for _, form := range []*string{&message.Zero, &message.One, &message.Two, &message.Few, &message.Many} {
+				if *form == "" {
+					*form = message.Other
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s: invalid value for %s: %v", messagesConfigKey, id, setting)
+		}
+		for _, text := range []string{message.Zero, message.One, message.Two, message.Few, message.Many, message.Other} {
+			if _, err := template.New(id).Parse(text); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", messagesConfigKey, id, err)
+			}
+		}
+		messages[id] = message
+	}
+	return messages, nil
+}
+
+// applyMessageOverrides replaces the configured messages in every loaded
+// language. Messages that do not exist are an error, since a misspelled ID
+// would silently change nothing.
+func applyMessageOverrides(bundle *i18n.Bundle, locales []localeInfo, overrides map[string]*i18n.Message) error {
+	english := i18n.NewLocalizer(bundle, "en")
+	for id := range overrides {
+		_, err := english.Localize(&i18n.LocalizeConfig{MessageID: id})
+		var notFound *i18n.MessageNotFoundErr
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%s: unknown message %q", messagesConfigKey, id)
+		}
+	}
+	for _, locale := range locales {
+		tag, err := language.Parse(locale.Tag)
+		if err != nil {
+			continue
+		}
+		for _, message := range overrides {
+			if err := bundle.AddMessages(tag, message); err != nil {
+				return fmt.Errorf("%s.%s: %w", messagesConfigKey, message.ID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// mirrorRemotes are remotes that mirror the others, such as a backup on a
+// second hosting provider; branches deleted on any other remote are deleted
+// on them too. Set from -mirrors.
+var mirrorRemotes []string
+
+// mirrorHeads caches the branches of each mirror remote, listed once per run
+var mirrorHeads = struct {
+	sync.Mutex
+	heads map[string]map[string]string
+}{heads: map[string]map[string]string{}}
+
+// mirrorDeletions records the tips of the branches deleteOnMirrors deleted,
+// keyed by "mirror/branch", so that an undo restores them with the others
+var mirrorDeletions = struct {
+	sync.Mutex
+	tips map[string]string
+}{tips: map[string]string{}}
+
+// lookupMirrorHeads returns the tip of every branch of a mirror remote, keyed
+// by its name without the remote prefix
+func lookupMirrorHeads(mirror string) (map[string]string, error) {
+	mirrorHeads.Lock()
+	defer mirrorHeads.Unlock()
+	if heads, ok := mirrorHeads.heads[mirror]; ok {
+		return heads, nil
+	}
+	remote, err := remoteHeads(mirror)
+	if err != nil {
+		return nil, err
+	}
+	heads := make(map[string]string, len(remote))
+	for branch, sha := range remote {
+		heads[strings.TrimPrefix(branch, mirror+"/")] = sha
+	}
+	mirrorHeads.heads[mirror] = heads
+	return heads, nil
+}
+
+// deleteOnMirrors deletes the branches that were just deleted on remoteName
+// from every mirror, or moves them to the trash there with -soft-delete. tips
+// are the SHAs the branches had; a mirror whose branch points elsewhere keeps
+// it, and so does one that does not have it. git's output is returned to be
+// shown with that of the primary deletion; failures are reported but do not
+// fail the deletion.
+func deleteOnMirrors(remoteName string, branchNames []string, tips map[string]string) string {
+	var output strings.Builder
+	for _, mirror := range mirrorRemotes {
+		if mirror == remoteName {
+			continue
+		}
+		heads, err := lookupMirrorHeads(mirror)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not list the branches of mirror %s: %v\n", mirror, err)
+			continue
+		}
+		args := []string{"push", "--atomic"}
+		var refspecs, deleted []string
+		for _, branchName := range branchNames {
+			sha, ok := heads[branchName]
+			switch {
+			case !ok:
+				continue
+			case sha != tips[branchName]:
+				fmt.Fprintf(os.Stderr, "Warning: Kept %s/%s on mirror, since it points at %s instead of %s\n", mirror, branchName, shortSHA(sha), shortSHA(tips[branchName]))
+				continue
+			}
+			// The lease makes sure the mirror's branch did not move since it
+			// was listed
+			args = append(args, "--force-with-lease=refs/heads/"+branchName+":"+sha)
+			if softDelete {
+				refspecs = append(refspecs, sha+":refs/heads/"+trashBranchName(branchName))
+			}
+			refspecs = append(refspecs, ":refs/heads/"+branchName)
+			deleted = append(deleted, branchName)
+		}
+		if len(refspecs) == 0 {
+			continue
+		}
+		args = append(append(args, mirror), refspecs...)
+		result, err := gitCommand(args...).CombinedOutput()
+		output.Write(result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not delete %s on mirror %s: %v\n", strings.Join(deleted, ", "), mirror, err)
+			continue
+		}
+		pruneTrackingRefs(mirror, deleted)
+		mirrorHeads.Lock()
+		for _, branchName := range deleted {
+			delete(heads, branchName)
+		}
+		mirrorHeads.Unlock()
+		mirrorDeletions.Lock()
+		for _, branchName := range deleted {
+			mirrorDeletions.tips[mirror+"/"+branchName] = tips[branchName]
+		}
+		mirrorDeletions.Unlock()
+	}
+	return output.String()
+}
+
+// mirrorWarnings names, one message per mirror, the branches deleting
+// branches (full "remote/branch" names) deletes on the mirror too: those it
+// has at the tip in tips
+func mirrorWarnings(localizer *i18n.Localizer, branches []string, tips map[string]string) []string {
+	var warnings []string
+	for _, mirror := range mirrorRemotes {
+		heads, err := lookupMirrorHeads(mirror)
+		if err != nil {
+			continue
+		}
+		var mirrored []string
+		for _, branch := range branches {
+			remoteName, branchName, ok := splitRemoteBranch(branch)
+			if !ok || remoteName == mirror {
+				continue
+			}
+			if sha, ok := heads[branchName]; ok && sha == tips[branch] && !slices.Contains(mirrored, branchName) {
+				mirrored = append(mirrored, branchName)
+			}
+		}
+		if len(mirrored) == 0 {
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "MirrorDeletionWarning",
+			TemplateData: map[string]interface{}{"Remote": mirror, "Branches": strings.Join(mirrored, ", "), "Count": len(mirrored)},
+			PluralCount:  len(mirrored),
+		})
+		warnings = append(warnings, msg)
+	}
+	return warnings
+}
+
+// withMirrorDeletions adds to deleted, full "remote/branch" names, the
+// branches deleteOnMirrors deleted along with them, and returns a copy of tips
+// with their tips added
+func withMirrorDeletions(deleted []string, tips map[string]string) ([]string, map[string]string) {
+	all := slices.Clone(deleted)
+	merged := maps.Clone(tips)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	mirrorDeletions.Lock()
+	defer mirrorDeletions.Unlock()
+	for _, branch := range deleted {
+		_, branchName, ok := splitRemoteBranch(branch)
+		if !ok {
+			continue
+		}
+		for _, mirror := range mirrorRemotes {
+			mirrored := mirror + "/" + branchName
+			if sha, ok := mirrorDeletions.tips[mirrored]; ok && !slices.Contains(all, mirrored) {
+				all = append(all, mirrored)
+				merged[mirrored] = sha
+			}
+		}
+	}
+	return all, merged
+}
+
+// remoteTipsOf returns the tips of the remote-tracking refs of branches of
+// remoteName (without the remote prefix), before they are deleted
+func remoteTipsOf(remoteName string, branchNames []string) map[string]string {
+	tips := map[string]string{}
+	for _, branchName := range branchNames {
+		if sha, err := runGit("rev-parse", "--verify", "refs/remotes/"+remoteName+"/"+branchName); err == nil {
+			tips[branchName] = sha
+		}
+	}
+	return tips
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// namingConfigKey is the configuration table holding the branch naming
+// convention that new branch names must follow, e.g.
+//
+//	[naming]
+//	prefixes = ["feature/", "fix/", "release/"]
+//	max-length = 60
+//	forbidden = "#@ "
+//	require-ticket = true
+//
+// Like other keys, the convention of a scope replaces that of the scopes
+// before it.
+const namingConfigKey = "naming"
+
+// NamingRules is a branch naming convention. Names are checked without their
+// remote prefix.
+type NamingRules struct {
+	// Prefixes are the allowed prefixes; any name is allowed when empty
+	Prefixes []string
+	// MaxLength is the maximum length in characters, or 0 for no limit
+	MaxLength int
+	// Forbidden are characters names must not contain
+	Forbidden string
+	// RequireTicket requires a ticket reference matching -ticket-pattern
+	RequireTicket bool
+}
+
+// namingViolation is a rule a branch name breaks, named after its key in the
+// naming table, with a localizable message
+type namingViolation struct {
+	Rule      string
+	MessageID string
+	Data      map[string]interface{}
+}
+
+// parseNaming parses the naming table of a configuration file
+func parseNaming(value interface{}) (*NamingRules, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", namingConfigKey)
+	}
+	rules := &NamingRules{}
+	for key, setting := range table {
+		var ok bool
+		switch key {
+		case "prefixes":
+			var prefixes []interface{}
+			if prefixes, ok = setting.([]interface{}); ok {
+				for _, prefix := range prefixes {
+					rules.Prefixes = append(rules.Prefixes, fmt.Sprint(prefix))
+				}
+			}
+		case "max-length":
+			var length int64
+			if length, ok = setting.(int64); ok {
+				rules.MaxLength = int(length)
+				ok = length >= 0
+			}
+		case "forbidden":
+			rules.Forbidden, ok = setting.(string)
+		case "require-ticket":
+			rules.RequireTicket, ok = setting.(bool)
+		default:
+			return nil, fmt.Errorf("%s: unknown key %q", namingConfigKey, key)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid value for %s: %v", namingConfigKey, key, setting)
+		}
+	}
+	return rules, nil
+}
+
+// check returns the rules name (without the remote prefix) breaks
+func (r *NamingRules) check(name string) []namingViolation {
+	if r == nil {
+		return nil
+	}
+	var violations []namingViolation
+	violate := func(rule, id string, data map[string]interface{}) {
+		violations = append(violations, namingViolation{Rule: rule, MessageID: id, Data: data})
+	}
+	if len(r.Prefixes) > 0 {
+		allowed := false
+		for _, prefix := range r.Prefixes {
+			allowed = allowed || strings.HasPrefix(name, prefix)
+		}
+		if !allowed {
+			violate("prefixes", "NamingPrefix", map[string]interface{}{"Prefixes": strings.Join(r.Prefixes, ", ")})
+		}
+	}
+	if r.MaxLength > 0 && utf8.RuneCountInString(name) > r.MaxLength {
+		violate("max-length", "NamingTooLong", map[string]interface{}{"Length": utf8.RuneCountInString(name), "Max": r.MaxLength})
+	}
+	if i := strings.IndexAny(name, r.Forbidden); r.Forbidden != "" && i >= 0 {
+		char, _ := utf8.DecodeRuneInString(name[i:])
+		violate("forbidden", "NamingForbidden", map[string]interface{}{"Char": fmt.Sprintf("%q", char)})
+	}
+	if r.RequireTicket && !ticketPattern.MatchString(name) {
+		violate("require-ticket", "NamingTicket", map[string]interface{}{"Pattern": ticketPattern.String()})
+	}
+	return violations
+}
+
+// localize returns the message describing the violation
+func (v namingViolation) localize(localizer *i18n.Localizer) string {
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: v.MessageID, TemplateData: v.Data})
+	return msg
+}
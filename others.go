@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// ownBranchesOnly is set from -own-only unless -others is given: only the
+// branches whose tip you authored, by user.email, are deleted
+var ownBranchesOnly bool
+
+// isOthersBranch reports whether -own-only keeps a branch because someone
+// else authored its tip. Without a user.email, every branch is someone
+// else's.
+func isOthersBranch(branch BranchDetail, email string) bool {
+	return ownBranchesOnly && (email == "" || !strings.EqualFold(branch.AuthorEmail, email))
+}
+
+// remoteBranchAuthors returns the remote branches by name, for telling who
+// authored their tips
+func remoteBranchAuthors() (map[string]BranchDetail, error) {
+	details, err := listRemoteBranches()
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[string]BranchDetail, len(details))
+	for _, detail := range details {
+		authors[detail.Name] = detail
+	}
+	return authors, nil
+}
+
+// withoutOthersBranches leaves the branches authored by others out of the
+// branches to delete with -own-only, saying which ones and why. When the
+// authors cannot be looked up, no branch is deleted.
+func withoutOthersBranches(localizer *i18n.Localizer, branches []string) []string {
+	if !ownBranchesOnly {
+		return branches
+	}
+	email, _ := runGit("config", "--get", "user.email")
+	authors, err := remoteBranchAuthors()
+	if err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "OwnOnlyAuthorsFailed",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+		for _, branch := range branches {
+			emitEvent(Event{Event: "skipped", Branch: branch, Reason: "others"})
+		}
+		return nil
+	}
+
+	var own []string
+	for _, branch := range branches {
+		detail, ok := authors[branch]
+		if !ok || !isOthersBranch(detail, email) {
+			own = append(own, branch)
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "OthersBranchSkipped",
+			TemplateData: map[string]interface{}{"Branch": branch, "Author": detail.Author, "Email": detail.AuthorEmail},
+		})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+		emitEvent(Event{Event: "skipped", Branch: branch, Reason: "others"})
+	}
+	if email == "" && len(own) < len(branches) {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "OwnOnlyNoEmail"})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+	}
+	return own
+}
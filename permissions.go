@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// unableToDeletePattern matches the error git reports without contacting the
+// remote, e.g. for a branch deleted since the last fetch
+var unableToDeletePattern = regexp.MustCompile(`unable to delete '([^']+)': (.+)`)
+
+// checkDeletePermissions probes every remote of the branches with a dry-run
+// push of the deletion, which authenticates like the real push, and returns
+// the branches the push would fail for with the reason. Server-side hooks do
+// not run on a dry run, so branch protection is only caught by -hosting.
+func checkDeletePermissions(branches []string) map[string]string {
+	var remotes []string
+	byRemote := map[string][]string{}
+	for _, branch := range branches {
+		remoteName, branchName, ok := splitRemoteBranch(branch)
+		if !ok {
+			continue
+		}
+		if _, seen := byRemote[remoteName]; !seen {
+			remotes = append(remotes, remoteName)
+		}
+		byRemote[remoteName] = append(byRemote[remoteName], branchName)
+	}
+
+	failures := map[string]string{}
+	for _, remoteName := range remotes {
+		for branchName, reason := range probeDeletion(remoteName, byRemote[remoteName]) {
+			failures[remoteName+"/"+branchName] = reason
+		}
+	}
+	return failures
+}
+
+// probeDeletion runs the push that would delete (or with -soft-delete, trash)
+// the branches of a remote with --dry-run and returns the branches it rejects.
+// When the push fails as a whole, e.g. for lack of credentials, every branch
+// is returned with git's first error.
+func probeDeletion(remoteName string, branchNames []string) map[string]string {
+	args := append([]string{"push", remoteName, "--delete"}, branchNames...)
+	if softDelete {
+		tips := remoteTipsOf(remoteName, branchNames)
+		if len(tips) < len(branchNames) {
+			logger.Debug("could not probe deletion", "remote", remoteName, "error", "missing remote-tracking refs")
+			return nil
+		}
+		args = softDeleteArgs(remoteName, branchNames, tips)
+	}
+	// A dry run still runs the pre-push hook, which is for real pushes
+	args = append([]string{"push", "--dry-run", "--porcelain", "--no-verify"}, args[1:]...)
+
+	// Both the branch and its trash copy map back to the branch
+	refs := map[string]string{}
+	for _, branchName := range branchNames {
+		refs["refs/heads/"+branchName] = branchName
+		refs["refs/heads/"+trashBranchName(branchName)] = branchName
+	}
+
+	var stderr bytes.Buffer
+	cmd := gitCommand(args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+
+	failures := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		// <flag> TAB <from>:<to> TAB <summary> (<reason>)
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || fields[0] != "!" {
+			continue
+		}
+		_, to, _ := strings.Cut(fields[1], ":")
+		if branchName, ok := refs[to]; ok {
+			failures[branchName] = fields[2]
+		}
+	}
+	var firstError string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if match := unableToDeletePattern.FindStringSubmatch(line); match != nil {
+			failures[strings.TrimPrefix(match[1], "refs/heads/")] = match[2]
+		} else if firstError == "" && (strings.HasPrefix(line, "fatal: ") || strings.HasPrefix(line, "error: ")) {
+			firstError = strings.TrimSpace(line[strings.Index(line, ":")+1:])
+		}
+	}
+	if err != nil && len(failures) == 0 {
+		if firstError == "" {
+			firstError = err.Error()
+		}
+		for _, branchName := range branchNames {
+			failures[branchName] = firstError
+		}
+	}
+	return failures
+}
+
+// permissionMark marks a branch of the confirmation the deletion would fail
+// for, or returns "" when it should succeed
+func permissionMark(localizer *i18n.Localizer, failures map[string]string, branch string) string {
+	reason, ok := failures[branch]
+	if !ok {
+		return ""
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "PermissionMark",
+		TemplateData: map[string]interface{}{"Reason": reason},
+	})
+	return msg
+}
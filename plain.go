@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// plainMode is set by -plain: branches are picked from a numbered list by
+// typing their numbers, and prompts are plain lines, so screen readers and
+// dumb terminals can follow along
+var plainMode bool
+
+// plainInput reads the answers to plain prompts. It is shared so nothing
+// buffered for one prompt is lost to the next.
+var plainInput = bufio.NewReader(os.Stdin)
+
+// plainFinder stands in for a finder with -plain; selectWithFinder shows the
+// numbered list instead of running it
+type plainFinder struct{}
+
+func (plainFinder) Name() string { return "plain" }
+
+func (plainFinder) Command(string) *exec.Cmd { return nil }
+
+func (plainFinder) SupportsANSI() bool { return false }
+
+func (plainFinder) IsCancelled(int) bool { return false }
+
+// readPlainLine prints prompt and reads the answer, reporting false at the
+// end of the input
+func readPlainLine(prompt string) (string, bool) {
+	fmt.Print(prompt + " ")
+	line, err := plainInput.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Println()
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// askPlainConfirm asks a yes/no question, defaulting to no
+func askPlainConfirm(message string) bool {
+	answer, ok := readPlainLine(message + " [y/N]")
+	if !ok {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// askPlainChoice prints the options as a numbered list and returns the index
+// of the one whose number is typed, or -1 at the end of the input
+func askPlainChoice(message string, options []string) int {
+	for i, option := range options {
+		fmt.Printf("%d. %s\n", i+1, option)
+	}
+	for {
+		answer, ok := readPlainLine(message)
+		if !ok {
+			return -1
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+	}
+}
+
+// selectPlain prints the analyzed branches as a numbered list and returns
+// the ones whose numbers the user types, e.g. "1,4-7". An empty answer
+// selects nothing.
+func selectPlain(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string) []string {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	if len(remoteBranches) == 0 {
+		return nil
+	}
+
+	fmt.Println(localize("PlainAnalyzing", map[string]interface{}{"Count": len(remoteBranches)}))
+	analyses := make(map[string]BranchAnalysis, len(remoteBranches))
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		analyses[branch.Name] = analysis
+	})
+	width := len(strconv.Itoa(len(remoteBranches)))
+	for i, branch := range remoteBranches {
+		fmt.Printf("%*d. %s\n", width, i+1, finderLine(localizer, plainFinder{}, branch, analyses[branch.Name]))
+	}
+
+	for {
+		answer, ok := readPlainLine(localize("PlainSelectPrompt", nil))
+		if !ok || answer == "" {
+			return nil
+		}
+		indexes, err := parseIndexRanges(answer, len(remoteBranches))
+		if err != nil {
+			fmt.Println(localize("PlainInvalidSelection", map[string]interface{}{"Error": err, "Count": len(remoteBranches)}))
+			continue
+		}
+		selected := make([]string, 0, len(indexes))
+		for _, index := range indexes {
+			selected = append(selected, remoteBranches[index].Name)
+		}
+		return selected
+	}
+}
+
+// parseIndexRanges parses a list of 1-based numbers and ranges such as
+// "1,4-7" (spaces may separate them too) into 0-based indexes below count,
+// in the order given and without duplicates
+func parseIndexRanges(input string, count int) ([]int, error) {
+	var indexes []int
+	seen := map[int]bool{}
+	for _, part := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("not a number: %s", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("not a range: %s", part)
+			}
+		}
+		if from < 1 || to > count || from > to {
+			return nil, fmt.Errorf("out of range: %s", part)
+		}
+		for n := from; n <= to; n++ {
+			if !seen[n-1] {
+				seen[n-1] = true
+				indexes = append(indexes, n-1)
+			}
+		}
+	}
+	return indexes, nil
+}
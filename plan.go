@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// planVersion is bumped whenever the plan file format changes incompatibly
+const planVersion = 1
+
+// Plan is a reviewable list of branch deletions written by `plan` and
+// executed later by `apply`
+type Plan struct {
+	Version  int             `json:"version"`
+	Created  time.Time       `json:"created"`
+	Base     string          `json:"base"`
+	Branches []PlannedBranch `json:"branches"`
+}
+
+// PlannedBranch is a branch to delete along with the tip it had when planned;
+// apply only deletes it if the tip is unchanged
+type PlannedBranch struct {
+	Name    string    `json:"name"`
+	SHA     string    `json:"sha"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Merged  bool      `json:"merged"`
+	Message string    `json:"message"`
+}
+
+// writePlan writes a plan to delete the selected branches to path, or to
+// stdout when path is empty or "-". Protected branches are left out. With
+// summary, the effect of the plan on each remote is shown on stderr.
+func writePlan(localizer *i18n.Localizer, selected []string, remoteBranches []BranchDetail, base, path string, summary bool) error {
+	wanted := map[string]bool{}
+	for _, name := range selected {
+		if isProtectedBranch(name) {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "ProtectedBranchSkipped",
+				TemplateData: map[string]interface{}{"Branch": name},
+			})
+			fmt.Fprintln(os.Stderr, msg)
+			continue
+		}
+		wanted[name] = true
+	}
+	if len(wanted) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "NoBranchesSelected"})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(0)
+	}
+
+	plan := Plan{Version: planVersion, Created: time.Now().UTC().Truncate(time.Second), Base: base}
+	// Analyze every branch, not just the selected ones, so the analysis cache
+	// keeps its entries for the rest
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if !wanted[branch.Name] {
+			return
+		}
+		plan.Branches = append(plan.Branches, PlannedBranch{
+			Name:    branch.Name,
+			SHA:     branch.Hash,
+			Author:  branch.Author,
+			Date:    branch.ActivityDate(),
+			Merged:  analysis.Merged,
+			Message: branch.Message,
+		})
+	})
+	sort.Slice(plan.Branches, func(i, j int) bool { return plan.Branches[i].Name < plan.Branches[j].Name })
+	if summary {
+		all := make([]string, len(remoteBranches))
+		for i, branch := range remoteBranches {
+			all[i] = branch.Name
+		}
+		planned := make([]string, len(plan.Branches))
+		for i, branch := range plan.Branches {
+			planned[i] = branch.Name
+		}
+		fmt.Fprint(os.Stderr, formatPlanSummary(localizer, summarizePlan(all, planned)))
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "PlanWritten",
+		TemplateData: map[string]interface{}{"Count": len(plan.Branches), "Path": path},
+	})
+	fmt.Println(msg)
+	return nil
+}
+
+// readPlan loads a plan file written by writePlan
+func readPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := parsePlan(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// parsePlan decodes a plan in the format written by writePlan
+func parsePlan(data []byte) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, err
+	}
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return &plan, nil
+}
+
+// remoteHeads returns the current tip of every branch on remote, keyed by the
+// full remote branch name (e.g. "origin/feature/x")
+func remoteHeads(remote string) (map[string]string, error) {
+	output, err := runGit("ls-remote", "--heads", remote)
+	if err != nil {
+		return nil, err
+	}
+	heads := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		sha, ref, ok := strings.Cut(line, "\t")
+		if ok {
+			heads[remote+"/"+strings.TrimPrefix(ref, "refs/heads/")] = sha
+		}
+	}
+	return heads, nil
+}
+
+// movedBranch is a planned branch that apply leaves alone because it is gone
+// (Current is empty) or its tip moved since the plan was written
+type movedBranch struct {
+	PlannedBranch
+	Current string
+}
+
+// checkPlan compares the planned branches with the current state of their
+// remotes, returning the ones whose tips have not moved and the others
+func checkPlan(plan *Plan) ([]string, []movedBranch, error) {
+	heads := map[string]map[string]string{}
+	var unchanged []string
+	var moved []movedBranch
+	for _, planned := range plan.Branches {
+		remoteName, _, ok := splitRemoteBranch(planned.Name)
+		if !ok {
+			continue
+		}
+		if _, ok := heads[remoteName]; !ok {
+			remote, err := remoteHeads(remoteName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", remoteName, err)
+			}
+			heads[remoteName] = remote
+		}
+		if sha := heads[remoteName][planned.Name]; sha == planned.SHA {
+			unchanged = append(unchanged, planned.Name)
+		} else {
+			moved = append(moved, movedBranch{PlannedBranch: planned, Current: sha})
+		}
+	}
+	return unchanged, moved, nil
+}
+
+// applyPlan checks the planned branches against the current state of their
+// remotes and deletes the ones whose tips have not moved since the plan was
+// written, with the usual confirmation. read loads the plan from path.
+func applyPlan(localizer *i18n.Localizer, path string, read func(string) (*Plan, error), options deleteOptions) {
+	plan, err := read(path)
+	if err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PlanReadFailed",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(1)
+	}
+
+	toDelete, skipped, err := checkPlan(plan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing branches: %v\n", err)
+		os.Exit(1)
+	}
+	for _, branch := range skipped {
+		if branch.Current == "" {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "PlanBranchGone",
+				TemplateData: map[string]interface{}{"Branch": branch.Name},
+			})
+			fmt.Println(msg)
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PlanBranchMoved",
+			TemplateData: map[string]interface{}{"Branch": branch.Name, "Planned": shortSHA(branch.SHA), "Current": shortSHA(branch.Current)},
+		})
+		fmt.Printf("%s%s%s\n", ColorYellow, msg, ColorReset)
+	}
+
+	// The branches are deleted at the commits the plan was reviewed at
+	options.tips = planTips(plan)
+	deleteBranches(localizer, toDelete, options)
+}
+
+// shortSHA abbreviates a commit hash for display
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
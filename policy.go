@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// policyConfigKey is the configuration table holding the cleanup policy
+// enforced by `enforce`, e.g.
+//
+//	[policy]
+//	merged = true
+//	older-than = "30d"
+//	except = ["release/*"]
+//
+// Several rules can be given as [[policy]] tables. Like other keys, the
+// policy of a scope replaces that of the scopes before it.
+const policyConfigKey = "policy"
+
+// PolicyRule selects the branches that satisfy all of its conditions
+type PolicyRule struct {
+	// Merged selects only branches merged into the base branch
+	Merged bool
+	// OlderThan selects only branches whose last commit is at least this old
+	OlderThan time.Duration
+	// Except are patterns of branches the rule never selects
+	Except []string
+	// olderThanText is OlderThan as configured, for display
+	olderThanText string
+}
+
+// parsePolicy parses the policy table, or array of tables, of a
+// configuration file
+func parsePolicy(value interface{}) ([]PolicyRule, error) {
+	var tables []map[string]interface{}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		tables = append(tables, value)
+	case []map[string]interface{}:
+		tables = value
+	default:
+		return nil, fmt.Errorf("%s must be a table", policyConfigKey)
+	}
+
+	var rules []PolicyRule
+	for i, table := range tables {
+		var rule PolicyRule
+		for key, setting := range table {
+			var ok bool
+			switch key {
+			case "merged":
+				rule.Merged, ok = setting.(bool)
+			case "older-than":
+				rule.olderThanText, ok = setting.(string)
+				if ok {
+					var err error
+					if rule.OlderThan, err = parseAge(rule.olderThanText); err != nil {
+						return nil, fmt.Errorf("%s rule %d: %w", policyConfigKey, i+1, err)
+					}
+				}
+			case "except":
+				var patterns []interface{}
+				if patterns, ok = setting.([]interface{}); ok {
+					for _, pattern := range patterns {
+						rule.Except = append(rule.Except, fmt.Sprint(pattern))
+					}
+				}
+			default:
+				return nil, fmt.Errorf("%s rule %d: unknown key %q", policyConfigKey, i+1, key)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s rule %d: invalid value for %s: %v", policyConfigKey, i+1, key, setting)
+			}
+		}
+		// A rule without conditions would select every branch
+		if !rule.Merged && rule.OlderThan == 0 {
+			return nil, fmt.Errorf("%s rule %d: set merged or older-than", policyConfigKey, i+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// selects reports whether the rule selects a branch
+func (r PolicyRule) selects(branch BranchDetail, analysis BranchAnalysis) bool {
+	if r.Merged && !analysis.Merged {
+		return false
+	}
+	if r.OlderThan > 0 && time.Since(branch.ActivityDate()) < r.OlderThan {
+		return false
+	}
+	for _, pattern := range r.Except {
+		if matchesBranchPattern(pattern, branch.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe summarizes the rule, e.g. "merged, older than 30d, except release/*"
+func (r PolicyRule) describe(localizer *i18n.Localizer) string {
+	var conditions []string
+	localize := func(id string, data map[string]interface{}) {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		conditions = append(conditions, msg)
+	}
+	if r.Merged {
+		localize("PolicyMerged", nil)
+	}
+	if r.OlderThan > 0 {
+		localize("PolicyOlderThan", map[string]interface{}{"Age": r.olderThanText})
+	}
+	if len(r.Except) > 0 {
+		localize("PolicyExcept", map[string]interface{}{"Patterns": strings.Join(r.Except, ", ")})
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// evaluatePolicy returns the branches selected by any rule. Protected
+// branches, the base branch, branches of protected namespaces that are not
+// allowed, branches in the trash and snoozed branches are never selected.
+func evaluatePolicy(rules []PolicyRule, remoteBranches []BranchDetail, base string) []string {
+	selected := map[string]bool{}
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if branch.Name == base || isProtectedBranch(branch.Name) || isTrashBranch(branch.Name) {
+			return
+		}
+		if _, snoozed := snoozedUntil(branch); snoozed {
+			return
+		}
+		if _, blocked := blockedNamespace(branch.Name); blocked {
+			return
+		}
+		for _, rule := range rules {
+			if rule.selects(branch, analysis) {
+				selected[branch.Name] = true
+				return
+			}
+		}
+	})
+	var names []string
+	for _, branch := range remoteBranches {
+		if selected[branch.Name] {
+			names = append(names, branch.Name)
+		}
+	}
+	return names
+}
+
+// enforcePolicy evaluates the configured policy and either writes a plan of
+// the branches it selects to path (stdout when empty) or, with execute,
+// deletes them without asking, for scheduled jobs
+func enforcePolicy(localizer *i18n.Localizer, remoteBranches []BranchDetail, base, path string, execute bool, options deleteOptions) {
+	rules := appConfig.policy
+	if len(rules) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "EnforceNoPolicy"})
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(usageExitCode())
+	}
+	// Progress goes to stderr so that the plan can be written to stdout
+	for _, rule := range rules {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "EnforceRule",
+			TemplateData: map[string]interface{}{"Rule": rule.describe(localizer)},
+		})
+		fmt.Fprintln(os.Stderr, msg)
+	}
+
+	selected := evaluatePolicy(rules, remoteBranches, base)
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "EnforceSelected",
+		TemplateData: map[string]interface{}{"Count": len(selected)},
+	})
+	fmt.Fprintln(os.Stderr, msg)
+	if len(selected) == 0 {
+		os.Exit(0)
+	}
+
+	if execute {
+		options.assumeYes = true
+		deleteBranches(localizer, selected, options)
+		return
+	}
+	if err := writePlan(localizer, selected, remoteBranches, base, path, options.summaryBranches != nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing plan: %v\n", err)
+		os.Exit(1)
+	}
+}
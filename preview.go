@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// previewCommand returns the shell command a finder runs to render the preview
+// of the highlighted line ({}), forwarding the flags the preview depends on
+func previewCommand(executablePath string, args ...string) string {
+	return shellquote.Join(append([]string{executablePath}, args...)...) + " -get-remote-log {}"
+}
+
+// previewOptions are the flags the preview depends on
+type previewOptions struct {
+	base      string
+	mode      string // "log", "diffstat" or "explain"
+	hosting   string
+	colorMode string // "always" or "never", passed on to git
+	detail    bool   // show the branch metadata header
+}
+
+// printPreview writes the preview of a remote branch to stdout: its metadata
+// with -preview-detail, its pull requests when hosting integration is
+// enabled, followed by its git log or, with the "diffstat" preview, the files
+// it changed since it forked from base or, with the "explain" preview, the
+// commits base lacks. branch comes from a finder line, so it must name an
+// existing remote branch and only its full ref is passed to git.
+func printPreview(localizer *i18n.Localizer, branch string, options previewOptions) error {
+	ref, err := remoteBranchRef(branch)
+	if err != nil {
+		return err
+	}
+
+	var cache *PreviewCache
+	if options.detail {
+		cache = loadPreviewCache()
+		printBranchDetail(localizer, branch, options.base, cache)
+	}
+	if options.hosting != "off" {
+		printPullRequests(localizer, branch, options.hosting, cache)
+	}
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			logger.Debug("preview cache", "error", err)
+		}
+	}
+
+	switch options.mode {
+	case "diffstat":
+		return printDiffstat(localizer, ref, options.base, options.colorMode)
+	case "explain":
+		return printExplain(localizer, branch, options.base)
+	}
+
+	cmd := gitCommand("log", "--color="+options.colorMode, ref, "--")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// printBranchDetail writes the tip commit, author, age, distance from base,
+// size, merged status and recent activity of branch. They are taken from the analysis cache, or else
+// from the preview cache, when possible, so scrolling through the finder does
+// not recompute them for every line.
+func printBranchDetail(localizer *i18n.Localizer, branch, base string, cache *PreviewCache) {
+	detail, err := lookupRemoteBranch(branch)
+	if err != nil {
+		fmt.Printf("%s%v%s\n\n", ColorYellow, err, ColorReset)
+		return
+	}
+	baseSHA, _ := runGit("rev-parse", base)
+
+	label := func(id string) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id})
+		return msg
+	}
+	fmt.Println(branch)
+	fmt.Printf("%s %s %s\n", label("PreviewDetailCommit"), shortSHA(detail.Hash), detail.Message)
+	fmt.Printf("%s %s <%s>, %s\n", label("PreviewDetailAuthor"), detail.Author, detail.AuthorEmail, formatDate(localizer, detail.ActivityDate()))
+
+	var mergedKnown bool
+	if baseSHA != "" {
+		// The list's analysis has usually computed everything already
+		analysis, analyzed := loadAnalysisCache().Branches[detail.Hash]
+		analyzed = analyzed && analysis.MergedBase == baseSHA
+
+		counts := analysis.AheadBehind
+		var err error
+		if !analyzed {
+			counts, err = cachedAheadBehind(cache, detail.Hash, baseSHA)
+		}
+		if err == nil {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "PreviewDetailAheadBehind",
+				TemplateData: map[string]interface{}{"Ahead": counts.Ahead, "Behind": counts.Behind, "Base": base},
+			})
+			fmt.Printf("%s %s\n", label("PreviewDetailBase"), msg)
+		}
+		if size, err := cachedBranchSize(cache, detail.Hash, baseSHA); err == nil {
+			fmt.Printf("%s %s\n", label("PreviewDetailSize"), formatBranchSize(localizer, size))
+		}
+
+		merged, ok := analysis.Merged, analyzed
+		if !analyzed {
+			if m, err := isAncestor(detail.Hash, baseSHA); err == nil {
+				merged, ok = m, true
+			}
+		}
+		mergedKnown = ok && merged
+		if ok {
+			status, color := statusIndicator(localizer, "UnmergedIndicator"), ColorUnmerged
+			if merged {
+				status, color = statusIndicator(localizer, "MergedIndicator"), ColorMerged
+			}
+			fmt.Printf("%s %s%s%s\n", label("PreviewDetailStatus"), color, status, ColorReset)
+		}
+	}
+	if tickets := ticketAnnotation(localizer, detail, mergedKnown); tickets != "" {
+		fmt.Printf("%s %s\n", label("PreviewDetailTickets"), tickets)
+	}
+	if activity, err := formatActivity(localizer, detail.Hash, baseSHA); err == nil {
+		fmt.Printf("%s %s\n", label("PreviewDetailActivity"), activity)
+	}
+	fmt.Println()
+}
+
+// cachedAheadBehind returns the number of commits tip has that baseSHA lacks
+// and vice versa, from cache if they were counted before
+func cachedAheadBehind(cache *PreviewCache, tip, baseSHA string) (AheadBehind, error) {
+	key := tip + "..." + baseSHA
+	if counts, ok := cache.AheadBehind[key]; ok {
+		return counts, nil
+	}
+	counts, err := countAheadBehind(tip, baseSHA)
+	if err != nil {
+		return AheadBehind{}, err
+	}
+	cache.AheadBehind[key] = counts
+	return counts, nil
+}
+
+// cachedBranchSize returns the size of tip relative to baseSHA, from cache if
+// it was estimated before
+func cachedBranchSize(cache *PreviewCache, tip, baseSHA string) (BranchSize, error) {
+	key := tip + "..." + baseSHA
+	if size, ok := cache.Sizes[key]; ok {
+		return size, nil
+	}
+	size, err := estimateBranchSize(tip, baseSHA)
+	if err != nil {
+		return BranchSize{}, err
+	}
+	cache.Sizes[key] = size
+	return size, nil
+}
+
+// printDiffstat writes the files changed on branch since its merge base with
+// base, i.e. the work that is unique to the branch
+func printDiffstat(localizer *i18n.Localizer, branch, base, colorMode string) error {
+	output, err := gitCommand("diff", "--stat", "--color="+colorMode, base+"..."+branch, "--").Output()
+	if err != nil {
+		return err
+	}
+	if len(output) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PreviewNoChanges",
+			TemplateData: map[string]interface{}{"Base": base},
+		})
+		fmt.Println(msg)
+		return nil
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "PreviewChangesSince",
+		TemplateData: map[string]interface{}{"Base": base},
+	})
+	fmt.Printf("%s\n\n%s", msg, output)
+	return nil
+}
+
+// printPullRequests writes the pull requests associated with branch. Errors
+// are shown inline since the preview window is the only place to report them.
+// Lookups are reused from cache for a few minutes when it is not nil.
+func printPullRequests(localizer *i18n.Localizer, branch, hosting string, cache *PreviewCache) {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return
+	}
+	var pulls []PullRequest
+	var fetched time.Time
+	var err error
+	if cached, hit := cache.pullRequests(hosting, branch); hit {
+		pulls, fetched = cached.PullRequests, cached.Fetched
+	} else {
+		var provider HostingProvider
+		if provider, err = newHostingProvider(hosting, remoteName); err == nil {
+			if pulls, err = provider.PullRequests(branchName); err == nil && cache != nil {
+				cache.storePullRequests(hosting, branch, pulls)
+			}
+		}
+	}
+	if err != nil {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PreviewPullRequestError",
+			TemplateData: map[string]interface{}{"Error": err},
+		})
+		fmt.Printf("%s%s%s\n\n", ColorYellow, msg, ColorReset)
+		return
+	}
+	if cached := pullRequestsCachedMessage(localizer, fetched); cached != "" {
+		fmt.Printf("%s%s%s\n", ColorDim, cached, ColorReset)
+	}
+	if len(pulls) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "PreviewNoPullRequest"})
+		fmt.Printf("%s\n\n", msg)
+		return
+	}
+	for _, pull := range pulls {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "PreviewPullRequest",
+			TemplateData: map[string]interface{}{"Number": pull.Number, "Title": pull.Title, "State": pull.State},
+		})
+		fmt.Printf("%s%s%s\n%s\n", pullRequestColor(pull.State), msg, ColorReset, pull.URL)
+	}
+	fmt.Println()
+}
+
+// pullRequestColor returns the color used to show a pull request in state
+func pullRequestColor(state string) string {
+	switch state {
+	case "open":
+		return ColorGreen
+	case "merged":
+		return ColorYellow
+	}
+	return ColorRed
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// profilesConfigKey is the configuration table of named profiles, each with
+// settings that apply only in repositories whose remote matches it, e.g.
+//
+//	[profiles."github.com-work"]
+//	match = "github.com/acme/*"
+//	token = "ghp_..."
+//	protected = ["release/*"]
+//
+//	[profiles."gitlab.internal"]
+//	stale-after = "3mo"
+//
+// A profile takes every key of the configuration file, plus match and token.
+const profilesConfigKey = "profiles"
+
+// profileMatchKey holds the patterns selecting a profile: a host name, or
+// host/owner/repo, with the wildcards of path.Match. Without it the profile's
+// name is the pattern.
+const profileMatchKey = "match"
+
+// profileTokenKey holds the token of the hosting API; the provider's token
+// environment variable still takes precedence
+const profileTokenKey = "token"
+
+// mergeProfiles adds the profiles table of a configuration file to profiles
+func mergeProfiles(profiles map[string]map[string]interface{}, value interface{}) error {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a table", profilesConfigKey)
+	}
+	for name, settings := range table {
+		settings, ok := settings.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.%s must be a table", profilesConfigKey, name)
+		}
+		if _, err := profilePatterns(name, settings); err != nil {
+			return fmt.Errorf("%s.%s: %w", profilesConfigKey, name, err)
+		}
+		if profiles[name] == nil {
+			profiles[name] = map[string]interface{}{}
+		}
+		for key, setting := range settings {
+			profiles[name][key] = setting
+		}
+	}
+	return nil
+}
+
+// profilePatterns returns the patterns of a profile's match key
+func profilePatterns(name string, settings map[string]interface{}) ([]string, error) {
+	var patterns []string
+	switch match := settings[profileMatchKey].(type) {
+	case nil:
+		patterns = []string{name}
+	case string:
+		patterns = []string{match}
+	case []interface{}:
+		for _, item := range match {
+			pattern, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value for %s: %v", profileMatchKey, item)
+			}
+			patterns = append(patterns, pattern)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for %s: %v", profileMatchKey, match)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in %s", pattern, profileMatchKey)
+		}
+	}
+	return patterns, nil
+}
+
+// parseProfileToken parses the token key of a profile
+func parseProfileToken(value interface{}) (string, error) {
+	token, ok := value.(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("invalid value for %s", profileTokenKey)
+	}
+	return token, nil
+}
+
+// selectProfile returns the profile matching the URL of origin, or of the
+// first remote without origin. When several match, the longest pattern, which
+// is the most specific one, wins.
+func selectProfile(profiles map[string]map[string]interface{}) (string, map[string]interface{}) {
+	if len(profiles) == 0 {
+		return "", nil
+	}
+	remoteURL, err := runGit("remote", "get-url", "origin")
+	if err != nil {
+		remotes, _ := runGit("remote")
+		first, _, _ := strings.Cut(remotes, "\n")
+		if first == "" {
+			return "", nil
+		}
+		if remoteURL, err = runGit("remote", "get-url", first); err != nil {
+			return "", nil
+		}
+	}
+	repo, err := parseRemoteURL(remoteURL)
+	if err != nil {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	selected, longest := "", -1
+	for _, name := range names {
+		patterns, _ := profilePatterns(name, profiles[name])
+		if match := profileMatch(patterns, repo); match > longest {
+			selected, longest = name, match
+		}
+	}
+	if selected == "" {
+		return "", nil
+	}
+	return selected, profiles[selected]
+}
+
+// profileMatch returns the length of the longest of patterns that matches
+// repo, or -1 if none does
+func profileMatch(patterns []string, repo RemoteRepo) int {
+	longest := -1
+	for _, pattern := range patterns {
+		subject := repo.Host
+		if strings.Contains(pattern, "/") {
+			subject = repo.Host + "/" + repo.Path
+		}
+		if matched, _ := path.Match(pattern, subject); matched {
+			longest = max(longest, len(pattern))
+		}
+	}
+	return longest
+}
+
+// profileToken returns the token of the selected profile for the hosting API
+// of repo, which may be on another remote than the one selecting the profile
+func profileToken(repo RemoteRepo) string {
+	if appConfig.token == "" || profileMatch(appConfig.profilePatterns, repo) < 0 {
+		return ""
+	}
+	return appConfig.token
+}
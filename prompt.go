@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/term"
+)
+
+// terminalAskOpts returns survey options that read answers from the
+// controlling terminal when stdin is not one, e.g. when branch names were
+// piped in with -stdin
+func terminalAskOpts() []survey.AskOpt {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	return []survey.AskOpt{survey.WithStdio(tty, tty, os.Stderr)}
+}
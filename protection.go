@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// defaultProtectedBranches can never be deleted by this tool
+var defaultProtectedBranches = []string{"main", "master"}
+
+var (
+	protectedPatternsOnce sync.Once
+	protectedPatterns     []string
+)
+
+// loadProtectedPatterns returns the glob patterns of protected branches: the
+// defaults, the `protected` patterns of the configuration, and every
+// `grbm.protected` value from git config (e.g. "release/*"), so protection can
+// be configured per repository
+func loadProtectedPatterns() []string {
+	protectedPatternsOnce.Do(func() {
+		protectedPatterns = append([]string{}, defaultProtectedBranches...)
+		protectedPatterns = append(protectedPatterns, appConfig.protected...)
+		// git config exits with 1 when the key is not set
+		output, err := runGit("config", "--get-all", "grbm.protected")
+		if err != nil {
+			return
+		}
+		for _, pattern := range strings.Split(output, "\n") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				protectedPatterns = append(protectedPatterns, pattern)
+			}
+		}
+	})
+	return protectedPatterns
+}
+
+// matchesBranchPattern reports whether a remote branch ("origin/release/1.0")
+// matches a glob pattern. Patterns are matched against the branch name without
+// the remote prefix ("release/*") as well as the full name ("origin/release/*").
+// As with `git branch --list`, * also matches "/".
+func matchesBranchPattern(pattern, branchName string) bool {
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return false
+	}
+	if re.MatchString(branchName) {
+		return true
+	}
+	if _, shortName, ok := splitRemoteBranch(branchName); ok {
+		return re.MatchString(shortName)
+	}
+	return false
+}
+
+// globToRegexp converts a glob pattern with *, ? and [...] classes into an
+// anchored regular expression
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// addProtectedPatterns protects branches matching additional patterns, such
+// as those looked up from a hosting provider
+func addProtectedPatterns(patterns ...string) {
+	protectedPatterns = append(loadProtectedPatterns(), patterns...)
+}
+
+// loadHostingProtection adds the protected branches that the hosting provider
+// of each remote reports. Failures are reported but do not stop the tool,
+// since local protection still applies.
+func loadHostingProtection(localizer *i18n.Localizer, hosting string, remoteBranches []BranchDetail) {
+	checked := map[string]bool{}
+	for _, branch := range remoteBranches {
+		remoteName, _, ok := splitRemoteBranch(branch.Name)
+		if !ok || checked[remoteName] {
+			continue
+		}
+		checked[remoteName] = true
+
+		provider, err := newHostingProvider(hosting, remoteName)
+		if err != nil {
+			logger.Debug("hosting", "remote", remoteName, "error", err)
+			continue
+		}
+		protection, ok := provider.(BranchProtectionProvider)
+		if !ok {
+			continue
+		}
+		patterns, err := protection.ProtectedBranchPatterns()
+		if err != nil {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "HostingProtectionError",
+				TemplateData: map[string]interface{}{"Remote": remoteName, "Error": err},
+			})
+			fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorYellow, msg, ColorReset)
+			continue
+		}
+		for _, pattern := range patterns {
+			addProtectedPatterns(remoteName + "/" + pattern)
+		}
+	}
+}
+
+// defaultProtectedNamespaces are the release-train namespaces whose branches
+// are only deleted with -allow-namespace
+var defaultProtectedNamespaces = []string{"release/*", "stable/*", "v[0-9]*"}
+
+var (
+	protectedNamespacesOnce sync.Once
+	protectedNamespaces     []string
+
+	// allowedNamespaces are the namespace patterns, or "all", whose branches
+	// may be deleted. Set from -allow-namespace.
+	allowedNamespaces []string
+)
+
+// loadProtectedNamespaces returns the glob patterns of protected namespaces:
+// the defaults and every `grbm.namespace` value from git config
+func loadProtectedNamespaces() []string {
+	protectedNamespacesOnce.Do(func() {
+		protectedNamespaces = append([]string{}, defaultProtectedNamespaces...)
+		output, err := runGit("config", "--get-all", "grbm.namespace")
+		if err != nil {
+			return
+		}
+		for _, pattern := range strings.Split(output, "\n") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				protectedNamespaces = append(protectedNamespaces, pattern)
+			}
+		}
+	})
+	return protectedNamespaces
+}
+
+// protectedNamespace returns the pattern of the protected namespace a branch
+// belongs to. Unlike protected branches, which can never be deleted, such
+// branches can be deleted after allowing their namespace.
+func protectedNamespace(branchName string) (string, bool) {
+	for _, pattern := range loadProtectedNamespaces() {
+		if matchesBranchPattern(pattern, branchName) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// isNamespaceAllowed reports whether -allow-namespace allows deleting the
+// branches of the namespace with the given pattern
+func isNamespaceAllowed(pattern string) bool {
+	for _, allowed := range allowedNamespaces {
+		if allowed == "all" || allowed == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedNamespace returns the namespace of a branch that may not be deleted
+// because its namespace is protected and not allowed
+func blockedNamespace(branchName string) (string, bool) {
+	namespace, ok := protectedNamespace(branchName)
+	if !ok || isNamespaceAllowed(namespace) {
+		return "", false
+	}
+	return namespace, true
+}
+
+// remoteHeadBranches returns those of the branches that their remote's HEAD
+// points to, i.e. its default branch whatever its name, as reported by
+// `git ls-remote --symref`. When a remote cannot be reached, the HEAD last
+// fetched from it (refs/remotes/<remote>/HEAD) is used instead.
+func remoteHeadBranches(branches []string) map[string]bool {
+	heads := map[string]bool{}
+	checked := map[string]bool{}
+	for _, branch := range branches {
+		remoteName, _, ok := splitRemoteBranch(branch)
+		if !ok || checked[remoteName] {
+			continue
+		}
+		checked[remoteName] = true
+
+		output, err := runGit("ls-remote", "--symref", remoteName, "HEAD")
+		if err == nil {
+			for _, line := range strings.Split(output, "\n") {
+				if target, ok := strings.CutPrefix(line, "ref: refs/heads/"); ok {
+					name, _, _ := strings.Cut(target, "\t")
+					heads[remoteName+"/"+name] = true
+				}
+			}
+		} else if head, err := runGit("symbolic-ref", "--short", "refs/remotes/"+remoteName+"/HEAD"); err == nil {
+			heads[head] = true
+		}
+	}
+	return heads
+}
+
+// includeProtected is set by -include-protected: protected branches can be
+// picked, and are deleted once their full name is typed at confirmation
+var includeProtected bool
+
+// acknowledgeProtectedDeletion asks for the full name of a protected branch
+// before deleting it, reporting whether it was typed
+func acknowledgeProtectedDeletion(localizer *i18n.Localizer, branch string) bool {
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "ConfirmProtectedDeletion",
+		TemplateData: map[string]interface{}{"Branch": branch},
+	})
+	fmt.Printf("%s%s%s\n", ColorRed, msg, ColorReset)
+	prompt, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "ConfirmProtectedPrompt"})
+	var answer string
+	if plainMode {
+		answer, _ = readPlainLine(prompt)
+	} else if err := survey.AskOne(&survey.Input{Message: prompt}, &answer, terminalAskOpts()...); err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == branch
+}
+
+// isProtectedBranch checks if a given branch name is a protected branch (e.g., main, master)
+// or frozen by the branch protect file
+func isProtectedBranch(branchName string) bool {
+	if isFrozenBranch(branchName) {
+		return true
+	}
+	for _, pattern := range loadProtectedPatterns() {
+		if matchesBranchPattern(pattern, branchName) {
+			return true
+		}
+	}
+	return false
+}
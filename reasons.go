@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/sync/errgroup"
+)
+
+// pullRequestLookups is the number of pull request lookups made at once
+const pullRequestLookups = 8
+
+// deletionReasons explains why each of the branches is a candidate for
+// deletion, for the confirmation: merged into the base, stale, its latest
+// pull request and whether a bot pushed it, or else how far ahead of the base
+// it is, and with -team who owns its area. all are every remote branch, whose
+// analyses stay cached.
+func deletionReasons(localizer *i18n.Localizer, branches []string, all []BranchDetail, options deleteOptions) map[string]string {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data, PluralCount: data["Count"]})
+		return msg
+	}
+	details := make(map[string]BranchDetail, len(all))
+	for _, branch := range all {
+		details[branch.Name] = branch
+	}
+
+	analyses := map[string]BranchAnalysis{}
+	if options.base != "" {
+		queue := make(chan BranchDetail)
+		go func() {
+			defer close(queue)
+			for _, branch := range branches {
+				if detail, ok := details[branch]; ok {
+					queue <- detail
+				}
+			}
+		}()
+		analyzeQueue(queue, options.base, func(branch BranchDetail, analysis BranchAnalysis) {
+			analyses[branch.Name] = analysis
+		})
+	}
+	pulls := lookupPullRequests(branches, options.hosting)
+
+	reasons := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		var parts []string
+		if isProtectedBranch(branch) {
+			parts = append(parts, localize("ReasonProtected", nil))
+		}
+		analysis, analyzed := analyses[branch]
+		if analyzed && analysis.Merged {
+			parts = append(parts, localize("ReasonMerged", nil))
+		}
+		if date := details[branch].ActivityDate(); !date.IsZero() && time.Since(date) >= ageThresholds.Stale {
+			parts = append(parts, localize("ReasonStale", map[string]interface{}{"Age": shortDuration(time.Since(date))}))
+		}
+		if prs := pulls[branch].PullRequests; len(prs) > 0 {
+			parts = append(parts, localize("ReasonPullRequest", map[string]interface{}{"Number": prs[0].Number, "State": prs[0].State}))
+		}
+		if _, ok := botPattern(branch); ok {
+			parts = append(parts, localize("ReasonBot", nil))
+		}
+		if len(parts) == 0 && analyzed {
+			parts = append(parts, localize("ReasonUnmerged", map[string]interface{}{"Count": analysis.Ahead}))
+		}
+		if owners := branchOwners(branch, options.base); len(owners) > 0 {
+			parts = append(parts, localize("ReasonOwners", map[string]interface{}{"Owners": strings.Join(owners, " ")}))
+		}
+		reasons[branch] = strings.Join(parts, ", ")
+	}
+	return reasons
+}
+
+// lookupPullRequests returns the pull requests of each branch on the hosting
+// provider of its remote, and when they were looked up, reusing the
+// preview's cache. Nothing is looked up with -hosting off, and failed lookups
+// are left out.
+func lookupPullRequests(branches []string, hosting string) map[string]cachedPullRequests {
+	pulls := map[string]cachedPullRequests{}
+	if hosting == "" || hosting == "off" {
+		return pulls
+	}
+	cache := loadPreviewCache()
+	var mu sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(pullRequestLookups)
+	for _, branch := range branches {
+		if cached, hit := cache.pullRequests(hosting, branch); hit {
+			pulls[branch] = cached
+			continue
+		}
+		remoteName, branchName, ok := splitRemoteBranch(branch)
+		if !ok {
+			continue
+		}
+		g.Go(func() error {
+			provider, err := newHostingProvider(hosting, remoteName)
+			if err != nil {
+				return nil
+			}
+			found, err := provider.PullRequests(branchName)
+			if err != nil {
+				logger.Debug("could not look up pull requests", "branch", branch, "error", err)
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			cache.storePullRequests(hosting, branch, found)
+			pulls[branch] = cachedPullRequests{Fetched: time.Now(), PullRequests: found}
+			return nil
+		})
+	}
+	g.Wait()
+	if err := cache.Save(); err != nil {
+		logger.Debug("could not save preview cache", "error", err)
+	}
+	return pulls
+}
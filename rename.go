@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// renameBranch renames a remote branch by pushing its tip under the new name
+// and deleting the old name in one atomic push, then re-points local branches
+// that tracked it. newName may be empty, in which case the user is prompted
+// for it.
+func renameBranch(localizer *i18n.Localizer, oldBranch, newName string) {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+
+	remoteName, oldName, ok := splitRemoteBranch(oldBranch)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid branch format: %s\n", oldBranch)
+		os.Exit(1)
+	}
+	if isProtectedBranch(oldBranch) {
+		fmt.Println(localize("RenameProtected", map[string]interface{}{"Branch": oldBranch}))
+		os.Exit(1)
+	}
+
+	if newName == "" {
+		message := localize("RenamePrompt", map[string]interface{}{"Branch": oldBranch})
+		if plainMode {
+			newName, _ = readPlainLine(message)
+		} else if err := survey.AskOne(&survey.Input{Message: message, Default: oldName}, &newName, terminalAskOpts()...); err != nil {
+			fmt.Println(localize("RenameCancelled", nil))
+			os.Exit(0)
+		}
+	}
+	// Accept the new name with or without the remote prefix
+	newName = strings.TrimPrefix(strings.TrimSpace(newName), remoteName+"/")
+	if newName == "" || newName == oldName {
+		fmt.Println(localize("RenameCancelled", nil))
+		os.Exit(0)
+	}
+	newBranch := remoteName + "/" + newName
+	if isProtectedBranch(newBranch) {
+		fmt.Println(localize("RenameProtected", map[string]interface{}{"Branch": newBranch}))
+		os.Exit(1)
+	}
+	if _, err := runGit("check-ref-format", "--branch", newName); err != nil {
+		fmt.Println(localize("RenameInvalidName", map[string]interface{}{"Name": newName}))
+		os.Exit(1)
+	}
+	if printNamingViolations(localizer, newName) {
+		os.Exit(1)
+	}
+
+	sha, err := runGit("rev-parse", "--verify", "refs/remotes/"+oldBranch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving %s: %v\n", oldBranch, err)
+		os.Exit(1)
+	}
+
+	message := localize("RenameConfirm", map[string]interface{}{"Old": oldBranch, "New": newBranch})
+	var confirm bool
+	if plainMode {
+		confirm = askPlainConfirm(message)
+	} else {
+		survey.AskOne(&survey.Confirm{Message: message, Default: false}, &confirm, terminalAskOpts()...)
+	}
+	if !confirm {
+		fmt.Println(localize("RenameCancelled", nil))
+		os.Exit(0)
+	}
+
+	// The new name is created and the old one deleted in one atomic push,
+	// so a failure changes nothing. The empty lease fails it if the new name
+	// was created on the remote since the last fetch, and the other one if
+	// someone pushed to the old name since.
+	newRef, oldRef := "refs/heads/"+newName, "refs/heads/"+oldName
+	if output, err := gitCommand("push", "--atomic", "--force-with-lease="+newRef+":", "--force-with-lease="+oldRef+":"+sha, remoteName, sha+":"+newRef, ":"+oldRef).CombinedOutput(); err != nil {
+		fmt.Println(localize("RenamePushFailed", map[string]interface{}{"Old": oldBranch, "New": newBranch, "Error": err}))
+		fmt.Println(string(output))
+		os.Exit(1)
+	}
+	// Make sure the remote-tracking ref exists before local branches are pointed at it
+	if _, err := runGit("update-ref", "refs/remotes/"+newBranch, sha); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not update refs/remotes/%s: %v\n", newBranch, err)
+	}
+	// and that the old name's is gone, as it would be after a fetch --prune
+	pruneTrackingRefs(remoteName, []string{oldName})
+
+	for _, localBranch := range localBranchesTracking(oldBranch) {
+		if _, err := runGit("branch", "--set-upstream-to="+newBranch, localBranch); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not update upstream of %s: %v\n", localBranch, err)
+			continue
+		}
+		fmt.Println(localize("RenameUpstreamUpdated", map[string]interface{}{"Local": localBranch, "Branch": newBranch}))
+	}
+
+	fmt.Println(localize("RenameSucceeded", map[string]interface{}{"Old": oldBranch, "New": newBranch}))
+}
+
+// localBranchesTracking returns the local branches whose upstream is the given
+// remote branch (e.g. "origin/feature")
+func localBranchesTracking(remoteBranch string) []string {
+	output, err := runGit("for-each-ref", "refs/heads", "--format=%(refname:short)%09%(upstream:short)")
+	if err != nil {
+		return nil
+	}
+	var locals []string
+	for _, line := range strings.Split(output, "\n") {
+		local, upstream, ok := strings.Cut(line, "\t")
+		if ok && upstream == remoteBranch {
+			locals = append(locals, local)
+		}
+	}
+	return locals
+}
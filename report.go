@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// AuthorReport aggregates the remote branches whose tip was authored by one person
+type AuthorReport struct {
+	Author       string    `json:"author"`
+	Email        string    `json:"email"`
+	Branches     int       `json:"branches"`
+	Merged       int       `json:"merged"`
+	Unmerged     int       `json:"unmerged"`
+	OldestDate   time.Time `json:"oldestDate"`
+	OldestBranch string    `json:"oldestBranch"`
+}
+
+// buildAuthorReports groups the non-protected branches by tip author, sorted
+// by branch count (descending) and then by author name
+func buildAuthorReports(remoteBranches []BranchDetail, base string) []*AuthorReport {
+	byAuthor := map[string]*AuthorReport{}
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if isProtectedBranch(branch.Name) {
+			return
+		}
+		key := strings.ToLower(branch.AuthorEmail)
+		report, ok := byAuthor[key]
+		if !ok {
+			report = &AuthorReport{Author: branch.Author, Email: branch.AuthorEmail}
+			byAuthor[key] = report
+		}
+		report.Branches++
+		if analysis.Merged {
+			report.Merged++
+		} else {
+			report.Unmerged++
+		}
+		if report.OldestDate.IsZero() || branch.ActivityDate().Before(report.OldestDate) {
+			report.OldestDate = branch.ActivityDate()
+			report.OldestBranch = branch.Name
+		}
+	})
+
+	reports := make([]*AuthorReport, 0, len(byAuthor))
+	for _, report := range byAuthor {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Branches != reports[j].Branches {
+			return reports[i].Branches > reports[j].Branches
+		}
+		return reports[i].Author < reports[j].Author
+	})
+	return reports
+}
+
+// printReport prints the branch ownership report as a table or as JSON
+func printReport(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string, asJSON bool) error {
+	reports := buildAuthorReports(remoteBranches, base)
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+
+	header := func(messageID string) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID})
+		return msg
+	}
+	fmt.Printf("%s %s %s %s %s  %s\n", padRight(header("ReportAuthor"), 40), padLeft(header("ReportBranches"), 8), padLeft(header("ReportMerged"), 8), padLeft(header("ReportUnmerged"), 8), padRight(header("ReportOldestAge"), dateColumnWidth), header("ReportOldestBranch"))
+	fmt.Println(strings.Repeat("-", 110))
+	for _, report := range reports {
+		author := fmt.Sprintf("%s <%s>", report.Author, report.Email)
+		fmt.Printf("%s %8d %8d %8d %s  %s\n", padRight(author, 40), report.Branches, report.Merged, report.Unmerged, padRight(formatDate(localizer, report.OldestDate), dateColumnWidth), report.OldestBranch)
+	}
+	return nil
+}
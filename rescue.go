@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// rescueActions are what can happen to a selected branch that is not merged
+// into the base, in the order they are offered
+var rescueActions = []struct{ Name, MessageID string }{
+	{"delete", "RescueActionDelete"},
+	{"delete-all", "RescueActionDeleteAll"},
+	{"pull-request", "RescueActionPullRequest"},
+	{"merge", "RescueActionMerge"},
+	{"keep", "RescueActionKeep"},
+}
+
+// rescueUnmerged offers to decide what to do with each selected branch that
+// is not merged into the base before it is deleted: delete it after all, open
+// a pull request for it, merge it into the current branch locally, or keep
+// it. It returns the branches that are still to be deleted.
+func rescueUnmerged(localizer *i18n.Localizer, branches []string, options deleteOptions) []string {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	all, err := listRemoteBranches()
+	if err != nil || options.base == "" {
+		return branches
+	}
+	selected := map[string]bool{}
+	for _, branch := range branches {
+		selected[branch] = true
+	}
+	queue := make(chan BranchDetail)
+	go func() {
+		defer close(queue)
+		for _, branch := range all {
+			if selected[branch.Name] {
+				queue <- branch
+			}
+		}
+	}()
+	unmerged := map[string]BranchAnalysis{}
+	analyzeQueue(queue, options.base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if !analysis.Merged {
+			unmerged[branch.Name] = analysis
+		}
+	})
+	if len(unmerged) == 0 {
+		return branches
+	}
+
+	// Deleting them all stays one keystroke away
+	var decide bool
+	message := localize("RescueAsk", map[string]interface{}{"Count": len(unmerged), "Base": options.base})
+	if plainMode {
+		decide = askPlainConfirm(message)
+	} else if err := survey.AskOne(&survey.Confirm{Message: message, Default: false}, &decide, terminalAskOpts()...); err == terminal.InterruptErr {
+		fmt.Println(localize("DeletionCancelled", nil))
+		os.Exit(0)
+	}
+	if !decide {
+		return branches
+	}
+
+	current, _ := runGit("symbolic-ref", "--quiet", "--short", "HEAD")
+	kept := map[string]bool{}
+	deleteAll := false
+	for _, branch := range branches {
+		analysis, ok := unmerged[branch]
+		if !ok || deleteAll {
+			continue
+		}
+		data := map[string]interface{}{"Branch": branch, "Base": options.base, "Ahead": analysis.Ahead, "Current": current}
+		var names, labels []string
+		for _, action := range rescueActions {
+			if action.Name == "merge" && current == "" {
+				continue
+			}
+			names = append(names, action.Name)
+			labels = append(labels, localize(action.MessageID, data))
+		}
+		message := localize("RescuePrompt", data)
+		var index int
+		if plainMode {
+			index = askPlainChoice(message, labels)
+		} else if err := survey.AskOne(&survey.Select{Message: message, Options: labels}, &index, terminalAskOpts()...); err == terminal.InterruptErr {
+			fmt.Println(localize("DeletionCancelled", nil))
+			os.Exit(0)
+		} else if err != nil {
+			index = -1
+		}
+		if index < 0 {
+			// Without an answer the branch is kept rather than deleted
+			kept[branch] = true
+			continue
+		}
+
+		switch names[index] {
+		case "delete-all":
+			deleteAll = true
+		case "pull-request":
+			if err := openPullRequest(branch, options.base, options.hosting); err != nil {
+				fmt.Printf("%s%s%s\n", ColorRed, localize("RescuePullRequestFailed", map[string]interface{}{"Branch": branch, "Error": err}), ColorReset)
+			}
+			kept[branch] = true
+		case "merge":
+			if output, err := mergeLocally(branch); err != nil {
+				fmt.Printf("%s%s%s\n%s\n", ColorRed, localize("RescueMergeFailed", map[string]interface{}{"Branch": branch, "Current": current, "Error": err}), ColorReset, strings.TrimSpace(output))
+			} else {
+				fmt.Println(localize("RescueMerged", data))
+			}
+			kept[branch] = true
+		case "keep":
+			kept[branch] = true
+		}
+	}
+
+	return slices.DeleteFunc(branches, func(branch string) bool { return kept[branch] })
+}
+
+// openPullRequest opens a pull request from branch into base with the command
+// line tool of the remote's hosting provider, gh or glab, which takes over
+// the terminal to ask for anything it needs
+func openPullRequest(branch, base, hosting string) error {
+	cmd, err := pullRequestCommand(branch, base, hosting)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// pullRequestCommand returns the gh or glab command that opens a pull request
+// from branch into base. -hosting off detects the provider from the remote's
+// host name.
+func pullRequestCommand(branch, base, hosting string) (*exec.Cmd, error) {
+	remoteName, branchName, ok := splitRemoteBranch(branch)
+	if !ok {
+		return nil, fmt.Errorf("invalid branch format: %s", branch)
+	}
+	if hosting == "" || hosting == "off" {
+		hosting = "auto"
+	}
+	provider, err := newHostingProvider(hosting, remoteName)
+	if err != nil {
+		return nil, err
+	}
+	target := base
+	if baseRemote, baseName, ok := splitRemoteBranch(base); ok && baseRemote == remoteName {
+		target = baseName
+	}
+
+	switch provider.Name() {
+	case "github":
+		return exec.Command("gh", "pr", "create", "--head", branchName, "--base", target, "--fill"), nil
+	case "gitlab":
+		return exec.Command("glab", "mr", "create", "--source-branch", branchName, "--target-branch", target, "--fill", "--yes"), nil
+	}
+	return nil, fmt.Errorf("opening pull requests on %s is not supported", provider.Name())
+}
+
+// mergeLocally merges branch into the checked-out branch, aborting the merge
+// if it conflicts so the work tree is left as it was
+func mergeLocally(branch string) (string, error) {
+	if _, err := runGit("symbolic-ref", "--quiet", "HEAD"); err != nil {
+		return "", errors.New("no branch is checked out")
+	}
+	output, err := gitCommand("merge", "--no-edit", "refs/remotes/"+branch).CombinedOutput()
+	if err != nil {
+		gitCommand("merge", "--abort").Run()
+	}
+	return string(output), err
+}
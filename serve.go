@@ -0,0 +1,399 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// defaultListenAddress is where serve listens unless -listen says otherwise;
+// only clients on the same machine can reach it
+const defaultListenAddress = "127.0.0.1:7878"
+
+// apiTokenEnv names the environment variable holding the bearer token that
+// serve requires from its clients. Without it, serve makes up a token and
+// prints it, since any web page the user visits can post to a local port.
+const apiTokenEnv = "GRBM_API_TOKEN"
+
+// maxPlanSize bounds the request body of POST /plans
+const maxPlanSize = 1 << 20
+
+// apiServer answers the HTTP API of `serve`. Requests are handled one at a
+// time since they all run git in the same repository.
+type apiServer struct {
+	mu      sync.Mutex
+	base    string
+	token   string
+	address string
+	options deleteOptions
+}
+
+// apiBranch is a remote branch as listed by GET /branches
+type apiBranch struct {
+	Name        string    `json:"name"`
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"authorEmail"`
+	Date        time.Time `json:"date"`
+	Message     string    `json:"message"`
+	Merged      bool      `json:"merged"`
+	AheadBehind
+	Stale     bool `json:"stale"`
+	Protected bool `json:"protected"`
+	Trash     bool `json:"trash"`
+	// Bot is the pattern that classifies the branch as pushed by a bot
+	Bot string `json:"bot,omitempty"`
+}
+
+// apiSkippedBranch is a planned branch that POST /plans did not delete.
+// Reason is one of gone, moved, default (the remote's HEAD), frozen (by the
+// branch protect file), protected or namespace.
+type apiSkippedBranch struct {
+	Branch  string `json:"branch"`
+	Reason  string `json:"reason"`
+	Current string `json:"current,omitempty"`
+}
+
+// apiFailedBranch is a planned branch whose deletion failed
+type apiFailedBranch struct {
+	Branch string `json:"branch"`
+	Error  string `json:"error"`
+	Output string `json:"output,omitempty"`
+}
+
+// apiPlanResult is the response of POST /plans
+type apiPlanResult struct {
+	DryRun  bool               `json:"dryRun,omitempty"`
+	Deleted []string           `json:"deleted"`
+	Failed  []apiFailedBranch  `json:"failed"`
+	Skipped []apiSkippedBranch `json:"skipped"`
+}
+
+// serveAPI serves the HTTP API on address until the process is stopped
+func serveAPI(localizer *i18n.Localizer, address, base string, options deleteOptions) error {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	server := &apiServer{base: base, token: os.Getenv(apiTokenEnv), address: address, options: options}
+	if server.token == "" {
+		token, err := generateAPIToken()
+		if err != nil {
+			return err
+		}
+		server.token = token
+		fmt.Fprintln(os.Stderr, localize("ServeGeneratedToken", map[string]interface{}{"Token": token, "Env": apiTokenEnv}))
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil && !isLoopbackHost(host) {
+		fmt.Fprintf(os.Stderr, "%s%s%s\n", ColorYellow, localize("ServeExposed", map[string]interface{}{"Address": address}), ColorReset)
+	}
+	fmt.Fprintln(os.Stderr, localize("ServeListening", map[string]interface{}{"Address": address}))
+
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
+
+// generateAPIToken makes up a random secret, such as the bearer token of a
+// serve started without one or the key of the finder's --listen API
+func generateAPIToken() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// isLoopbackHost reports whether host, as given to -listen, only accepts
+// connections from the same machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// allowedHost reports whether a request's Host header names the address the
+// server listens on, which defeats DNS rebinding: a page of another site
+// reaching the port through its own host name sends that name. When the
+// server listens on every interface, only the port can be checked.
+func (s *apiServer) allowedHost(requestHost string) bool {
+	listenHost, listenPort, err := net.SplitHostPort(s.address)
+	if err != nil {
+		return requestHost == s.address
+	}
+	host, port, err := net.SplitHostPort(requestHost)
+	if err != nil || port != listenPort {
+		return false
+	}
+	if ip := net.ParseIP(listenHost); listenHost == "" || (ip != nil && ip.IsUnspecified()) {
+		return true
+	}
+	return host == listenHost || (isLoopbackHost(listenHost) && isLoopbackHost(host))
+}
+
+// handler routes the API endpoints, behind the host, token and content type
+// checks
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /branches", s.listBranches)
+	mux.HandleFunc("POST /plans", s.applyPlan)
+	mux.HandleFunc("GET /history", s.listHistory)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("api", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+		if !s.allowedHost(r.Host) {
+			writeAPIError(w, http.StatusMisdirectedRequest, fmt.Errorf("unexpected host: %s", r.Host))
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+		// Browsers send cross-origin form posts without asking first, but
+		// never with a JSON content type
+		if r.Method == http.MethodPost {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				writeAPIError(w, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json"))
+				return
+			}
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON sends value as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// writeAPIError sends err as a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// newAPIBranch describes a remote branch and its analysis for API clients
+func newAPIBranch(branch BranchDetail, analysis BranchAnalysis) apiBranch {
+	bot, _ := botPattern(branch.Name)
+	return apiBranch{
+		Name:        branch.Name,
+		SHA:         branch.Hash,
+		Author:      branch.Author,
+		AuthorEmail: branch.AuthorEmail,
+		Date:        branch.ActivityDate(),
+		Message:     branch.Message,
+		Merged:      analysis.Merged,
+		AheadBehind: analysis.AheadBehind,
+		Stale:       time.Since(branch.ActivityDate()) >= ageThresholds.Stale,
+		Protected:   isProtectedBranch(branch.Name),
+		Trash:       isTrashBranch(branch.Name),
+		Bot:         bot,
+	}
+}
+
+// listAPIBranches returns every remote branch, or those matching a filter
+// preset, analyzed against base
+func listAPIBranches(base, filter string) ([]apiBranch, error) {
+	if filter == "" {
+		filter = "all"
+	}
+	if !isBranchFilter(filter) || filter == "menu" {
+		return nil, fmt.Errorf("unknown filter %q", filter)
+	}
+	remoteBranches, err := listRemoteBranches()
+	if err != nil {
+		return nil, err
+	}
+	remoteBranches = filterBranches(remoteBranches, base, filter)
+
+	analyses := make(map[string]BranchAnalysis, len(remoteBranches))
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		analyses[branch.Name] = analysis
+	})
+	branches := make([]apiBranch, 0, len(remoteBranches))
+	for _, branch := range remoteBranches {
+		branches = append(branches, newAPIBranch(branch, analyses[branch.Name]))
+	}
+	return branches, nil
+}
+
+// checkPlanDeletions checks a plan like apply does, returning the planned
+// branches that can be deleted and why the others are skipped. With
+// -own-only, the branches others authored are skipped, and the check fails
+// when the authors cannot be looked up.
+func checkPlanDeletions(plan *Plan) ([]string, []apiSkippedBranch, error) {
+	unchanged, moved, err := checkPlan(plan)
+	if err != nil {
+		return nil, nil, err
+	}
+	var email string
+	var authors map[string]BranchDetail
+	if ownBranchesOnly {
+		email, _ = runGit("config", "--get", "user.email")
+		if authors, err = remoteBranchAuthors(); err != nil {
+			return nil, nil, fmt.Errorf("looking up the authors of the branches for -own-only: %w", err)
+		}
+	}
+	skipped := []apiSkippedBranch{}
+	for _, branch := range moved {
+		reason := "moved"
+		if branch.Current == "" {
+			reason = "gone"
+		}
+		skipped = append(skipped, apiSkippedBranch{Branch: branch.Name, Reason: reason, Current: branch.Current})
+	}
+	var toDelete []string
+	heads := remoteHeadBranches(unchanged)
+	for _, branch := range unchanged {
+		if heads[branch] {
+			skipped = append(skipped, apiSkippedBranch{Branch: branch, Reason: "default"})
+		} else if isFrozenBranch(branch) {
+			skipped = append(skipped, apiSkippedBranch{Branch: branch, Reason: "frozen"})
+		} else if isProtectedBranch(branch) {
+			skipped = append(skipped, apiSkippedBranch{Branch: branch, Reason: "protected"})
+		} else if _, blocked := blockedNamespace(branch); blocked {
+			skipped = append(skipped, apiSkippedBranch{Branch: branch, Reason: "namespace"})
+		} else if ownBranchesOnly && isOthersBranch(authors[branch], email) {
+			skipped = append(skipped, apiSkippedBranch{Branch: branch, Reason: "others"})
+		} else {
+			toDelete = append(toDelete, branch)
+		}
+	}
+	return toDelete, skipped, nil
+}
+
+// planTips returns the planned tip of every branch in plan
+func planTips(plan *Plan) map[string]string {
+	tips := make(map[string]string, len(plan.Branches))
+	for _, branch := range plan.Branches {
+		tips[branch.Name] = branch.SHA
+	}
+	return tips
+}
+
+// checkDeletionLimit refuses to delete more branches than -max-deletions
+// allows, like the interactive flow does
+func checkDeletionLimit(branches []string, options deleteOptions) error {
+	if options.exceedsLimit(len(branches)) {
+		return fmt.Errorf("%d branches exceed the limit of %d deletions per run (-max-deletions)", len(branches), options.maxDeletions)
+	}
+	return nil
+}
+
+// deleteWithoutAsking deletes the branches for an API client, leased on the
+// tips the client confirmed or planned, and records the deleted ones in the
+// history with those tips
+func deleteWithoutAsking(branches []string, tips map[string]string, options deleteOptions, source string) apiPlanResult {
+	result := apiPlanResult{Deleted: []string{}, Failed: []apiFailedBranch{}, Skipped: []apiSkippedBranch{}}
+	deleteInBatches(branches, tips, options.batchSize, options.retries, func() bool { return false }, func(deleted deletionResult) {
+		if deleted.err != nil {
+			for _, branch := range deleted.branches {
+				result.Failed = append(result.Failed, apiFailedBranch{Branch: branch, Error: deleted.err.Error(), Output: deleted.output})
+			}
+			return
+		}
+		result.Deleted = append(result.Deleted, deleted.branches...)
+		recordDeletions(deleted.branches, tips, source)
+	})
+	options.finish(result.Deleted)
+	return result
+}
+
+// listBranches answers GET /branches with every remote branch and its
+// analysis against the base, or only those matching ?filter=merged, stale
+// or mine
+func (s *apiServer) listBranches(w http.ResponseWriter, r *http.Request) {
+	branches, err := listAPIBranches(s.base, r.URL.Query().Get("filter"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, branches)
+}
+
+// applyPlan answers POST /plans: the body is a plan as written by the plan
+// command, and the planned branches whose tips have not moved are deleted
+// without asking, or only checked with ?dry-run=true
+func (s *apiServer) applyPlan(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry-run"))
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPlanSize))
+	if err != nil {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	plan, err := parsePlan(data)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	toDelete, skipped, err := checkPlanDeletions(plan)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err)
+		return
+	}
+	if err := checkDeletionLimit(toDelete, s.options); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var result apiPlanResult
+	if dryRun {
+		result = apiPlanResult{DryRun: true, Deleted: append([]string{}, toDelete...), Failed: []apiFailedBranch{}}
+	} else {
+		result = deleteWithoutAsking(toDelete, planTips(plan), s.options, "api")
+	}
+	result.Skipped = skipped
+	writeJSON(w, http.StatusOK, result)
+}
+
+// listHistory answers GET /history with the audit history, most recent
+// deletion first, limited to ?limit entries and to those of ?branch
+func (s *apiServer) listHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+			return
+		}
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	matched := []HistoryEntry{}
+	for _, entry := range entries {
+		if branch := query.Get("branch"); branch != "" && entry.Branch != branch {
+			continue
+		}
+		matched = append(matched, entry)
+		if len(matched) == limit {
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, matched)
+}
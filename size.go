@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// BranchSize estimates what a branch adds on top of the base branch, so that
+// massive experimental branches can be archived rather than deleted outright
+type BranchSize struct {
+	Commits    int // commits the base branch lacks
+	Files      int // files changed since the branch forked
+	Insertions int
+	Deletions  int
+	// DiskUsage is the size in bytes of the objects only the branch
+	// references, or -1 when git cannot tell
+	DiskUsage int64
+}
+
+var shortStatPattern = regexp.MustCompile(`(\d+) (file|insertion|deletion)`)
+
+// estimateBranchSize computes the size of tip relative to base
+func estimateBranchSize(tip, base string) (BranchSize, error) {
+	counts, err := countAheadBehind(tip, base)
+	if err != nil {
+		return BranchSize{}, err
+	}
+	size := BranchSize{Commits: counts.Ahead, DiskUsage: -1}
+
+	// e.g. " 3 files changed, 10 insertions(+), 2 deletions(-)"
+	stat, err := runGit("diff", "--shortstat", base+"..."+tip, "--")
+	if err != nil {
+		return BranchSize{}, err
+	}
+	for _, match := range shortStatPattern.FindAllStringSubmatch(stat, -1) {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "file":
+			size.Files = n
+		case "insertion":
+			size.Insertions = n
+		case "deletion":
+			size.Deletions = n
+		}
+	}
+
+	// --disk-usage needs git 2.38; older versions simply leave it unknown
+	if usage, err := runGit("rev-list", "--disk-usage", "--objects", tip, "--not", base); err == nil {
+		if n, err := strconv.ParseInt(usage, 10, 64); err == nil {
+			size.DiskUsage = n
+		}
+	}
+	return size, nil
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.2 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatBranchSize describes the size of a branch in one line
+func formatBranchSize(localizer *i18n.Localizer, size BranchSize) string {
+	data := map[string]interface{}{
+		"Commits": size.Commits, "Files": size.Files, "Insertions": size.Insertions, "Deletions": size.Deletions,
+	}
+	messageID := "BranchSize"
+	if size.DiskUsage >= 0 {
+		messageID = "BranchSizeWithObjects"
+		data["Objects"] = formatBytes(size.DiskUsage)
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+	return msg
+}
+
+// largeObjectThreshold is the size in bytes from which a blob that would be
+// lost by deleting branches is warned about, or 0 for no warning. Set from
+// -large-object.
+var largeObjectThreshold int64
+
+// parseByteSize parses a size such as "10M" or "512K" (binary units) or a
+// plain byte count
+func parseByteSize(text string) (int64, error) {
+	number, multiplier := strings.ToUpper(strings.TrimSpace(text)), int64(1)
+	for i, unit := range []string{"K", "M", "G"} {
+		if trimmed, ok := strings.CutSuffix(number, unit); ok {
+			number, multiplier = trimmed, int64(1)<<(10*(i+1))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", text)
+	}
+	return n * multiplier, nil
+}
+
+// LargeBlob is a blob only reachable from branches about to be deleted
+type LargeBlob struct {
+	SHA  string
+	Path string
+	Size int64
+}
+
+// uniqueLargeBlobs returns the blobs of at least largeObjectThreshold bytes
+// that branch references but that neither the other branches of its remote,
+// except those in deleting, nor any tag reach. Once the branches are deleted,
+// the server's garbage collection drops them for good.
+func uniqueLargeBlobs(branch string, deleting []string) ([]LargeBlob, error) {
+	remoteName, _, ok := splitRemoteBranch(branch)
+	if !ok || largeObjectThreshold <= 0 {
+		return nil, nil
+	}
+	args := []string{"rev-list", "--objects", "refs/remotes/" + branch, "--not"}
+	for _, other := range deleting {
+		args = append(args, "--exclude=refs/remotes/"+other)
+	}
+	args = append(args, "--glob=refs/remotes/"+remoteName+"/*", "--tags")
+	objects, err := runGit(args...)
+	if err != nil || objects == "" {
+		return nil, err
+	}
+
+	// Commits and trees come with an empty path; only blobs can be large
+	paths := map[string]string{}
+	var shas []string
+	for _, line := range strings.Split(objects, "\n") {
+		sha, path, _ := strings.Cut(line, " ")
+		if path != "" {
+			paths[sha] = path
+			shas = append(shas, sha)
+		}
+	}
+	cmd := gitCommand("cat-file", "--batch-check=%(objecttype) %(objectsize) %(objectname)")
+	cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var blobs []LargeBlob
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "blob" {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[1], 10, 64); err == nil && size >= largeObjectThreshold {
+			blobs = append(blobs, LargeBlob{SHA: fields[2], Path: paths[fields[2]], Size: size})
+		}
+	}
+	return blobs, nil
+}
+
+// largeObjectWarnings describes the large blobs that deleting the branches
+// would lose, one message per blob. Moving branches to the trash loses
+// nothing.
+func largeObjectWarnings(localizer *i18n.Localizer, branches []string) []string {
+	if softDelete {
+		return nil
+	}
+	var warnings []string
+	for _, branch := range branches {
+		blobs, err := uniqueLargeBlobs(branch, branches)
+		if err != nil {
+			logger.Debug("could not look for large objects", "branch", branch, "error", err)
+			continue
+		}
+		for _, blob := range blobs {
+			msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+				MessageID:    "LargeObjectWarning",
+				TemplateData: map[string]interface{}{"Branch": branch, "Path": blob.Path, "Size": formatBytes(blob.Size)},
+			})
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings
+}
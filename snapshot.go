@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// maxSnapshots is the number of inventory snapshots kept; older ones are
+// removed when a new one is taken
+const maxSnapshots = 100
+
+// snapshotTimeLayout names the snapshot files, which sort by time
+const snapshotTimeLayout = "20060102T150405Z"
+
+// Snapshot is the inventory of the remote branches at one point in time
+type Snapshot struct {
+	Time     time.Time                 `json:"time"`
+	Branches map[string]SnapshotBranch `json:"branches"`
+}
+
+// SnapshotBranch is a remote branch as recorded in a snapshot
+type SnapshotBranch struct {
+	SHA    string    `json:"sha"`
+	Author string    `json:"author"`
+	Date   time.Time `json:"date"`
+}
+
+// SnapshotDiff is how the remote branches changed between two snapshots
+type SnapshotDiff struct {
+	Since       time.Time            `json:"since"`
+	Appeared    []SnapshotDiffBranch `json:"appeared"`
+	Disappeared []SnapshotDiffBranch `json:"disappeared"`
+	Moved       []SnapshotDiffBranch `json:"moved"`
+}
+
+// SnapshotDiffBranch is a branch that appeared, disappeared or moved
+type SnapshotDiffBranch struct {
+	Name   string `json:"name"`
+	SHA    string `json:"sha"`
+	OldSHA string `json:"oldSha,omitempty"`
+	Author string `json:"author"`
+	// DeletedByTool is true for disappeared branches the audit history shows
+	// were deleted with this tool since the snapshot
+	DeletedByTool bool `json:"deletedByTool,omitempty"`
+}
+
+// snapshotDir returns the directory of the snapshots inside the git
+// directory, next to the analysis cache
+func snapshotDir() (string, error) {
+	gitDir, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "grbm", "snapshots"), nil
+}
+
+// newSnapshot records the current inventory of the remote branches
+func newSnapshot(remoteBranches []BranchDetail) *Snapshot {
+	snapshot := &Snapshot{Time: time.Now().UTC().Truncate(time.Second), Branches: make(map[string]SnapshotBranch, len(remoteBranches))}
+	for _, branch := range remoteBranches {
+		snapshot.Branches[branch.Name] = SnapshotBranch{SHA: branch.Hash, Author: branch.Author, Date: branch.ActivityDate()}
+	}
+	return snapshot
+}
+
+// snapshotFiles returns the paths of the snapshots, oldest first
+func snapshotFiles() ([]string, error) {
+	dir, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// findSnapshot returns the latest snapshot taken at or before at, or nil if
+// there is none
+func findSnapshot(at time.Time) (*Snapshot, error) {
+	files, err := snapshotFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range slices.Backward(files) {
+		taken, err := time.Parse(snapshotTimeLayout, strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil || taken.After(at) {
+			continue
+		}
+		return readSnapshot(path)
+	}
+	return nil, nil
+}
+
+// recordSnapshot saves the current inventory unless it matches the latest
+// snapshot, and removes the snapshots beyond maxSnapshots. Snapshots are best
+// effort: failing to write one never fails a run.
+func recordSnapshot(remoteBranches []BranchDetail) {
+	snapshot := newSnapshot(remoteBranches)
+	if latest, err := findSnapshot(snapshot.Time); err == nil && latest != nil && sameInventory(latest, snapshot) {
+		return
+	}
+	dir, err := snapshotDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(snapshot)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, snapshot.Time.Format(snapshotTimeLayout)+".json"), data, 0o644)
+	}
+	if err != nil {
+		logger.Debug("could not write snapshot", "error", err)
+		return
+	}
+
+	files, err := snapshotFiles()
+	if err != nil {
+		return
+	}
+	for len(files) > maxSnapshots {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+}
+
+// sameInventory reports whether two snapshots have the same branches at the
+// same tips
+func sameInventory(a, b *Snapshot) bool {
+	return maps.EqualFunc(a.Branches, b.Branches, func(x, y SnapshotBranch) bool { return x.SHA == y.SHA })
+}
+
+// diffSnapshots compares the current inventory with an earlier snapshot
+func diffSnapshots(old, current *Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{Since: old.Time, Appeared: []SnapshotDiffBranch{}, Disappeared: []SnapshotDiffBranch{}, Moved: []SnapshotDiffBranch{}}
+	deleted := map[string]bool{}
+	if history, err := readHistory(); err == nil {
+		for _, entry := range history {
+			if !entry.Time.Before(old.Time) {
+				deleted[entry.Branch] = true
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(current.Branches)) {
+		branch := current.Branches[name]
+		previous, existed := old.Branches[name]
+		switch {
+		case !existed:
+			diff.Appeared = append(diff.Appeared, SnapshotDiffBranch{Name: name, SHA: branch.SHA, Author: branch.Author})
+		case previous.SHA != branch.SHA:
+			diff.Moved = append(diff.Moved, SnapshotDiffBranch{Name: name, SHA: branch.SHA, OldSHA: previous.SHA, Author: branch.Author})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(old.Branches)) {
+		if _, exists := current.Branches[name]; !exists {
+			branch := old.Branches[name]
+			diff.Disappeared = append(diff.Disappeared, SnapshotDiffBranch{Name: name, SHA: branch.SHA, Author: branch.Author, DeletedByTool: deleted[name]})
+		}
+	}
+	return diff
+}
+
+// printSnapshotDiff compares the current inventory with the snapshot taken
+// at or before since, or the latest one when since is zero, and lists the
+// branches that appeared, disappeared or moved, or prints them as JSON
+func printSnapshotDiff(localizer *i18n.Localizer, remoteBranches []BranchDetail, since time.Time, asJSON bool) error {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	current := newSnapshot(remoteBranches)
+	if since.IsZero() {
+		since = current.Time
+	}
+	old, err := findSnapshot(since)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		fmt.Println(localize("NoSnapshot", nil))
+		return nil
+	}
+	diff := diffSnapshots(old, current)
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+
+	fmt.Println(localize("SnapshotDiffHeader", map[string]interface{}{"Date": formatDate(localizer, old.Time), "Count": len(old.Branches), "Current": len(current.Branches)}))
+	if len(diff.Appeared)+len(diff.Disappeared)+len(diff.Moved) == 0 {
+		fmt.Println(localize("SnapshotUnchanged", nil))
+		return nil
+	}
+	for _, branch := range diff.Appeared {
+		fmt.Printf("%s+ %s%s %s%s %s%s\n", ColorGreen, padRight(branch.Name, 40), ColorReset, ColorDim, shortSHA(branch.SHA), branch.Author, ColorReset)
+	}
+	for _, branch := range diff.Disappeared {
+		note := ""
+		if branch.DeletedByTool {
+			note = " " + localize("SnapshotDeletedByTool", nil)
+		}
+		fmt.Printf("%s- %s%s %s%s %s%s%s\n", ColorRed, padRight(branch.Name, 40), ColorReset, ColorDim, shortSHA(branch.SHA), branch.Author, note, ColorReset)
+	}
+	for _, branch := range diff.Moved {
+		fmt.Printf("%s~ %s%s %s%s..%s %s%s\n", ColorYellow, padRight(branch.Name, 40), ColorReset, ColorDim, shortSHA(branch.OldSHA), shortSHA(branch.SHA), branch.Author, ColorReset)
+	}
+	fmt.Println(localize("SnapshotDiffSummary", map[string]interface{}{"Appeared": len(diff.Appeared), "Disappeared": len(diff.Disappeared), "Moved": len(diff.Moved)}))
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// snoozeNotesRef holds the snoozes, as git notes on the tips of the snoozed
+// branches. Each note has a line per branch snoozed at that commit:
+//
+//	snooze feature/x 2025-09-01 alice@example.com
+//
+// Notes follow the commit, so a branch that gets new commits is no longer
+// snoozed, which is what new work on it should mean.
+const snoozeNotesRef = "refs/notes/grbm"
+
+// Snooze defers the cleanup of a branch until a date
+type Snooze struct {
+	// Branch is the name without the remote prefix, since remotes are named
+	// differently in every clone
+	Branch string
+	Until  time.Time
+	By     string
+}
+
+var (
+	snoozesOnce sync.Once
+	// snoozes are the snoozes of snoozeNotesRef by the commit they are on
+	snoozes map[string][]Snooze
+)
+
+// parseSnoozeNote parses the lines of a snooze note, skipping those it does
+// not understand
+func parseSnoozeNote(note string) []Snooze {
+	var parsed []Snooze
+	for _, line := range strings.Split(note, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "snooze" {
+			continue
+		}
+		until, err := time.ParseInLocation(time.DateOnly, fields[2], time.Local)
+		if err != nil {
+			continue
+		}
+		snooze := Snooze{Branch: fields[1], Until: until}
+		if len(fields) > 3 {
+			snooze.By = fields[3]
+		}
+		parsed = append(parsed, snooze)
+	}
+	return parsed
+}
+
+// formatSnoozeNote writes snoozes back in the format of parseSnoozeNote
+func formatSnoozeNote(snoozes []Snooze) string {
+	var lines []string
+	for _, snooze := range snoozes {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("snooze %s %s %s", snooze.Branch, snooze.Until.Format(time.DateOnly), snooze.By)))
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// loadSnoozes reads every snooze note with a single git cat-file
+func loadSnoozes() map[string][]Snooze {
+	snoozesOnce.Do(func() {
+		snoozes = map[string][]Snooze{}
+		list, err := runGit("notes", "--ref="+snoozeNotesRef, "list")
+		if err != nil || list == "" {
+			return
+		}
+		var blobs, commits []string
+		for _, line := range strings.Split(list, "\n") {
+			if blob, commit, ok := strings.Cut(line, " "); ok {
+				blobs = append(blobs, blob)
+				commits = append(commits, commit)
+			}
+		}
+		cmd := gitCommand("cat-file", "--batch")
+		cmd.Stdin = strings.NewReader(strings.Join(blobs, "\n") + "\n")
+		output, err := cmd.Output()
+		if err != nil {
+			logger.Debug("could not read snoozes", "error", err)
+			return
+		}
+		// Each object is "<sha> blob <size>\n<content>\n"
+		rest := string(output)
+		for _, commit := range commits {
+			header, body, ok := strings.Cut(rest, "\n")
+			if !ok {
+				break
+			}
+			var size int
+			if fields := strings.Fields(header); len(fields) != 3 {
+				break
+			} else if _, err := fmt.Sscanf(fields[2], "%d", &size); err != nil || size > len(body) {
+				break
+			}
+			snoozes[commit] = parseSnoozeNote(body[:size])
+			rest = strings.TrimPrefix(body[size:], "\n")
+		}
+	})
+	return snoozes
+}
+
+// snoozedUntil returns until when a branch is snoozed, if it is snoozed at
+// its current tip and the snooze has not expired. Merged notes may snooze a
+// branch more than once; the latest date wins.
+func snoozedUntil(branch BranchDetail) (time.Time, bool) {
+	_, name, ok := splitRemoteBranch(branch.Name)
+	if !ok {
+		return time.Time{}, false
+	}
+	var until time.Time
+	for _, snooze := range loadSnoozes()[branch.Hash] {
+		if snooze.Branch == name && snooze.Until.After(until) {
+			until = snooze.Until
+		}
+	}
+	return until, time.Now().Before(until)
+}
+
+// snoozeAnnotation returns the suffix the branch list adds to a branch
+// snoozed until until, or nothing for the zero time
+func snoozeAnnotation(localizer *i18n.Localizer, until time.Time) string {
+	if until.IsZero() {
+		return ""
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    "SnoozedIndicator",
+		TemplateData: map[string]interface{}{"Until": until.Format(time.DateOnly)},
+	})
+	return msg
+}
+
+// withoutSnoozed leaves the snoozed branches out of the picker, saying how
+// many were left out
+func withoutSnoozed(localizer *i18n.Localizer, branches []BranchDetail) []BranchDetail {
+	kept := slices.DeleteFunc(slices.Clone(branches), func(branch BranchDetail) bool {
+		_, snoozed := snoozedUntil(branch)
+		return snoozed
+	})
+	if hidden := len(branches) - len(kept); hidden > 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "SnoozedHidden",
+			TemplateData: map[string]interface{}{"Count": hidden},
+			PluralCount:  hidden,
+		})
+		fmt.Printf("%s%s%s\n", ColorDim, msg, ColorReset)
+	}
+	return kept
+}
+
+// snoozeBranch snoozes a remote branch until a date: it merges the snoozes
+// of the branch's remote into the local ones, records the snooze on the
+// branch's tip, and pushes the snoozes back so others see it too
+func snoozeBranch(localizer *i18n.Localizer, branch string, until time.Time) error {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	ref, err := remoteBranchRef(branch)
+	if err != nil {
+		return err
+	}
+	remoteName, name, _ := splitRemoteBranch(branch)
+	tip, err := runGit("rev-parse", ref)
+	if err != nil {
+		return err
+	}
+
+	// Snoozes others pushed are merged line by line, so both sides' are kept
+	remoteNotes := "refs/notes/remotes/" + remoteName + "/grbm"
+	if _, err := runGit("fetch", "--quiet", remoteName, "+"+snoozeNotesRef+":"+remoteNotes); err == nil {
+		if _, err := runGit("notes", "--ref="+snoozeNotesRef, "merge", "--quiet", "--strategy=cat_sort_uniq", remoteNotes); err != nil {
+			fmt.Printf("%s%s%s\n", ColorYellow, localize("SnoozeMergeFailed", map[string]interface{}{"Remote": remoteName, "Error": err}), ColorReset)
+		}
+	}
+
+	var existing []Snooze
+	if note, err := runGit("notes", "--ref="+snoozeNotesRef, "show", tip); err == nil {
+		existing = parseSnoozeNote(note)
+	}
+	existing = slices.DeleteFunc(existing, func(snooze Snooze) bool { return snooze.Branch == name })
+	by, _ := runGit("config", "--get", "user.email")
+	existing = append(existing, Snooze{Branch: name, Until: until, By: by})
+	if _, err := runGit("notes", "--ref="+snoozeNotesRef, "add", "--force", "--message="+strings.TrimSuffix(formatSnoozeNote(existing), "\n"), tip); err != nil {
+		return err
+	}
+	fmt.Println(localize("BranchSnoozed", map[string]interface{}{"Branch": branch, "Until": until.Format(time.DateOnly)}))
+
+	if output, err := gitCommand("push", "--quiet", remoteName, snoozeNotesRef).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s\n%s", localize("SnoozePushFailed", map[string]interface{}{"Remote": remoteName, "Error": err}), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
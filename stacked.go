@@ -0,0 +1,70 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// stackedDependents returns the remote branches built on top of branch, as
+// in stacked pull requests: branches that contain its tip and have commits
+// of their own. Branches in deleting go along with it and are left out, as
+// are protected branches, which a branch reaches by being merged. A branch
+// merged into base has no dependents, since every branch started from base
+// since contains it.
+func stackedDependents(branch string, deleting []string, base string) ([]string, error) {
+	ref, err := remoteBranchRef(branch)
+	if err != nil {
+		return nil, err
+	}
+	if merged, err := isAncestor(ref, cmp.Or(base, "HEAD")); err != nil || merged {
+		return nil, err
+	}
+	tip, err := runGit("rev-parse", ref)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runGit("for-each-ref", "--contains", ref, "--format=%(refname) %(objectname)", "refs/remotes/")
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, line := range strings.Split(output, "\n") {
+		refname, sha, ok := strings.Cut(line, " ")
+		if !ok || sha == tip || strings.HasSuffix(refname, "/HEAD") {
+			continue
+		}
+		name := strings.TrimPrefix(refname, "refs/remotes/")
+		if slices.Contains(deleting, name) || isProtectedBranch(name) {
+			continue
+		}
+		dependents = append(dependents, name)
+	}
+	return dependents, nil
+}
+
+// stackedBranchWarnings warns about the branches to delete that other
+// branches are stacked on, listing the dependents whose pull requests the
+// deletion may close or leave without a base, one message per branch
+func stackedBranchWarnings(localizer *i18n.Localizer, branches []string, base string) []string {
+	var warnings []string
+	for _, branch := range branches {
+		dependents, err := stackedDependents(branch, branches, base)
+		if err != nil {
+			logger.Debug("could not look for stacked branches", "branch", branch, "error", err)
+			continue
+		}
+		if len(dependents) == 0 {
+			continue
+		}
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "StackedBranchWarning",
+			TemplateData: map[string]interface{}{"Branch": branch, "Count": len(dependents), "Dependents": strings.Join(dependents, ", ")},
+			PluralCount:  len(dependents),
+		})
+		warnings = append(warnings, msg)
+	}
+	return warnings
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// statsTopAuthors is the number of authors listed under the stale branches
+const statsTopAuthors = 5
+
+// AgeBucket counts the branches whose age falls in [Min, Max); Max is zero for
+// the last, open-ended bucket
+type AgeBucket struct {
+	Label    string        `json:"label"`
+	Min      time.Duration `json:"-"`
+	Max      time.Duration `json:"-"`
+	Branches int           `json:"branches"`
+}
+
+// AuthorCount is the number of branches whose tip was authored by one person
+type AuthorCount struct {
+	Author   string `json:"author"`
+	Email    string `json:"email"`
+	Branches int    `json:"branches"`
+}
+
+// BranchStats summarizes the hygiene of a repository's remote branches.
+// Everything except Total and Protected leaves out protected branches.
+type BranchStats struct {
+	Total           int           `json:"total"`
+	Protected       int           `json:"protected"`
+	Merged          int           `json:"merged"`
+	Unmerged        int           `json:"unmerged"`
+	Stale           int           `json:"stale"`
+	StaleAfter      string        `json:"staleAfter"`
+	AgeBuckets      []*AgeBucket  `json:"ageBuckets"`
+	TopStaleAuthors []AuthorCount `json:"topStaleAuthors"`
+	AverageAgeDays  float64       `json:"averageAgeDays"`
+}
+
+// newAgeBuckets returns the empty age buckets reported by stats
+func newAgeBuckets() []*AgeBucket {
+	const day = 24 * time.Hour
+	return []*AgeBucket{
+		{Label: "<1mo", Max: 30 * day},
+		{Label: "1-3mo", Min: 30 * day, Max: 90 * day},
+		{Label: "3-6mo", Min: 90 * day, Max: 180 * day},
+		{Label: "6-12mo", Min: 180 * day, Max: 365 * day},
+		{Label: ">1y", Min: 365 * day},
+	}
+}
+
+// buildBranchStats computes the statistics of the remote branches
+func buildBranchStats(remoteBranches []BranchDetail, base string) *BranchStats {
+	stats := &BranchStats{Total: len(remoteBranches), StaleAfter: shortDuration(ageThresholds.Stale), AgeBuckets: newAgeBuckets()}
+	staleAuthors := map[string]*AuthorCount{}
+	var totalAge time.Duration
+	var dated int
+
+	analyzeBranches(remoteBranches, base, func(branch BranchDetail, analysis BranchAnalysis) {
+		if isProtectedBranch(branch.Name) {
+			stats.Protected++
+			return
+		}
+		if analysis.Merged {
+			stats.Merged++
+		} else {
+			stats.Unmerged++
+		}
+
+		date := branch.ActivityDate()
+		if date.IsZero() {
+			return
+		}
+		age := time.Since(date)
+		totalAge += age
+		dated++
+		for _, bucket := range stats.AgeBuckets {
+			if age >= bucket.Min && (bucket.Max == 0 || age < bucket.Max) {
+				bucket.Branches++
+			}
+		}
+		if age >= ageThresholds.Stale {
+			stats.Stale++
+			key := strings.ToLower(branch.AuthorEmail)
+			author, ok := staleAuthors[key]
+			if !ok {
+				author = &AuthorCount{Author: branch.Author, Email: branch.AuthorEmail}
+				staleAuthors[key] = author
+			}
+			author.Branches++
+		}
+	})
+
+	if dated > 0 {
+		stats.AverageAgeDays = totalAge.Hours() / 24 / float64(dated)
+	}
+	stats.TopStaleAuthors = []AuthorCount{}
+	for _, author := range staleAuthors {
+		stats.TopStaleAuthors = append(stats.TopStaleAuthors, *author)
+	}
+	sort.Slice(stats.TopStaleAuthors, func(i, j int) bool {
+		if stats.TopStaleAuthors[i].Branches != stats.TopStaleAuthors[j].Branches {
+			return stats.TopStaleAuthors[i].Branches > stats.TopStaleAuthors[j].Branches
+		}
+		return stats.TopStaleAuthors[i].Author < stats.TopStaleAuthors[j].Author
+	})
+	if len(stats.TopStaleAuthors) > statsTopAuthors {
+		stats.TopStaleAuthors = stats.TopStaleAuthors[:statsTopAuthors]
+	}
+	return stats
+}
+
+// printStats prints the branch statistics as text or as JSON
+func printStats(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string, asJSON bool) error {
+	stats := buildBranchStats(remoteBranches, base)
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(stats)
+	}
+
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	fmt.Printf("%s %d\n", padRight(localize("StatsTotal", nil), 32), stats.Total)
+	fmt.Printf("%s %d\n", padRight(localize("StatsProtected", nil), 32), stats.Protected)
+	fmt.Printf("%s %d\n", padRight(localize("StatsMerged", nil), 32), stats.Merged)
+	fmt.Printf("%s %d\n", padRight(localize("StatsUnmerged", nil), 32), stats.Unmerged)
+	fmt.Printf("%s %d\n", padRight(localize("StatsStale", map[string]interface{}{"Age": stats.StaleAfter}), 32), stats.Stale)
+	fmt.Printf("%s %.1f\n", padRight(localize("StatsAverageAge", nil), 32), stats.AverageAgeDays)
+
+	fmt.Printf("\n%s\n", localize("StatsAgeBuckets", nil))
+	for _, bucket := range stats.AgeBuckets {
+		fmt.Printf("  %-8s %6d\n", bucket.Label, bucket.Branches)
+	}
+
+	if len(stats.TopStaleAuthors) > 0 {
+		fmt.Printf("\n%s\n", localize("StatsTopStaleAuthors", nil))
+		for _, author := range stats.TopStaleAuthors {
+			fmt.Printf("  %s %6d\n", padRight(fmt.Sprintf("%s <%s>", author.Author, author.Email), 40), author.Branches)
+		}
+	}
+	return nil
+}
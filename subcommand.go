@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gitSubcommandName is the executable name that makes the tool a git
+// subcommand, so that it runs as `git rbm`
+const gitSubcommandName = "git-rbm"
+
+// gitSubcommand reports whether the tool was started as git-rbm, typically
+// by git itself, and follows git's conventions
+var gitSubcommand = isGitSubcommand(os.Args[0])
+
+func isGitSubcommand(executable string) bool {
+	name := strings.TrimSuffix(filepath.Base(executable), ".exe")
+	return name == gitSubcommandName
+}
+
+// programName is the name usage messages show for the tool
+func programName() string {
+	if gitSubcommand {
+		return "git " + strings.TrimPrefix(gitSubcommandName, "git-")
+	}
+	return "git-remote-branch-manager"
+}
+
+// usageExitCode is the exit status of invalid arguments and settings: 2, or
+// 129 like git's own usage errors when run as git rbm
+func usageExitCode() int {
+	if gitSubcommand {
+		return 129
+	}
+	return 2
+}
+
+// fatalExitCode is the exit status of running where the tool cannot work at
+// all, such as outside a repository: 1, or 128 like git's fatal errors when
+// run as git rbm
+func fatalExitCode() int {
+	if gitSubcommand {
+		return 128
+	}
+	return 1
+}
+
+// configParameters is the -c flag, which sets a git configuration variable
+// for every git command the tool runs, like `git -c name=value`. The
+// variables are passed on in the environment, as git passes its own -c
+// options on to subcommands, so they reach the finder's preview too.
+type configParameters struct{}
+
+func (configParameters) String() string { return "" }
+
+func (configParameters) Set(value string) error {
+	name, setting, ok := strings.Cut(value, "=")
+	if !ok {
+		// As with git, a variable without a value is true
+		setting = "true"
+	}
+	if name == "" || !strings.Contains(name, ".") {
+		return fmt.Errorf("invalid configuration variable %q, expected section.name=value", value)
+	}
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	os.Setenv("GIT_CONFIG_KEY_"+strconv.Itoa(count), name)
+	os.Setenv("GIT_CONFIG_VALUE_"+strconv.Itoa(count), setting)
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count+1))
+	return nil
+}
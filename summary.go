@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// countChange is the number of branches before and after a deletion
+type countChange struct {
+	Name          string
+	Before, After int
+}
+
+// remoteSummary is the effect of a deletion on one remote and on each of
+// its namespaces that loses branches
+type remoteSummary struct {
+	countChange
+	Namespaces []countChange
+}
+
+// branchNamespace returns the namespace of a remote branch, e.g. "feature/*"
+// for "origin/feature/x", or an empty string for a top-level branch
+func branchNamespace(branch string) string {
+	_, name, _ := splitRemoteBranch(branch)
+	if namespace, _, ok := strings.Cut(name, "/"); ok {
+		return namespace + "/*"
+	}
+	return ""
+}
+
+// summarizePlan counts the branches of every remote, and of the namespaces
+// that lose branches, before and after deleting toDelete from all
+func summarizePlan(all, toDelete []string) []remoteSummary {
+	deleting := map[string]bool{}
+	for _, branch := range toDelete {
+		deleting[branch] = true
+	}
+
+	var remotes []string
+	byRemote := map[string]*remoteSummary{}
+	namespaces := map[string]map[string]*countChange{}
+	for _, branch := range all {
+		remoteName, _, ok := splitRemoteBranch(branch)
+		if !ok {
+			continue
+		}
+		summary, ok := byRemote[remoteName]
+		if !ok {
+			remotes = append(remotes, remoteName)
+			summary = &remoteSummary{countChange: countChange{Name: remoteName}}
+			byRemote[remoteName] = summary
+			namespaces[remoteName] = map[string]*countChange{}
+		}
+		namespace := branchNamespace(branch)
+		count, ok := namespaces[remoteName][namespace]
+		if !ok {
+			count = &countChange{Name: namespace}
+			namespaces[remoteName][namespace] = count
+		}
+		summary.Before++
+		count.Before++
+		if !deleting[branch] {
+			summary.After++
+			count.After++
+		}
+	}
+
+	sort.Strings(remotes)
+	summaries := make([]remoteSummary, 0, len(remotes))
+	for _, remoteName := range remotes {
+		summary := byRemote[remoteName]
+		for _, count := range namespaces[remoteName] {
+			if count.After < count.Before {
+				summary.Namespaces = append(summary.Namespaces, *count)
+			}
+		}
+		// The namespaces losing the most branches come first
+		sort.Slice(summary.Namespaces, func(i, j int) bool {
+			a, b := summary.Namespaces[i], summary.Namespaces[j]
+			if a.Before-a.After != b.Before-b.After {
+				return a.Before-a.After > b.Before-b.After
+			}
+			return a.Name < b.Name
+		})
+		summaries = append(summaries, *summary)
+	}
+	return summaries
+}
+
+// formatPlanSummary formats the effect of a deletion for -plan-summary, e.g.
+//
+//	origin: 412 → 318 branches (−94)
+//	  feature/*: 200 → 120 (−80)
+func formatPlanSummary(localizer *i18n.Localizer, summaries []remoteSummary) string {
+	localize := func(id string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+		return msg
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, localize("PlanSummaryHeader", nil))
+	for _, summary := range summaries {
+		fmt.Fprintf(&b, "  %s\n", localize("PlanSummaryRemote", map[string]interface{}{
+			"Remote": summary.Name, "Before": summary.Before, "After": summary.After, "Deleted": summary.Before - summary.After,
+		}))
+		for _, namespace := range summary.Namespaces {
+			name := namespace.Name
+			if name == "" {
+				name = localize("PlanSummaryTopLevel", nil)
+			}
+			fmt.Fprintf(&b, "    %s: %d → %d (−%d)\n", name, namespace.Before, namespace.After, namespace.Before-namespace.After)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// trackingRefs returns the tip of every remote-tracking branch by name,
+// skipping symbolic refs such as origin/HEAD
+func trackingRefs() (map[string]string, error) {
+	branches, err := listRemoteBranches()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		refs[branch.Name] = branch.Hash
+	}
+	return refs, nil
+}
+
+// renameCandidates returns the remote branches of after on remoteName that
+// point at sha. When the old name was only pruned by this fetch, branches
+// that already existed before it are not renames of it.
+func renameCandidates(before, after map[string]string, remoteName, sha string, justPruned bool) []string {
+	var candidates []string
+	for name, tip := range after {
+		if tip != sha || !strings.HasPrefix(name, remoteName+"/") {
+			continue
+		}
+		if _, existed := before[name]; existed && justPruned {
+			continue
+		}
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// syncRenamedBranches fetches every remote, pruning the remote-tracking
+// branches that are gone, and finds the ones that were renamed: a new branch
+// on the same remote points at the commit the old one did. Local branches
+// tracking a renamed branch are pointed at its new name after asking, and
+// renamed along with it when they had the old name.
+func syncRenamedBranches(localizer *i18n.Localizer) {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	confirm := func(message string) bool {
+		if plainMode {
+			return askPlainConfirm(message)
+		}
+		var answer bool
+		if err := survey.AskOne(&survey.Confirm{Message: message, Default: false}, &answer, terminalAskOpts()...); err == terminal.InterruptErr {
+			fmt.Println(localize("SyncCancelled", nil))
+			os.Exit(0)
+		}
+		return answer
+	}
+
+	before, err := trackingRefs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing remote branches: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(localize("SyncFetching", nil))
+	if output, err := gitCommand("fetch", "--all", "--prune", "--no-tags").CombinedOutput(); err != nil {
+		fmt.Println(localize("SyncFetchFailed", map[string]interface{}{"Error": err}))
+		fmt.Println(strings.TrimSpace(string(output)))
+		os.Exit(1)
+	}
+	after, err := trackingRefs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing remote branches: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Remote-tracking branches this fetch pruned
+	found := false
+	var pruned []string
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			pruned = append(pruned, name)
+		}
+	}
+	sort.Strings(pruned)
+	for _, name := range pruned {
+		remoteName, _, _ := splitRemoteBranch(name)
+		if candidates := renameCandidates(before, after, remoteName, before[name], true); len(candidates) == 1 {
+			fmt.Println(localize("SyncRenamed", map[string]interface{}{"Old": name, "New": candidates[0]}))
+			found = true
+		}
+	}
+
+	// Local branches whose upstream is gone, whether this fetch or an earlier
+	// one pruned it
+	for _, local := range loadLocalBranches() {
+		if local.Upstream == "" {
+			continue
+		}
+		if _, ok := after[local.Upstream]; ok {
+			continue
+		}
+		remoteName, oldName, ok := splitRemoteBranch(local.Upstream)
+		if !ok {
+			continue
+		}
+		sha, justPruned := before[local.Upstream]
+		if !justPruned {
+			sha = local.Hash
+		}
+		candidates := renameCandidates(before, after, remoteName, sha, justPruned)
+		if len(candidates) == 0 {
+			continue
+		}
+		found = true
+
+		newBranch := candidates[0]
+		if len(candidates) > 1 {
+			options := append(candidates, localize("SyncSkip", nil))
+			message := localize("SyncChooseCandidate", map[string]interface{}{"Local": local.Name, "Old": local.Upstream, "Count": len(candidates)})
+			var index int
+			if plainMode {
+				index = askPlainChoice(message, options)
+			} else if err := survey.AskOne(&survey.Select{Message: message, Options: options}, &index, terminalAskOpts()...); err == terminal.InterruptErr {
+				fmt.Println(localize("SyncCancelled", nil))
+				os.Exit(0)
+			} else if err != nil {
+				index = -1
+			}
+			if index < 0 || index == len(candidates) {
+				continue
+			}
+			newBranch = candidates[index]
+		}
+
+		// A local branch named like its upstream follows the rename, unless
+		// a local branch of the new name exists
+		_, newName, _ := splitRemoteBranch(newBranch)
+		renameLocal := local.Name == oldName
+		if _, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+newName); err == nil {
+			renameLocal = false
+		}
+		data := map[string]interface{}{"Local": local.Name, "Old": local.Upstream, "New": newBranch, "NewLocal": newName}
+		question := "SyncTrackNew"
+		if renameLocal {
+			question = "SyncTrackAndRename"
+		}
+		if !confirm(localize(question, data)) {
+			continue
+		}
+
+		if _, err := runGit("branch", "--set-upstream-to="+newBranch, local.Name); err != nil {
+			fmt.Printf("%s%s%s\n", ColorRed, localize("SyncUpstreamFailed", map[string]interface{}{"Local": local.Name, "Error": err}), ColorReset)
+			continue
+		}
+		fmt.Println(localize("RenameUpstreamUpdated", map[string]interface{}{"Local": local.Name, "Branch": newBranch}))
+		if renameLocal {
+			if _, err := runGit("branch", "-m", local.Name, newName); err != nil {
+				fmt.Printf("%s%s%s\n", ColorRed, localize("SyncLocalRenameFailed", map[string]interface{}{"Local": local.Name, "NewLocal": newName, "Error": err}), ColorReset)
+				continue
+			}
+			fmt.Println(localize("SyncLocalRenamed", data))
+		}
+	}
+
+	if !found {
+		fmt.Println(localize("SyncNothing", nil))
+	}
+}
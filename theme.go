@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// themeConfigKey is the configuration table customizing the status
+// indicators of the branch list and their colors, e.g.
+//
+//	[theme]
+//	merged = "✔"
+//	unmerged-color = "magenta"
+//	protected-color = "38;5;208"
+//
+// Keys that are not set keep the values of the -theme. Colors are names
+// (red, bright-blue, ...), "none", or the parameters of an ANSI SGR code.
+const themeConfigKey = "theme"
+
+// Theme is the text and color of each status indicator. Empty indicators
+// are the localized ones and empty colors the defaults.
+type Theme struct {
+	Merged         string
+	Unmerged       string
+	Protected      string
+	MergedColor    string
+	UnmergedColor  string
+	ProtectedColor string
+}
+
+// themes are the themes -theme accepts
+var themes = map[string]Theme{
+	"text":  {},
+	"emoji": {Merged: "✅", Unmerged: "🟥", Protected: "🔒"},
+}
+
+// theme is the current theme, with the configured indicators applied
+var theme Theme
+
+// colorNames are the colors a theme may use by name
+var colorNames = map[string]string{
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"bright-black": "90", "bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+	"bold": "1", "dim": "2",
+}
+
+// sgrPattern matches the parameters of an SGR escape code, e.g. "38;5;208"
+var sgrPattern = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+
+// parseColor turns a color of a theme into its escape code
+func parseColor(name string) (string, error) {
+	if name == "none" {
+		return "", nil
+	}
+	if code, ok := colorNames[name]; ok {
+		return "\033[" + code + "m", nil
+	}
+	if sgrPattern.MatchString(name) {
+		return "\033[" + name + "m", nil
+	}
+	return "", fmt.Errorf("invalid color: %s", name)
+}
+
+// parseTheme parses the theme table of a configuration file
+func parseTheme(value interface{}) (*Theme, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", themeConfigKey)
+	}
+	custom := &Theme{}
+	indicators := map[string]*string{"merged": &custom.Merged, "unmerged": &custom.Unmerged, "protected": &custom.Protected}
+	colors := map[string]*string{"merged-color": &custom.MergedColor, "unmerged-color": &custom.UnmergedColor, "protected-color": &custom.ProtectedColor}
+	for key, setting := range table {
+		field, isColor := colors[key]
+		if !isColor {
+			if field, ok = indicators[key]; !ok {
+				return nil, fmt.Errorf("%s: unknown key %q", themeConfigKey, key)
+			}
+		}
+		text, ok := setting.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid value for %s: %v", themeConfigKey, key, setting)
+		}
+		if _, err := parseColor(text); isColor && err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", themeConfigKey, key, err)
+		}
+		*field = text
+	}
+	return custom, nil
+}
+
+// applyTheme makes the named theme, with the configured indicators and colors
+// on top, the current one. It must run before colors are disabled.
+func applyTheme(name string, custom *Theme) error {
+	var ok bool
+	if theme, ok = themes[name]; !ok {
+		return fmt.Errorf("unknown theme: %s", name)
+	}
+	if custom != nil {
+		theme.Merged = cmp.Or(custom.Merged, theme.Merged)
+		theme.Unmerged = cmp.Or(custom.Unmerged, theme.Unmerged)
+		theme.Protected = cmp.Or(custom.Protected, theme.Protected)
+		theme.MergedColor = cmp.Or(custom.MergedColor, theme.MergedColor)
+		theme.UnmergedColor = cmp.Or(custom.UnmergedColor, theme.UnmergedColor)
+		theme.ProtectedColor = cmp.Or(custom.ProtectedColor, theme.ProtectedColor)
+	}
+	for _, color := range []struct {
+		name   string
+		target *string
+	}{
+		{theme.MergedColor, &ColorMerged}, {theme.UnmergedColor, &ColorUnmerged}, {theme.ProtectedColor, &ColorProtected},
+	} {
+		if color.name == "" {
+			continue
+		}
+		code, err := parseColor(color.name)
+		if err != nil {
+			return err
+		}
+		*color.target = code
+	}
+	return nil
+}
+
+// statusIndicator returns the indicator of the theme for a status, given by
+// the message ID of its localized text
+func statusIndicator(localizer *i18n.Localizer, messageID string) string {
+	custom := map[string]string{
+		"MergedIndicator":    theme.Merged,
+		"UnmergedIndicator":  theme.Unmerged,
+		"ProtectedIndicator": theme.Protected,
+	}[messageID]
+	if custom != "" {
+		return custom
+	}
+	return localizer.MustLocalize(&i18n.LocalizeConfig{MessageID: messageID})
+}
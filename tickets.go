@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// defaultTicketPattern matches Jira-style issue keys such as "JIRA-1234"
+const defaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// ticketMode is set from -tickets: "off", "show" to only extract and show
+// ticket references, or "jira" or "github" to also look up their status
+var ticketMode = "off"
+
+// ticketPattern is compiled from -ticket-pattern
+var ticketPattern = regexp.MustCompile(defaultTicketPattern)
+
+// TicketStatus is the state of a ticket on an issue tracker
+type TicketStatus struct {
+	Status string // e.g. "Done" or "closed", as the tracker names it
+	Closed bool
+}
+
+// TicketTracker looks up tickets on an issue tracker
+type TicketTracker interface {
+	TicketStatus(key string) (TicketStatus, error)
+}
+
+// ticketTracker is the tracker of -tickets jira or github; nil otherwise
+var ticketTracker TicketTracker
+
+// extractTickets returns the ticket references in the name of a branch and
+// the subject of its last commit, without duplicates
+func extractTickets(branch BranchDetail) []string {
+	_, name, _ := splitRemoteBranch(branch.Name)
+	var tickets []string
+	seen := map[string]bool{}
+	for _, text := range []string{name, branch.Message} {
+		for _, key := range ticketPattern.FindAllString(text, -1) {
+			if !seen[key] {
+				seen[key] = true
+				tickets = append(tickets, key)
+			}
+		}
+	}
+	return tickets
+}
+
+// ticketCache memoizes ticket lookups, since many branches usually refer to
+// the same tickets
+var ticketCache = struct {
+	sync.Mutex
+	statuses map[string]*TicketStatus
+}{statuses: map[string]*TicketStatus{}}
+
+// lookupTicket returns the status of a ticket, or false when there is no
+// tracker or the lookup failed
+func lookupTicket(key string) (TicketStatus, bool) {
+	if ticketTracker == nil {
+		return TicketStatus{}, false
+	}
+	ticketCache.Lock()
+	defer ticketCache.Unlock()
+	status, ok := ticketCache.statuses[key]
+	if !ok {
+		if result, err := ticketTracker.TicketStatus(key); err == nil {
+			status = &result
+		} else {
+			logger.Debug("ticket", "key", key, "error", err)
+		}
+		ticketCache.statuses[key] = status
+	}
+	if status == nil {
+		return TicketStatus{}, false
+	}
+	return *status, true
+}
+
+// ticketAnnotation describes the tickets a branch refers to, e.g.
+// "JIRA-12 (Done)", with closed tickets in green. When every ticket is closed
+// and the branch is merged, it is marked as safe to delete.
+func ticketAnnotation(localizer *i18n.Localizer, branch BranchDetail, merged bool) string {
+	if ticketMode == "off" {
+		return ""
+	}
+	tickets := extractTickets(branch)
+	if len(tickets) == 0 {
+		return ""
+	}
+	var parts []string
+	allClosed := ticketTracker != nil
+	for _, key := range tickets {
+		status, ok := lookupTicket(key)
+		switch {
+		case !ok:
+			allClosed = false
+			parts = append(parts, key)
+		case status.Closed:
+			parts = append(parts, fmt.Sprintf("%s%s (%s)%s", ColorGreen, key, status.Status, ColorReset))
+		default:
+			allClosed = false
+			parts = append(parts, fmt.Sprintf("%s (%s)", key, status.Status))
+		}
+	}
+	annotation := strings.Join(parts, ", ")
+	if allClosed && merged {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: "TicketsSafeToDelete"})
+		annotation += " " + ColorGreen + msg + ColorReset
+	}
+	return annotation
+}
+
+// jiraTracker uses the Jira REST API at baseURL. A personal access token is
+// read from JIRA_TOKEN; with JIRA_USER set, the token is sent as the password
+// of basic authentication, as Jira Cloud API tokens require.
+type jiraTracker struct {
+	baseURL string
+	headers map[string]string
+}
+
+func newJiraTracker(baseURL string) *jiraTracker {
+	headers := map[string]string{"Accept": "application/json"}
+	if token := firstEnv("JIRA_TOKEN"); token != "" {
+		if user := firstEnv("JIRA_USER"); user != "" {
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+token))
+		} else {
+			headers["Authorization"] = "Bearer " + token
+		}
+	}
+	return &jiraTracker{baseURL: strings.TrimSuffix(baseURL, "/"), headers: headers}
+}
+
+func (t *jiraTracker) TicketStatus(key string) (TicketStatus, error) {
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	requestURL := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=status", t.baseURL, url.PathEscape(key))
+	if err := getJSON(requestURL, t.headers, &issue); err != nil {
+		return TicketStatus{}, err
+	}
+	status := issue.Fields.Status
+	return TicketStatus{Status: status.Name, Closed: status.StatusCategory.Key == "done"}, nil
+}
+
+// gitHubIssueTracker looks up GitHub issues of the origin repository. The
+// issue number is the last number in the ticket reference, so patterns like
+// "#[0-9]+" or "GH-[0-9]+" can be used.
+type gitHubIssueTracker struct {
+	*gitHubProvider
+}
+
+var trailingNumber = regexp.MustCompile(`[0-9]+$`)
+
+func (t gitHubIssueTracker) TicketStatus(key string) (TicketStatus, error) {
+	number := trailingNumber.FindString(key)
+	if number == "" {
+		return TicketStatus{}, fmt.Errorf("no issue number in %s", key)
+	}
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if t.token != "" {
+		headers["Authorization"] = "Bearer " + t.token
+	}
+	var issue struct {
+		State string `json:"state"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/repos/%s/issues/%s", t.apiURL, t.repo.Path, number), headers, &issue); err != nil {
+		return TicketStatus{}, err
+	}
+	return TicketStatus{Status: issue.State, Closed: issue.State == "closed"}, nil
+}
+
+// newTicketTracker returns the tracker for -tickets jira or github
+func newTicketTracker(mode, jiraURL string) (TicketTracker, error) {
+	switch mode {
+	case "jira":
+		return newJiraTracker(jiraURL), nil
+	case "github":
+		remoteURL, err := runGit("remote", "get-url", "origin")
+		if err != nil {
+			return nil, err
+		}
+		repo, err := parseRemoteURL(remoteURL)
+		if err != nil {
+			return nil, err
+		}
+		return gitHubIssueTracker{newGitHubProvider(repo)}, nil
+	}
+	return nil, nil
+}
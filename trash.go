@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// trashNamespace is where -soft-delete moves branches instead of deleting
+// them, under the date they were moved: trash/2024-05-01/feature/x
+const trashNamespace = "trash"
+
+// trashDateLayout formats the date component of trash branch names
+const trashDateLayout = "2006-01-02"
+
+// softDelete is set from -soft-delete
+var softDelete bool
+
+// trashDate is the date branches deleted by this run are filed under
+var trashDate = time.Now().Format(trashDateLayout)
+
+// trashBranchName returns the name branchName (without the remote prefix) is
+// moved to by a soft deletion
+func trashBranchName(branchName string) string {
+	return trashNamespace + "/" + trashDate + "/" + branchName
+}
+
+// trashedBranch returns the remote branch a soft deletion moves branch (e.g.
+// "origin/feature") to
+func trashedBranch(branch string) string {
+	remoteName, branchName, _ := splitRemoteBranch(branch)
+	return remoteName + "/" + trashBranchName(branchName)
+}
+
+// trashedAt returns the date a branch in the trash namespace was moved there
+func trashedAt(branch string) (time.Time, bool) {
+	_, branchName, _ := splitRemoteBranch(branch)
+	rest, ok := strings.CutPrefix(branchName, trashNamespace+"/")
+	if !ok {
+		return time.Time{}, false
+	}
+	date, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(trashDateLayout, date, time.Local)
+	return t, err == nil
+}
+
+// isTrashBranch reports whether branch (e.g. "origin/trash/2024-05-01/x") was
+// moved to the trash by a soft deletion
+func isTrashBranch(branch string) bool {
+	_, ok := trashedAt(branch)
+	return ok
+}
+
+// withoutTrash leaves the branches in the trash out of the picker, so they are
+// only removed by empty-trash
+func withoutTrash(branches []BranchDetail) []BranchDetail {
+	var kept []BranchDetail
+	for _, branch := range branches {
+		if !isTrashBranch(branch.Name) {
+			kept = append(kept, branch)
+		}
+	}
+	return kept
+}
+
+// softDeleteArgs returns the git push arguments that move branches (without
+// the remote prefix) of one remote into the trash: each tip is pushed under
+// its trash name and the old name is deleted in the same atomic push, so a
+// branch is never lost half-way. tips are the commits to trash by branch
+// name, i.e. what the user saw when selecting the branches.
+func softDeleteArgs(remoteName string, branchNames []string, tips map[string]string) []string {
+	args := []string{"push", "--atomic", remoteName}
+	for _, branchName := range branchNames {
+		args = append(args, tips[branchName]+":refs/heads/"+trashBranchName(branchName), ":refs/heads/"+branchName)
+	}
+	return args
+}
+
+// deletedMessage reports a successfully deleted, or with -soft-delete
+// trashed, branch
+func deletedMessage(localizer *i18n.Localizer, branch string) string {
+	messageID := "BranchDeletedSuccessfully"
+	if softDelete {
+		messageID = "BranchMovedToTrash"
+	}
+	msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    messageID,
+		TemplateData: map[string]interface{}{"Branch": branch, "Trash": trashedBranch(branch)},
+	})
+	return msg
+}
+
+// trashOlderThan returns the branches that were moved to the trash at least
+// olderThan ago
+func trashOlderThan(branches []BranchDetail, olderThan time.Duration) []string {
+	var expired []string
+	for _, branch := range branches {
+		if trashed, ok := trashedAt(branch.Name); ok && time.Since(trashed) >= olderThan {
+			expired = append(expired, branch.Name)
+		}
+	}
+	return expired
+}
+
+// emptyTrash permanently deletes the branches moved to the trash at least
+// olderThan ago, with the usual confirmation. age is olderThan as given by
+// the user.
+func emptyTrash(localizer *i18n.Localizer, branches []BranchDetail, age string, olderThan time.Duration, options deleteOptions) {
+	expired := trashOlderThan(branches, olderThan)
+	if len(expired) == 0 {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "TrashNothingToEmpty",
+			TemplateData: map[string]interface{}{"Age": age},
+		})
+		fmt.Println(msg)
+		os.Exit(0)
+	}
+	// Emptying the trash really deletes, and its branches were reported when
+	// they were trashed
+	softDelete = false
+	options.deleteLocal = false
+	options.afterDeletion = nil
+	deleteBranches(localizer, expired, options)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// approvalColumns are the optional columns of a triage CSV that mark the
+// rows approved for deletion; rows with another value are left out
+var approvalColumns = []string{"approved", "delete"}
+
+// readTriageFile reads a list of branches approved for deletion with the
+// tips they were approved at: a CSV with at least the name and sha columns of
+// `list -csv`, the JSON of `list -json`, or a plan
+func readTriageFile(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan *Plan
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		plan, err = parsePlan(data)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		plan, err = parseInventoryJSON(data)
+	default:
+		plan, err = parseTriageCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// parseInventoryJSON reads the branches of `list -json` output
+func parseInventoryJSON(data []byte) (*Plan, error) {
+	var inventory []InventoryBranch
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		return nil, err
+	}
+	plan := &Plan{Version: planVersion}
+	for i, branch := range inventory {
+		if branch.Name == "" || branch.SHA == "" {
+			return nil, fmt.Errorf("entry %d: name and sha are required", i+1)
+		}
+		plan.Branches = append(plan.Branches, PlannedBranch{Name: branch.Name, SHA: branch.SHA, Author: branch.Author, Date: branch.Date, Merged: branch.Merged})
+	}
+	return plan, nil
+}
+
+// parseTriageCSV reads the approved rows of a CSV with a header row naming
+// its columns, as written by `list -csv`. Column names are case-insensitive
+// and extra columns, e.g. for notes, are ignored.
+func parseTriageCSV(data []byte) (*Plan, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	nameColumn, hasName := columns["name"]
+	shaColumn, hasSHA := columns["sha"]
+	if !hasName || !hasSHA {
+		return nil, fmt.Errorf("the header must name the name and sha columns")
+	}
+	approvalColumn := -1
+	for _, name := range approvalColumns {
+		if i, ok := columns[name]; ok {
+			approvalColumn = i
+			break
+		}
+	}
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	plan := &Plan{Version: planVersion}
+	for line, record := range records[1:] {
+		if approvalColumn >= 0 && !isApproval(field(record, approvalColumn)) {
+			continue
+		}
+		name, sha := field(record, nameColumn), field(record, shaColumn)
+		if name == "" && sha == "" {
+			continue
+		}
+		if name == "" || sha == "" {
+			return nil, fmt.Errorf("line %d: name and sha are required", line+2)
+		}
+		plan.Branches = append(plan.Branches, PlannedBranch{Name: name, SHA: sha})
+	}
+	return plan, nil
+}
+
+// isApproval reports whether a cell of the approval column approves a row
+func isApproval(value string) bool {
+	return slices.Contains([]string{"true", "yes", "y", "x", "1", "delete"}, strings.ToLower(value))
+}
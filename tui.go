@@ -0,0 +1,1273 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// ANSI escape codes used only by the TUI
+const (
+	styleReverse = "\033[7m"
+	styleBold    = "\033[1m"
+	styleDim     = "\033[2m"
+	styleReset   = "\033[0m"
+)
+
+// tuiPageSize is the number of rows in a page of the table; rows are analyzed
+// a page at a time, starting with the pages on screen
+const tuiPageSize = 100
+
+// spinnerFrames animate the rows still being analyzed
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is the time between two spinner frames
+const spinnerInterval = 100 * time.Millisecond
+
+// pullColumnWidth fits the PR column of a pull request like "#1234 merged"
+const pullColumnWidth = 12
+
+// tuiState is the screen the TUI is currently showing
+type tuiState int
+
+const (
+	tuiBrowsing tuiState = iota
+	tuiFiltering
+	tuiConfirming
+	tuiDeleting
+	tuiDone
+)
+
+// tuiRow is a single branch in the TUI table
+type tuiRow struct {
+	// index is the position of the row in the unfiltered table
+	index    int
+	branch   BranchDetail
+	analysis BranchAnalysis
+	analyzed bool
+	selected bool
+
+	// size and activity are computed once the row is first shown in the
+	// detail pane
+	size     *BranchSize
+	sizing   bool
+	activity string
+	charting bool
+
+	// pullRequest is the latest pull request of the branch with -hosting,
+	// looked up once the row's page is first shown
+	pullRequest *PullRequest
+	lookingUp   bool
+}
+
+// analysisMsg delivers the analysis of one branch to the TUI as it completes
+type analysisMsg struct {
+	branch   BranchDetail
+	analysis BranchAnalysis
+}
+
+// spinnerMsg advances the spinners of the rows being analyzed
+type spinnerMsg struct{}
+
+// sizeMsg delivers the size estimate of a branch for the detail pane
+type sizeMsg struct {
+	name string
+	size BranchSize
+	err  error
+}
+
+// activityMsg delivers the activity sparkline of a branch for the detail pane
+type activityMsg struct {
+	name     string
+	activity string
+	err      error
+}
+
+// pullRequestsMsg delivers the pull requests of the branches of a page
+type pullRequestsMsg struct {
+	pulls map[string]cachedPullRequests
+}
+
+// tuiQuestionKind is what a question of the confirmation asks
+type tuiQuestionKind int
+
+const (
+	// questionOtherTeams asks whether to delete the branches of areas
+	// CODEOWNERS assigns to other teams
+	questionOtherTeams tuiQuestionKind = iota
+	// questionDelete asks whether to delete the rows
+	questionDelete
+	// questionDrift asks what to do with a row someone pushed to since it
+	// was listed
+	questionDrift
+)
+
+// tuiQuestion is one of the questions the confirmation asks in turn
+type tuiQuestion struct {
+	kind tuiQuestionKind
+	// rows are the rows to delete the question is about
+	rows []*tuiRow
+	// remote names the remote and its URL when a selection spanning
+	// several remotes is confirmed one remote at a time
+	remote string
+	// drift is the new tip of the row of a questionDrift, and commits and
+	// err what showing its new commits found
+	drift   driftedBranch
+	commits string
+	err     string
+}
+
+// deletionMsg reports the result of one push of the deletion and of deleting
+// the local counterparts of the branches it deleted
+type deletionMsg struct {
+	result deletionResult
+	locals []localDeletion
+}
+
+// localDeletion is the outcome of deleting the local counterpart of a deleted
+// remote branch
+type localDeletion struct {
+	branch string
+	output string
+	err    error
+}
+
+// rescueMsg reports the outcome of opening a pull request for a branch or
+// merging it, instead of deleting it
+type rescueMsg struct {
+	branch string
+	action string
+	err    error
+}
+
+// deletionDoneMsg reports that every push of the deletion is done
+type deletionDoneMsg struct{}
+
+type tuiModel struct {
+	localizer *i18n.Localizer
+	options   deleteOptions
+	base      string
+
+	rows    []*tuiRow
+	byName  map[string]*tuiRow
+	visible []*tuiRow
+	cursor  int
+	offset  int
+	filter  string
+
+	state    tuiState
+	input    string
+	status   string
+	toDelete []*tuiRow
+	failed   []*tuiRow
+	deleted  []string
+	results  []string
+
+	// blocked are the selected rows left out of toDelete because their
+	// namespace is protected
+	blocked []*tuiRow
+	// others are the selected rows left out of toDelete because someone else
+	// authored them, with -own-only
+	others []*tuiRow
+
+	// locals are the local counterparts deleted along with toDelete, by
+	// remote branch, with -delete-local
+	locals        map[string]string
+	skippedLocals []skippedLocalBranch
+	// largeObjects warns about large files the deletion would lose
+	largeObjects []string
+	// mirrored names the branches the deletion deletes on mirrors too
+	mirrored []string
+	// stacked warns about branches stacked on the ones to delete
+	stacked []string
+	// failures are the branches a dry-run push shows cannot be deleted
+	failures map[string]string
+	// reasons explain why each branch to delete is a candidate
+	reasons map[string]string
+	// defaults are the selected rows left out of toDelete because they are
+	// the default branch of their remote
+	defaults []*tuiRow
+	// otherTeams are the owners of the rows to delete whose areas CODEOWNERS
+	// assigns to other teams than -team, by branch
+	otherTeams map[string][]string
+	// questions are the questions of the confirmation left to answer, the
+	// one on screen first
+	questions []tuiQuestion
+	// driftChecked is set once the confirmed rows were checked for pushes
+	// since they were listed
+	driftChecked bool
+	// tips are the commits of the remote branches when deleting started,
+	// those of the rows to delete as shown, on which the deletion is leased,
+	// for the events and the history
+	tips map[string]string
+	// deletions delivers the results of the deletion running in the
+	// background, one per push
+	deletions <-chan deletionMsg
+
+	// review disables deleting: d and enter end the TUI with the selected
+	// branches as recommended for deletion
+	review      bool
+	recommended []string
+
+	// pages asks the background analysis for the pages of rows on screen
+	pages    chan int
+	analyzed int
+	spinner  int
+
+	width  int
+	height int
+}
+
+// newTUIModel builds the TUI for the branches, before they are analyzed
+func newTUIModel(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string, options deleteOptions) *tuiModel {
+	model := &tuiModel{
+		localizer: localizer,
+		options:   options,
+		base:      base,
+		byName:    make(map[string]*tuiRow, len(remoteBranches)),
+		pages:     make(chan int, 64),
+	}
+	for i, branch := range remoteBranches {
+		row := &tuiRow{index: i, branch: branch}
+		model.rows = append(model.rows, row)
+		model.byName[branch.Name] = row
+	}
+	model.applyFilter()
+	return model
+}
+
+// run shows the TUI until it quits while the branches are analyzed in the
+// background
+func (m *tuiModel) run(remoteBranches []BranchDetail) error {
+	program := tea.NewProgram(m, tea.WithAltScreen())
+	go analyzeQueue(m.queuePages(remoteBranches), m.base, func(branch BranchDetail, analysis BranchAnalysis) {
+		program.Send(analysisMsg{branch: branch, analysis: analysis})
+	})
+	_, err := program.Run()
+	return err
+}
+
+// runTUI shows the full-screen branch table and runs the in-app
+// confirm/delete flow; results are printed once the TUI exits
+func runTUI(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string, options deleteOptions) error {
+	model := newTUIModel(localizer, remoteBranches, base, options)
+	if err := model.run(remoteBranches); err != nil {
+		return err
+	}
+	for _, result := range model.results {
+		fmt.Println(result)
+	}
+	if model.state != tuiDone {
+		fmt.Println(model.localize("DeletionCancelled", nil))
+	}
+	options.finish(model.deleted)
+	// As after a deletion from the prompts, a mistake noticed as the last
+	// branch goes can still be undone
+	if !options.assumeYes {
+		offerUndo(localizer, model.deleted, model.tips)
+	}
+	return nil
+}
+
+// runReviewTUI shows the branch table with deleting disabled, for triage by
+// people who cannot push, and returns the branches recommended for deletion
+func runReviewTUI(localizer *i18n.Localizer, remoteBranches []BranchDetail, base string) ([]string, error) {
+	model := newTUIModel(localizer, remoteBranches, base, deleteOptions{})
+	model.review = true
+	if err := model.run(remoteBranches); err != nil {
+		return nil, err
+	}
+	return model.recommended, nil
+}
+
+func (m *tuiModel) localize(messageID string, data map[string]interface{}) string {
+	msg, _ := m.localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+	return msg
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	m.requestPages()
+	return tea.Batch(m.sizeCmd(), m.activityCmd(), m.spinnerCmd(), m.pullRequestsCmd())
+}
+
+// queuePages feeds the branches to the analysis a page of rows at a time: the
+// page last asked for on m.pages first, then the others in order. Asking for
+// another page switches to it, even halfway through the current one.
+func (m *tuiModel) queuePages(branches []BranchDetail) <-chan BranchDetail {
+	queue := make(chan BranchDetail)
+	go func() {
+		defer close(queue)
+		queued := make([]bool, len(branches))
+		page, next := 0, 0
+		for {
+			select {
+			case page = <-m.pages:
+			default:
+			}
+			i, end := page*tuiPageSize, min((page+1)*tuiPageSize, len(branches))
+			for i < end && queued[i] {
+				i++
+			}
+			if i == end {
+				// The page is done: go on with the first page left
+				for next < len(branches) && queued[next] {
+					next++
+				}
+				if next == len(branches) {
+					return
+				}
+				page, i = next/tuiPageSize, next
+			}
+			queued[i] = true
+			queue <- branches[i]
+		}
+	}()
+	return queue
+}
+
+// requestPages asks the analysis for the pages of the rows on screen that are
+// not analyzed yet, without waiting
+func (m *tuiModel) requestPages() {
+	requested := map[int]bool{}
+	for i := m.offset; i < min(m.offset+m.tableHeight(), len(m.visible)); i++ {
+		row := m.visible[i]
+		if page := row.index / tuiPageSize; !row.analyzed && !requested[page] {
+			requested[page] = true
+			select {
+			case m.pages <- page:
+			default:
+			}
+		}
+	}
+}
+
+// spinnerCmd shows the next spinner frame after a while, as long as rows are
+// being analyzed
+func (m *tuiModel) spinnerCmd() tea.Cmd {
+	if m.analyzed >= len(m.rows) {
+		return nil
+	}
+	return tea.Tick(spinnerInterval, func(time.Time) tea.Msg { return spinnerMsg{} })
+}
+
+// sizeCmd estimates the size of the branch under the cursor in the
+// background, unless it is known or being estimated already
+func (m *tuiModel) sizeCmd() tea.Cmd {
+	if len(m.visible) == 0 {
+		return nil
+	}
+	row := m.visible[m.cursor]
+	if row.size != nil || row.sizing || isProtectedBranch(row.branch.Name) {
+		return nil
+	}
+	row.sizing = true
+	name, tip, base := row.branch.Name, row.branch.Hash, m.base
+	return func() tea.Msg {
+		size, err := estimateBranchSize(tip, base)
+		return sizeMsg{name: name, size: size, err: err}
+	}
+}
+
+// activityCmd charts the recent activity of the branch under the cursor in
+// the background, unless it is known or being charted already
+func (m *tuiModel) activityCmd() tea.Cmd {
+	if len(m.visible) == 0 {
+		return nil
+	}
+	row := m.visible[m.cursor]
+	if row.activity != "" || row.charting {
+		return nil
+	}
+	row.charting = true
+	name, tip, base, localizer := row.branch.Name, row.branch.Hash, m.base, m.localizer
+	return func() tea.Msg {
+		activity, err := formatActivity(localizer, tip, base)
+		return activityMsg{name: name, activity: activity, err: err}
+	}
+}
+
+// pullRequestsCmd looks up the pull requests of the page of the cursor in the
+// background with -hosting, unless they are known or being looked up already
+func (m *tuiModel) pullRequestsCmd() tea.Cmd {
+	hosting := m.options.hosting
+	if hosting == "" || hosting == "off" {
+		return nil
+	}
+	var names []string
+	page := m.cursor / tuiPageSize * tuiPageSize
+	for _, row := range m.visible[page:min(page+tuiPageSize, len(m.visible))] {
+		if !row.lookingUp && !isProtectedBranch(row.branch.Name) {
+			row.lookingUp = true
+			names = append(names, row.branch.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		return pullRequestsMsg{pulls: lookupPullRequests(names, hosting)}
+	}
+}
+
+// applyFilter recomputes the visible rows from the current filter
+func (m *tuiModel) applyFilter() {
+	query := strings.ToLower(m.filter)
+	m.visible = m.visible[:0]
+	for _, row := range m.rows {
+		if query == "" ||
+			strings.Contains(strings.ToLower(row.branch.Name), query) ||
+			strings.Contains(strings.ToLower(row.branch.Author), query) ||
+			(showSubjects && strings.Contains(strings.ToLower(row.branch.Message), query)) {
+			m.visible = append(m.visible, row)
+		}
+	}
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// tableHeight is the number of branch rows that fit on screen
+func (m *tuiModel) tableHeight() int {
+	if m.height == 0 {
+		return 20
+	}
+	// title, filter, column header, separator, detail pane (7) and help line
+	if h := m.height - 12; h > 1 {
+		return h
+	}
+	return 1
+}
+
+func (m *tuiModel) selectedRows() []*tuiRow {
+	var selected []*tuiRow
+	for _, row := range m.rows {
+		if row.selected {
+			selected = append(selected, row)
+		}
+	}
+	return selected
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.requestPages()
+	case analysisMsg:
+		if row, ok := m.byName[msg.branch.Name]; ok {
+			if !row.analyzed {
+				m.analyzed++
+			}
+			row.analysis = msg.analysis
+			row.analyzed = true
+		}
+	case spinnerMsg:
+		m.spinner = (m.spinner + 1) % len(spinnerFrames)
+		return m, m.spinnerCmd()
+	case sizeMsg:
+		if row, ok := m.byName[msg.name]; ok && msg.err == nil {
+			row.size = &msg.size
+		}
+	case activityMsg:
+		if row, ok := m.byName[msg.name]; ok && msg.err == nil {
+			row.activity = msg.activity
+		}
+	case pullRequestsMsg:
+		for name, cached := range msg.pulls {
+			if row, ok := m.byName[name]; ok && len(cached.PullRequests) > 0 {
+				row.pullRequest = &cached.PullRequests[0]
+			}
+		}
+	case deletionMsg:
+		return m.handleDeletion(msg)
+	case deletionDoneMsg:
+		m.state = tuiDone
+		return m, nil
+	case rescueMsg:
+		m.status = m.rescueStatus(msg)
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" && m.state != tuiDeleting {
+			return m, tea.Quit
+		}
+		switch m.state {
+		case tuiBrowsing:
+			model, cmd := m.updateBrowsing(msg)
+			m.requestPages()
+			return model, tea.Batch(cmd, m.sizeCmd(), m.activityCmd(), m.pullRequestsCmd())
+		case tuiFiltering:
+			model, cmd := m.updateFiltering(msg)
+			m.requestPages()
+			return model, tea.Batch(cmd, m.sizeCmd(), m.activityCmd(), m.pullRequestsCmd())
+		case tuiConfirming:
+			return m.updateConfirming(msg)
+		case tuiDone:
+			if msg.String() == "r" && len(m.failed) > 0 {
+				m.toDelete, m.failed = m.failed, nil
+				return m.startDeletion()
+			}
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m *tuiModel) updateBrowsing(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	switch msg.String() {
+	case "q", "esc":
+		if msg.String() == "esc" && m.filter != "" {
+			m.filter = ""
+			m.applyFilter()
+			return m, nil
+		}
+		return m, tea.Quit
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case "pgup":
+		m.moveCursor(-m.tableHeight())
+	case "pgdown":
+		m.moveCursor(m.tableHeight())
+	case "[":
+		m.moveCursor(-tuiPageSize)
+	case "]":
+		m.moveCursor(tuiPageSize)
+	case " ", "x":
+		if len(m.visible) > 0 {
+			row := m.visible[m.cursor]
+			if !isProtectedBranch(row.branch.Name) {
+				row.selected = !row.selected
+			}
+		}
+	case "a":
+		// Select all visible rows, or clear them if they are all selected already
+		allSelected := true
+		for _, row := range m.visible {
+			if !row.selected && !isProtectedBranch(row.branch.Name) {
+				allSelected = false
+			}
+		}
+		for _, row := range m.visible {
+			if !isProtectedBranch(row.branch.Name) {
+				row.selected = !allSelected
+			}
+		}
+	case "c":
+		// Copy the selected branch names, or the one under the cursor
+		rows := m.selectedRows()
+		if len(rows) == 0 && len(m.visible) > 0 {
+			rows = []*tuiRow{m.visible[m.cursor]}
+		}
+		var names []string
+		for _, row := range rows {
+			names = append(names, row.branch.Name)
+		}
+		if err := clipboard.WriteAll(strings.Join(names, "\n")); err != nil {
+			m.status = m.localize("CopyFailed", map[string]interface{}{"Error": err})
+		} else {
+			m.status = m.localize("CopySucceeded", map[string]interface{}{"Count": len(names)})
+		}
+	case "/":
+		m.state = tuiFiltering
+	case "p":
+		// Open a pull request for the branch under the cursor instead of
+		// deleting it; gh and glab take over the terminal meanwhile
+		if len(m.visible) > 0 {
+			name := m.visible[m.cursor].branch.Name
+			cmd, err := pullRequestCommand(name, m.base, m.options.hosting)
+			if err != nil {
+				m.status = m.rescueStatus(rescueMsg{branch: name, action: "pull-request", err: err})
+				return m, nil
+			}
+			return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+				return rescueMsg{branch: name, action: "pull-request", err: err}
+			})
+		}
+	case "m":
+		// Merge the branch under the cursor into the checked-out branch
+		if len(m.visible) > 0 {
+			name := m.visible[m.cursor].branch.Name
+			return m, func() tea.Msg {
+				_, err := mergeLocally(name)
+				return rescueMsg{branch: name, action: "merge", err: err}
+			}
+		}
+	case "d", "enter":
+		if m.review {
+			for _, row := range m.selectedRows() {
+				m.recommended = append(m.recommended, row.branch.Name)
+			}
+			if len(m.recommended) == 0 {
+				m.status = m.localize("NoBranchesSelected", nil)
+				return m, nil
+			}
+			return m, tea.Quit
+		}
+		// Branches of protected namespaces are left out unless allowed
+		m.toDelete, m.blocked, m.others, m.defaults = nil, nil, nil, nil
+		email, _ := runGit("config", "--get", "user.email")
+		var selected []string
+		for _, row := range m.selectedRows() {
+			selected = append(selected, row.branch.Name)
+		}
+		emitBranchEvents("selected", selected)
+		heads := remoteHeadBranches(selected)
+		for _, row := range m.selectedRows() {
+			if heads[row.branch.Name] {
+				m.defaults = append(m.defaults, row)
+				emitEvent(Event{Event: "skipped", Branch: row.branch.Name, Reason: "default-branch"})
+			} else if _, blocked := blockedNamespace(row.branch.Name); blocked {
+				m.blocked = append(m.blocked, row)
+				emitEvent(Event{Event: "skipped", Branch: row.branch.Name, Reason: "namespace"})
+			} else if isOthersBranch(row.branch, email) {
+				m.others = append(m.others, row)
+				emitEvent(Event{Event: "skipped", Branch: row.branch.Name, Reason: "others"})
+			} else {
+				m.toDelete = append(m.toDelete, row)
+			}
+		}
+		if len(m.toDelete) == 0 {
+			m.status = m.localize("NoBranchesSelected", nil)
+			if len(m.others) > 0 {
+				m.status = m.othersMessage(m.others[0])
+			}
+			if len(m.blocked) > 0 {
+				m.status = m.namespaceMessage("NamespaceBranchSkipped", m.blocked[0])
+			}
+			if len(m.defaults) > 0 {
+				m.status = m.defaultBranchMessage(m.defaults[0])
+			}
+			return m, nil
+		}
+		if m.options.exceedsLimit(len(m.toDelete)) {
+			m.status = m.localize("DeletionLimitExceeded", map[string]interface{}{"Count": len(m.toDelete), "Limit": m.options.maxDeletions})
+			m.toDelete = nil
+			return m, nil
+		}
+		var names []string
+		for _, row := range m.toDelete {
+			names = append(names, row.branch.Name)
+		}
+		if m.options.deleteLocal {
+			m.locals, m.skippedLocals = planLocalDeletions(names)
+		}
+		m.largeObjects = largeObjectWarnings(m.localizer, names)
+		shown := map[string]string{}
+		for _, row := range m.toDelete {
+			shown[row.branch.Name] = row.branch.Hash
+		}
+		m.mirrored = mirrorWarnings(m.localizer, names, shown)
+		m.stacked = stackedBranchWarnings(m.localizer, names, m.base)
+		m.failures = checkDeletePermissions(names)
+		all := make([]BranchDetail, len(m.rows))
+		for i, row := range m.rows {
+			all[i] = row.branch
+		}
+		m.reasons = deletionReasons(m.localizer, names, all, m.options)
+
+		// Branches of areas other teams own are asked about first, as by
+		// the command line
+		m.otherTeams = map[string][]string{}
+		var otherTeamRows []*tuiRow
+		for _, row := range m.toDelete {
+			if owners := branchOwners(row.branch.Name, m.base); len(owners) > 0 && !isOwnedByTeam(owners) {
+				m.otherTeams[row.branch.Name] = owners
+				otherTeamRows = append(otherTeamRows, row)
+			}
+		}
+		m.questions = nil
+		if len(otherTeamRows) > 0 {
+			m.questions = append(m.questions, tuiQuestion{kind: questionOtherTeams, rows: otherTeamRows})
+		}
+		// A selection spanning several remotes is confirmed one remote at
+		// a time, as by the command line
+		if groups := groupByRemote(names); len(groups) > 1 {
+			for _, group := range groups {
+				question := tuiQuestion{kind: questionDelete, remote: remoteGroupLabel(m.localizer, group)}
+				for _, row := range m.toDelete {
+					if slices.Contains(group.branches, row.branch.Name) {
+						question.rows = append(question.rows, row)
+					}
+				}
+				m.questions = append(m.questions, question)
+			}
+		} else {
+			m.questions = append(m.questions, tuiQuestion{kind: questionDelete, rows: slices.Clone(m.toDelete)})
+		}
+		m.input = ""
+		m.driftChecked = false
+		m.state = tuiConfirming
+	}
+	return m, nil
+}
+
+// dropRows leaves rows out of the deletion and of the questions left
+func (m *tuiModel) dropRows(rows []*tuiRow) {
+	drop := func(row *tuiRow) bool { return slices.Contains(rows, row) }
+	m.toDelete = slices.DeleteFunc(m.toDelete, drop)
+	for i := range m.questions {
+		m.questions[i].rows = slices.DeleteFunc(m.questions[i].rows, drop)
+	}
+	m.questions = slices.DeleteFunc(m.questions, func(question tuiQuestion) bool { return len(question.rows) == 0 })
+}
+
+// nextQuestion moves on from the question on screen
+func (m *tuiModel) nextQuestion() (tea.Model, tea.Cmd) {
+	m.questions = m.questions[1:]
+	return m.askNext()
+}
+
+// askNext goes on with the next question, with deleting once all are
+// answered, or back to the table when nothing is left to delete
+func (m *tuiModel) askNext() (tea.Model, tea.Cmd) {
+	m.input = ""
+	if len(m.toDelete) == 0 {
+		m.state, m.questions = tuiBrowsing, nil
+		m.status = m.localize("NoBranchesSelected", nil)
+		return m, nil
+	}
+	if len(m.questions) > 0 {
+		return m, nil
+	}
+	// Right before deleting, as by the command line, the rows are checked
+	// for pushes since they were listed
+	if !m.driftChecked {
+		m.driftChecked = true
+		var names []string
+		shown := map[string]string{}
+		for _, row := range m.toDelete {
+			names = append(names, row.branch.Name)
+			shown[row.branch.Name] = row.branch.Hash
+		}
+		for _, drifted := range detectDrift(names, shown) {
+			row := m.toDelete[slices.IndexFunc(m.toDelete, func(row *tuiRow) bool { return row.branch.Name == drifted.Branch })]
+			m.questions = append(m.questions, tuiQuestion{kind: questionDrift, rows: []*tuiRow{row}, drift: drifted})
+		}
+		if len(m.questions) > 0 {
+			return m, nil
+		}
+	}
+	return m.startDeletion()
+}
+
+// updateDrift handles the keys of a questionDrift: d deletes the branch
+// anyway, s shows its new commits and k skips it. The branch is fetched
+// first so that the deletion is leased on its new tip.
+func (m *tuiModel) updateDrift(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	question := &m.questions[0]
+	data := map[string]interface{}{"Branch": question.drift.Branch}
+	switch msg.String() {
+	case "d", "s":
+		if question.commits == "" {
+			if err := fetchRemoteBranch(question.drift.Branch); err != nil {
+				data["Error"] = err
+				question.err = m.localize("DriftFetchFailed", data)
+				return m, nil
+			}
+		}
+		if msg.String() == "d" {
+			// The deletion is leased on the new tip just fetched
+			question.rows[0].branch.Hash = question.drift.Current
+			return m.nextQuestion()
+		}
+		commits, err := runGit("log", "--format=%h %an: %s", question.drift.Shown+".."+question.drift.Current)
+		if err != nil || commits == "" {
+			// A force push leaves no new commits on top of the shown tip
+			commits, _ = runGit("log", "-1", "--format=%h %an: %s", question.drift.Current)
+		}
+		question.commits = commits
+	case "k", "n", "N":
+		m.emitSkipped(question.rows, "moved")
+		return m.declineQuestion(*question)
+	case "esc", "q":
+		m.state = tuiBrowsing
+	}
+	return m, nil
+}
+
+func (m *tuiModel) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.tableHeight() {
+		m.offset = m.cursor - m.tableHeight() + 1
+	}
+}
+
+func (m *tuiModel) updateFiltering(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.state = tuiBrowsing
+	case tea.KeyEsc:
+		m.filter = ""
+		m.state = tuiBrowsing
+	case tea.KeyBackspace:
+		if runes := []rune(m.filter); len(runes) > 0 {
+			m.filter = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+	}
+	m.offset = 0
+	m.applyFilter()
+	return m, nil
+}
+
+func (m *tuiModel) updateConfirming(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	question := m.questions[0]
+	if question.kind == questionDrift {
+		return m.updateDrift(msg)
+	}
+	if question.kind == questionOtherTeams {
+		switch msg.String() {
+		case "y", "Y":
+			return m.nextQuestion()
+		case "n", "N":
+			return m.declineQuestion(question)
+		case "esc", "q":
+			m.state = tuiBrowsing
+		}
+		return m, nil
+	}
+
+	if len(question.rows) > m.options.confirmThreshold {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.state = tuiBrowsing
+		case tea.KeyEnter:
+			if isLargeDeletionConfirmed(m.input, len(question.rows)) {
+				return m.nextQuestion()
+			}
+			// An empty answer keeps the branches of this remote
+			if m.input == "" && question.remote != "" {
+				m.emitSkipped(question.rows, "declined")
+				return m.declineQuestion(question)
+			}
+			m.input = ""
+		case tea.KeyBackspace:
+			if runes := []rune(m.input); len(runes) > 0 {
+				m.input = string(runes[:len(runes)-1])
+			}
+		case tea.KeyRunes:
+			m.input += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "y", "Y":
+		return m.nextQuestion()
+	case "n", "N":
+		if question.remote != "" {
+			m.emitSkipped(question.rows, "declined")
+			return m.declineQuestion(question)
+		}
+		m.state = tuiBrowsing
+	case "esc", "q":
+		m.state = tuiBrowsing
+	}
+	return m, nil
+}
+
+// emitSkipped writes a skipped event for each of rows
+func (m *tuiModel) emitSkipped(rows []*tuiRow, reason string) {
+	for _, row := range rows {
+		emitEvent(Event{Event: "skipped", Branch: row.branch.Name, Reason: reason})
+	}
+}
+
+// rescueStatus describes the outcome of a rescueMsg for the status line
+func (m *tuiModel) rescueStatus(msg rescueMsg) string {
+	current, _ := runGit("symbolic-ref", "--quiet", "--short", "HEAD")
+	data := map[string]interface{}{"Branch": msg.branch, "Base": m.base, "Current": current, "Error": msg.err}
+	switch {
+	case msg.action == "merge" && msg.err != nil:
+		return m.localize("RescueMergeFailed", data)
+	case msg.action == "merge":
+		return m.localize("RescueMerged", data)
+	case msg.err != nil:
+		return m.localize("RescuePullRequestFailed", data)
+	}
+	return m.localize("RescuePullRequestOpened", data)
+}
+
+// declineQuestion keeps the rows of the question on screen and goes on with
+// the rest
+func (m *tuiModel) declineQuestion(question tuiQuestion) (tea.Model, tea.Cmd) {
+	m.questions = m.questions[1:]
+	m.dropRows(question.rows)
+	return m.askNext()
+}
+
+// startDeletion deletes m.toDelete in the background, in batches as by the
+// command line, leased on the tips the rows showed
+func (m *tuiModel) startDeletion() (tea.Model, tea.Cmd) {
+	m.state = tuiDeleting
+	m.tips = remoteTips()
+	var names []string
+	for _, row := range m.toDelete {
+		names = append(names, row.branch.Name)
+		m.tips[row.branch.Name] = row.branch.Hash
+	}
+	tips, locals := maps.Clone(m.tips), m.locals
+	batchSize, retries := m.options.batchSize, m.options.retries
+	deletions := make(chan deletionMsg)
+	go func() {
+		defer close(deletions)
+		deleteInBatches(names, tips, batchSize, retries, func() bool { return false }, func(result deletionResult) {
+			msg := deletionMsg{result: result}
+			for _, branch := range result.branches {
+				if local, ok := locals[branch]; ok && result.err == nil {
+					output, err := deleteLocalBranch(local)
+					msg.locals = append(msg.locals, localDeletion{branch: local, output: output, err: err})
+				}
+			}
+			deletions <- msg
+		})
+	}()
+	m.deletions = deletions
+	return m, m.nextDeletionCmd()
+}
+
+// nextDeletionCmd waits for the next push of the deletion to be done
+func (m *tuiModel) nextDeletionCmd() tea.Cmd {
+	deletions := m.deletions
+	return func() tea.Msg {
+		msg, ok := <-deletions
+		if !ok {
+			return deletionDoneMsg{}
+		}
+		return msg
+	}
+}
+
+func (m *tuiModel) handleDeletion(msg deletionMsg) (tea.Model, tea.Cmd) {
+	var hints []string
+	for _, name := range msg.result.branches {
+		if msg.result.err != nil {
+			hint := pushFailureHint(m.localizer, name, msg.result.output)
+			if i := slices.IndexFunc(m.toDelete, func(row *tuiRow) bool { return row.branch.Name == name }); i >= 0 {
+				m.failed = append(m.failed, m.toDelete[i])
+			}
+			m.results = append(m.results, m.localize("ErrorDeletingBranch", map[string]interface{}{"Branch": name, "Error": msg.result.err}))
+			reason := msg.result.err.Error()
+			if hint != "" {
+				reason += ": " + hint
+				hints = append(hints, hint)
+			}
+			emitEvent(Event{Event: "failed", Branch: name, SHA: m.tips[name], Error: reason})
+			continue
+		}
+		m.deleted = append(m.deleted, name)
+		m.results = append(m.results, deletedMessage(m.localizer, name))
+		event := Event{Event: "deleted", Branch: name, SHA: m.tips[name]}
+		if softDelete {
+			event.Trash = trashedBranch(name)
+		}
+		emitEvent(event)
+	}
+	if msg.result.err == nil {
+		recordDeletions(msg.result.branches, m.tips, "cli")
+	}
+	if output := strings.TrimSpace(msg.result.output); output != "" {
+		m.results = append(m.results, output)
+	}
+	for _, hint := range hints {
+		m.results = append(m.results, ColorYellow+hint+ColorReset)
+	}
+	for _, local := range msg.locals {
+		if local.err != nil {
+			m.results = append(m.results, m.localize("ErrorDeletingLocalBranch", map[string]interface{}{"Branch": local.branch, "Error": local.err}), strings.TrimSpace(local.output))
+		} else {
+			m.results = append(m.results, m.localize("LocalBranchDeleted", map[string]interface{}{"Branch": local.branch}))
+		}
+	}
+	return m, m.nextDeletionCmd()
+}
+
+func (m *tuiModel) View() string {
+	switch m.state {
+	case tuiConfirming:
+		return m.viewConfirm()
+	case tuiDeleting, tuiDone:
+		return m.viewResults()
+	}
+	return m.viewTable()
+}
+
+func (m *tuiModel) viewTable() string {
+	var b strings.Builder
+
+	selected := len(m.selectedRows())
+	fmt.Fprintf(&b, "%s%s%s", styleBold, m.localize("TUITitle", map[string]interface{}{"Count": len(m.rows), "Selected": selected}), styleReset)
+	pages := max((len(m.visible)+tuiPageSize-1)/tuiPageSize, 1)
+	fmt.Fprintf(&b, "%s  %s%s\n", styleDim, m.localize("TUIPage", map[string]interface{}{"Page": m.cursor/tuiPageSize + 1, "Pages": pages, "Analyzed": m.analyzed, "Count": len(m.rows)}), styleReset)
+	if m.state == tuiFiltering || m.filter != "" {
+		cursor := ""
+		if m.state == tuiFiltering {
+			cursor = "_"
+		}
+		fmt.Fprintf(&b, "/%s%s\n", m.filter, cursor)
+	} else {
+		b.WriteString("\n")
+	}
+
+	// Names are sized to the page of the cursor, not the whole table
+	nameWidth := 20
+	page := m.cursor / tuiPageSize * tuiPageSize
+	for _, row := range m.visible[page:min(page+tuiPageSize, len(m.visible))] {
+		if n := displayWidth(row.branch.Name); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	if nameWidth > 50 {
+		nameWidth = 50
+	}
+
+	// The PR column is only there when -hosting looks pull requests up
+	showPulls := m.options.hosting != "" && m.options.hosting != "off"
+	header := fmt.Sprintf("    %s %s %s %s ", padRight(m.localize("Branch", nil), nameWidth), padRight(m.localize("TUIColumnAge", nil), dateColumnWidth), padRight(m.localize("TUIColumnDistance", nil), 11), padRight(m.localize("TUIColumnAuthor", nil), 20))
+	if showPulls {
+		header += padRight(m.localize("TUIColumnPR", nil), pullColumnWidth) + " "
+	}
+	header += m.localize("TUIColumnStatus", nil)
+	fmt.Fprintf(&b, "%s%s%s\n", styleDim, header, styleReset)
+
+	end := m.offset + m.tableHeight()
+	if end > len(m.visible) {
+		end = len(m.visible)
+	}
+	for i := m.offset; i < end; i++ {
+		row := m.visible[i]
+		mark := "[ ]"
+		if row.selected {
+			mark = "[x]"
+		}
+		indicator, color := spinnerFrames[m.spinner]+" "+m.localize("TUIAnalyzing", nil), styleDim
+		if row.analyzed || isProtectedBranch(row.branch.Name) {
+			indicator, color = branchIndicator(m.localizer, row.branch, row.analysis)
+			if forcePushed := forcePushedAnnotation(m.localizer, row.analysis.ForcePushed); forcePushed != "" {
+				indicator += " " + forcePushed
+			}
+		}
+		if i == m.cursor {
+			color = styleReverse
+		}
+		// The age cell keeps its staleness color within the row's color
+		age := padRight(formatDate(m.localizer, row.branch.ActivityDate()), dateColumnWidth)
+		if _, ageColor := ageAnnotation(m.localizer, row.branch); ageColor != "" {
+			age = ageColor + age + styleReset + color
+		}
+		var distance string
+		if row.analyzed && !isProtectedBranch(row.branch.Name) {
+			distance = formatAheadBehind(row.analysis.AheadBehind)
+		}
+		line := fmt.Sprintf("%s %s %s %s %s ", mark, padRight(truncate(row.branch.Name, nameWidth), nameWidth), age, padRight(distance, 11), padRight(truncate(row.branch.Author, 20), 20))
+		if showPulls {
+			var pull string
+			if row.pullRequest != nil {
+				pull = fmt.Sprintf("#%d %s", row.pullRequest.Number, row.pullRequest.State)
+			}
+			line += padRight(pull, pullColumnWidth) + " "
+		}
+		line += indicator
+		fmt.Fprintf(&b, "%s%s%s\n", color, line, styleReset)
+	}
+	for i := end - m.offset; i < m.tableHeight(); i++ {
+		b.WriteString("\n")
+	}
+
+	// Detail pane for the branch under the cursor
+	b.WriteString(styleDim + strings.Repeat("-", 60) + styleReset + "\n")
+	if len(m.visible) > 0 {
+		branch := m.visible[m.cursor].branch
+		fmt.Fprintf(&b, "%s\n", branch.Hash)
+		fmt.Fprintf(&b, "%s <%s>\n", branch.Author, branch.AuthorEmail)
+		fmt.Fprintf(&b, "%s\n", m.localize("TUIDates", map[string]interface{}{
+			"Author":    formatDate(m.localizer, branch.Date),
+			"Committer": formatDate(m.localizer, branch.CommitterDate),
+		}))
+		fmt.Fprintf(&b, "%s\n", branch.Message)
+		if size := m.visible[m.cursor].size; size != nil {
+			fmt.Fprintf(&b, "%s\n", formatBranchSize(m.localizer, *size))
+		} else {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n", m.visible[m.cursor].activity)
+	} else {
+		b.WriteString("\n\n\n\n\n\n")
+	}
+
+	if m.status != "" {
+		fmt.Fprintf(&b, "%s%s%s", ColorYellow, m.status, styleReset)
+	} else {
+		help := "TUIHelp"
+		if m.review {
+			help = "TUIReviewHelp"
+		}
+		fmt.Fprintf(&b, "%s%s%s", styleDim, m.localize(help, nil), styleReset)
+	}
+	return b.String()
+}
+
+// namespaceMessage localizes a message about the protected namespace of row
+func (m *tuiModel) namespaceMessage(messageID string, row *tuiRow) string {
+	namespace, _ := protectedNamespace(row.branch.Name)
+	return m.localize(messageID, map[string]interface{}{"Branch": row.branch.Name, "Namespace": namespace})
+}
+
+// defaultBranchMessage explains that row is not deleted because it is the
+// default branch of its remote
+func (m *tuiModel) defaultBranchMessage(row *tuiRow) string {
+	remoteName, _, _ := splitRemoteBranch(row.branch.Name)
+	return m.localize("DefaultBranchBlocked", map[string]interface{}{"Branch": row.branch.Name, "Remote": remoteName})
+}
+
+// othersMessage explains that row is not deleted because someone else
+// authored it
+func (m *tuiModel) othersMessage(row *tuiRow) string {
+	return m.localize("OthersBranchSkipped", map[string]interface{}{"Branch": row.branch.Name, "Author": row.branch.Author, "Email": row.branch.AuthorEmail})
+}
+
+func (m *tuiModel) viewConfirm() string {
+	var b strings.Builder
+	question := m.questions[0]
+	if question.kind == questionDrift {
+		data := map[string]interface{}{"Branch": question.drift.Branch, "Shown": shortSHA(question.drift.Shown), "Current": shortSHA(question.drift.Current)}
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.localize("BranchDrifted", data), styleReset)
+		if question.commits != "" {
+			fmt.Fprintf(&b, "\n%s\n", m.localize("DriftNewCommits", data))
+			for _, line := range strings.Split(question.commits, "\n") {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+		if question.err != "" {
+			fmt.Fprintf(&b, "\n%s%s%s\n", ColorRed, question.err, styleReset)
+		}
+		fmt.Fprintf(&b, "\n%s\n%s\n", m.localize("DriftActionPrompt", data), m.localize("TUIDriftKeys", nil))
+		return b.String()
+	}
+	if question.kind == questionOtherTeams {
+		for _, row := range question.rows {
+			owners := strings.Join(m.otherTeams[row.branch.Name], ", ")
+			fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.localize("OtherTeamBranch", map[string]interface{}{"Branch": row.branch.Name, "Owners": owners}), styleReset)
+		}
+		msg, _ := m.localizer.Localize(&i18n.LocalizeConfig{
+			MessageID:    "OtherTeamConfirm",
+			TemplateData: map[string]interface{}{"Count": len(question.rows), "Team": codeownersTeam},
+			PluralCount:  len(question.rows),
+		})
+		fmt.Fprintf(&b, "\n%s %s\n", msg, m.localize("TUIYesNo", nil))
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s\n\n", m.localize("ConfirmDeletion", nil))
+	for _, row := range question.rows {
+		fmt.Fprintf(&b, "  %s %s %s", padRight(row.branch.Name, 40), padRight(formatDate(m.localizer, row.branch.ActivityDate()), dateColumnWidth), m.reasons[row.branch.Name])
+		if mark := permissionMark(m.localizer, m.failures, row.branch.Name); mark != "" {
+			fmt.Fprintf(&b, " %s%s%s", ColorRed, mark, styleReset)
+		}
+		b.WriteString("\n")
+		if local, ok := m.locals[row.branch.Name]; ok {
+			fmt.Fprintf(&b, "  %s %s\n", local, m.localize("LocalBranchLabel", nil))
+		}
+	}
+	b.WriteString("\n")
+	if m.options.summaryBranches != nil {
+		var names []string
+		for _, row := range m.toDelete {
+			names = append(names, row.branch.Name)
+		}
+		fmt.Fprintf(&b, "%s\n", formatPlanSummary(m.localizer, summarizePlan(m.options.summaryBranches, names)))
+	}
+	for _, row := range m.defaults {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorRed, m.defaultBranchMessage(row), styleReset)
+	}
+	for _, row := range m.blocked {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.namespaceMessage("NamespaceBranchSkipped", row), styleReset)
+	}
+	for _, row := range m.others {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.othersMessage(row), styleReset)
+	}
+	for _, row := range m.toDelete {
+		if _, ok := protectedNamespace(row.branch.Name); ok {
+			fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.namespaceMessage("NamespaceDeletionWarning", row), styleReset)
+		}
+	}
+	for _, skipped := range m.skippedLocals {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.localize(skipped.MessageID, map[string]interface{}{"Branch": skipped.Local, "Remote": skipped.Remote, "Worktree": skipped.Worktree}), styleReset)
+	}
+	for _, row := range m.toDelete {
+		if worktree, ok := worktreeForRemoteBranch(row.branch.Name); ok {
+			fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.localize("WorktreeWarning", map[string]interface{}{"Branch": row.branch.Name, "Worktree": worktree}), styleReset)
+		}
+	}
+	if len(m.failures) > 0 {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, m.localize("PermissionWarning", map[string]interface{}{"Count": len(m.failures)}), styleReset)
+	}
+	for _, warning := range m.stacked {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, warning, styleReset)
+	}
+	for _, warning := range m.mirrored {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, warning, styleReset)
+	}
+	for _, warning := range m.largeObjects {
+		fmt.Fprintf(&b, "%s%s%s\n", ColorYellow, warning, styleReset)
+	}
+	if question.remote != "" {
+		fmt.Fprintf(&b, "\n%s", question.remote)
+	}
+	if len(question.rows) > m.options.confirmThreshold {
+		if question.remote != "" {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s_\n", m.localize("ConfirmLargeDeletion", map[string]interface{}{"Count": len(question.rows)}), m.input)
+	} else if question.remote != "" {
+		fmt.Fprintf(&b, " %s\n", m.localize("TUIYesNo", nil))
+	} else {
+		fmt.Fprintf(&b, "%s\n", m.localize("TUIConfirmPrompt", nil))
+	}
+	return b.String()
+}
+
+func (m *tuiModel) viewResults() string {
+	var b strings.Builder
+	for _, result := range m.results {
+		fmt.Fprintf(&b, "%s\n", result)
+	}
+	if m.state == tuiDone {
+		if len(m.failed) > 0 {
+			fmt.Fprintf(&b, "\n%s%s%s\n", ColorYellow, m.localize("TUIRetryFailed", map[string]interface{}{"Count": len(m.failed)}), styleReset)
+		}
+		fmt.Fprintf(&b, "\n%s%s%s\n", styleDim, m.localize("TUIPressAnyKey", nil), styleReset)
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most width terminal columns, marking the cut
+// with "~"; wide characters are never split
+func truncate(s string, width int) string {
+	if width <= 1 {
+		return runewidth.Truncate(s, width, "")
+	}
+	return runewidth.Truncate(s, width, "~")
+}
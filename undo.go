@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// offerUndo asks, right after a deletion, whether to undo it, and pushes the
+// deleted branches back at the tips they had, on every remote they were
+// deleted from, mirrors included, with their remote-tracking refs. It is the
+// escape hatch for a mistake noticed as the last branch goes; later, branches
+// are restored from the history by hand.
+func offerUndo(localizer *i18n.Localizer, deleted []string, tips map[string]string) {
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data, PluralCount: data["Count"]})
+		return msg
+	}
+	deleted, tips = withMirrorDeletions(deleted, tips)
+	var restorable []string
+	for _, branch := range deleted {
+		if tips[branch] != "" {
+			restorable = append(restorable, branch)
+		}
+	}
+	if len(restorable) == 0 {
+		return
+	}
+
+	message := localize("UndoDeletion", map[string]interface{}{"Count": len(restorable)})
+	var undo bool
+	if plainMode {
+		undo = askPlainConfirm(message)
+	} else {
+		survey.AskOne(&survey.Confirm{Message: message, Default: false}, &undo, terminalAskOpts()...)
+	}
+	if !undo {
+		return
+	}
+
+	for _, group := range groupByRemote(restorable) {
+		output, err := restoreBranches(group.remote, group.branches, tips)
+		fmt.Println(strings.TrimSpace(output))
+		if err != nil {
+			fmt.Printf("%s%s%s\n", ColorRed, localize("UndoFailed", map[string]interface{}{"Remote": group.remote, "Error": err}), ColorReset)
+			continue
+		}
+		restoreTrackingRefs(group.remote, group.branches, tips)
+		for _, branch := range group.branches {
+			fmt.Println(localize("BranchRestored", map[string]interface{}{"Branch": branch, "SHA": shortSHA(tips[branch])}))
+			emitEvent(Event{Event: "restored", Branch: branch, SHA: tips[branch]})
+		}
+	}
+}
+
+// restoreBranches pushes branches of one remote back at their tips, in one
+// atomic push. The lease fails the push if someone has created a branch of
+// the same name since. Branches a soft deletion moved to the trash are moved
+// back out of it.
+func restoreBranches(remoteName string, branches []string, tips map[string]string) (string, error) {
+	args := []string{"push", "--atomic"}
+	var refspecs []string
+	for _, branch := range branches {
+		_, branchName, _ := splitRemoteBranch(branch)
+		args = append(args, "--force-with-lease=refs/heads/"+branchName+":")
+		refspecs = append(refspecs, tips[branch]+":refs/heads/"+branchName)
+		if softDelete {
+			args = append(args, "--force-with-lease=refs/heads/"+trashBranchName(branchName)+":"+tips[branch])
+			refspecs = append(refspecs, ":refs/heads/"+trashBranchName(branchName))
+		}
+	}
+	args = append(append(args, remoteName), refspecs...)
+	output, err := gitCommand(args...).CombinedOutput()
+	return string(output), err
+}
+
+// restoreTrackingRefs recreates the remote-tracking refs of restored branches,
+// which the deletion pruned, and drops those of their trash copies
+func restoreTrackingRefs(remoteName string, branches []string, tips map[string]string) {
+	var commands strings.Builder
+	var trashed []string
+	for _, branch := range branches {
+		_, branchName, _ := splitRemoteBranch(branch)
+		fmt.Fprintf(&commands, "update refs/remotes/%s/%s %s\n", remoteName, branchName, tips[branch])
+		trashed = append(trashed, trashBranchName(branchName))
+	}
+	cmd := gitCommand("update-ref", "--stdin")
+	cmd.Stdin = strings.NewReader(commands.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Debug("could not restore remote-tracking refs", "remote", remoteName, "error", err, "output", strings.TrimSpace(string(output)))
+	}
+	if softDelete {
+		pruneTrackingRefs(remoteName, trashed)
+	}
+}
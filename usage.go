@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// timeSavedPerBranch is the estimated time it takes to delete a branch by
+// hand: finding it, checking it is merged and pushing the deletion
+const timeSavedPerBranch = time.Minute
+
+// UsageMonth counts the usage of one calendar month
+type UsageMonth struct {
+	Runs            int `json:"runs"`
+	BranchesDeleted int `json:"branchesDeleted"`
+}
+
+// Usage is the usage of the tool by the current user across repositories.
+// It is only ever written to a file in the user's config directory; nothing
+// is sent anywhere.
+type Usage struct {
+	Since           time.Time              `json:"since"`
+	Runs            int                    `json:"runs"`
+	BranchesDeleted int                    `json:"branchesDeleted"`
+	Months          map[string]*UsageMonth `json:"months"`
+}
+
+// usagePath returns the location of the usage file, next to the user
+// configuration
+func usagePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "grbm", "usage.json"), nil
+}
+
+// loadUsage reads the usage file, returning empty usage when there is none
+func loadUsage() (*Usage, error) {
+	usage := &Usage{Months: map[string]*UsageMonth{}}
+	path, err := usagePath()
+	if err != nil {
+		return usage, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, err
+	}
+	if err := json.Unmarshal(data, usage); err != nil {
+		return usage, err
+	}
+	if usage.Months == nil {
+		usage.Months = map[string]*UsageMonth{}
+	}
+	return usage, nil
+}
+
+// recordUsage adds runs and deleted branches to the usage file. Like the
+// history it is best effort: failing to write it never fails a run.
+func recordUsage(runs, branchesDeleted int) {
+	usage, err := loadUsage()
+	if err != nil {
+		logger.Debug("could not read usage", "error", err)
+		return
+	}
+	now := time.Now()
+	if usage.Since.IsZero() {
+		usage.Since = now.UTC().Truncate(time.Second)
+	}
+	month := now.Format("2006-01")
+	if usage.Months[month] == nil {
+		usage.Months[month] = &UsageMonth{}
+	}
+	usage.Runs += runs
+	usage.BranchesDeleted += branchesDeleted
+	usage.Months[month].Runs += runs
+	usage.Months[month].BranchesDeleted += branchesDeleted
+
+	path, err := usagePath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(usage, "", "  "); err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		logger.Debug("could not write usage", "error", err)
+	}
+}
+
+// TimeSaved estimates the time the deleted branches would have taken to
+// delete by hand
+func (u *Usage) TimeSaved() time.Duration {
+	return time.Duration(u.BranchesDeleted) * timeSavedPerBranch
+}
+
+// printUsage prints the usage of the tool as text or as JSON
+func printUsage(localizer *i18n.Localizer, asJSON bool) error {
+	usage, err := loadUsage()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			*Usage
+			TimeSavedMinutes int `json:"timeSavedMinutes"`
+		}{usage, int(usage.TimeSaved().Minutes())})
+	}
+
+	localize := func(messageID string, data map[string]interface{}) string {
+		msg, _ := localizer.Localize(&i18n.LocalizeConfig{MessageID: messageID, TemplateData: data})
+		return msg
+	}
+	if usage.Since.IsZero() {
+		fmt.Println(localize("UsageEmpty", nil))
+		return nil
+	}
+	fmt.Printf("%s %s\n", padRight(localize("UsageSince", nil), 32), usage.Since.Local().Format("2006-01-02"))
+	fmt.Printf("%s %d\n", padRight(localize("UsageRuns", nil), 32), usage.Runs)
+	fmt.Printf("%s %d\n", padRight(localize("UsageBranchesDeleted", nil), 32), usage.BranchesDeleted)
+	fmt.Printf("%s %s\n", padRight(localize("UsageTimeSaved", map[string]interface{}{"Minutes": int(timeSavedPerBranch.Minutes())}), 32), formatHoursMinutes(usage.TimeSaved()))
+
+	months := make([]string, 0, len(usage.Months))
+	for month := range usage.Months {
+		months = append(months, month)
+	}
+	slices.Sort(months)
+	fmt.Printf("\n%s\n", localize("UsageMonths", nil))
+	for _, month := range months {
+		fmt.Printf("  %-8s %6d %6d\n", month, usage.Months[month].Runs, usage.Months[month].BranchesDeleted)
+	}
+	return nil
+}
+
+// formatHoursMinutes formats a duration as hours and minutes, e.g. "3h 05m"
+func formatHoursMinutes(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
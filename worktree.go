@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// checkedOutBranch is a local branch checked out in one of the repository's
+// worktrees (the main one or a linked one)
+type checkedOutBranch struct {
+	Local    string // e.g. "feature/x"
+	Upstream string // e.g. "origin/feature/x", empty if none is set
+	Worktree string // path of the worktree
+}
+
+var (
+	checkedOutOnce     sync.Once
+	checkedOutBranches []checkedOutBranch
+)
+
+// loadCheckedOutBranches parses `git worktree list --porcelain` and returns
+// the branches checked out in any worktree along with their upstreams
+func loadCheckedOutBranches() []checkedOutBranch {
+	checkedOutOnce.Do(func() {
+		output, err := runGit("worktree", "list", "--porcelain")
+		if err != nil {
+			return
+		}
+		upstreams := map[string]string{}
+		for _, branch := range loadLocalBranches() {
+			upstreams[branch.Name] = branch.Upstream
+		}
+
+		var worktree string
+		for _, line := range strings.Split(output, "\n") {
+			if path, ok := strings.CutPrefix(line, "worktree "); ok {
+				worktree = path
+			} else if ref, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+				checkedOutBranches = append(checkedOutBranches, checkedOutBranch{Local: ref, Upstream: upstreams[ref], Worktree: worktree})
+			}
+		}
+	})
+	return checkedOutBranches
+}
+
+// worktreeForRemoteBranch returns the path of the worktree in which a local
+// counterpart of remoteBranch is checked out
+func worktreeForRemoteBranch(remoteBranch string) (string, bool) {
+	for _, branch := range loadCheckedOutBranches() {
+		if isLocalCounterpart(branch.Local, branch.Upstream, remoteBranch) {
+			return branch.Worktree, true
+		}
+	}
+	return "", false
+}
+
+// worktreeForLocalBranch returns the path of the worktree in which the local
+// branch is checked out
+func worktreeForLocalBranch(local string) (string, bool) {
+	for _, branch := range loadCheckedOutBranches() {
+		if branch.Local == local {
+			return branch.Worktree, true
+		}
+	}
+	return "", false
+}